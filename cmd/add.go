@@ -22,34 +22,91 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
-	ipfs "github.com/ipfs/go-ipfs-api"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/will-rowe/scribe/src/backend"
 	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/helpers"
 	"github.com/will-rowe/scribe/src/records"
 )
 
 // addCmd represents the add command
 var addCmd = &cobra.Command{
-	Use:   "add <run|library>",
-	Short: "Add a run or library to an existing project",
-	Long: `Add a run or library to an existing project.
+	Use:   "add <run|library|sample>",
+	Short: "Add a run, library or sample to an existing project",
+	Long: `Add a run, library or sample to an existing project.
 	
 	This will collect the project (registered using scribe set --project XXX) and then add
-specified record to the project, before committing it back to the IPFS.`,
+specified record to the project, before committing it back to the IPFS.
+
+A run needs a label, the directory it is stored in and its fast5 and fastq directories, e.g.:
+
+	scribe add run --label run1 --output-dir ./run1 --fast5-dir ./run1/fast5_pass --fastq-dir ./run1/fastq_pass
+
+A library needs a label, the prep kit and the flowcell type, e.g.:
+
+	scribe add library --label lib1 --prep-kit SQK-LSK109 --barcode-kit EXP-NBD104 --flowcell FLO-MIN106
+
+A run can then be linked to the library with --library, and tagged with the services
+to process it (in order) with --tag, which are requested by scribe orchestrate.
+
+A sample needs a label, and is attached to a run with its barcode, e.g.:
+
+	scribe add sample --label sample1 --run run1 --barcode 1
+
+The barcode must be unique within the run and the sample's libraries (the run's library, and any
+library listing the sample with --sample), and in the range of their barcode kits.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		runAdd(args[0])
 	},
 }
 
+// set up the flags
+var (
+	projectDescription *string // describes a project registered by the add subcommand
+	recordLabel        *string
+	recordComment      *string
+	runOutputDir       *string
+	runFast5Dir        *string
+	runFastqDir        *string
+	runLibrary         *string
+	runTags            *[]string
+	libraryPrepKit     *string
+	libraryBarcodeKit  *string
+	libraryFlowcell    *string
+	libraryInputDNA    *float64
+	libraryOperator    *string
+	librarySamples     *[]string
+	sampleRun          *string
+	sampleBarcode      *int32
+)
+
 func init() {
 	rootCmd.AddCommand(addCmd)
+	projectDescription = addCmd.Flags().String("description", "", "Description for the project, if it needs registering")
+	recordLabel = addCmd.Flags().String("label", "", "Label for the run, library or sample (must be unique within the project)")
+	recordComment = addCmd.Flags().String("comment", "", "Comment to add to the run, library or sample history")
+	runOutputDir = addCmd.Flags().String("output-dir", "", "Directory the run is stored in")
+	runFast5Dir = addCmd.Flags().String("fast5-dir", "", "Directory the run fast5 data is stored in")
+	runFastqDir = addCmd.Flags().String("fastq-dir", "", "Directory the run fastq data is stored in")
+	runLibrary = addCmd.Flags().String("library", "", "Label of the library sequenced in the run")
+	runTags = addCmd.Flags().StringSlice("tag", []string{}, "Service to process the run, in the order they are given (can be repeated)")
+	libraryPrepKit = addCmd.Flags().String("prep-kit", "", "Prep kit used for the library (e.g. SQK-LSK109)")
+	libraryBarcodeKit = addCmd.Flags().String("barcode-kit", "", "Barcoding kit used for the library, if it is multiplexed (e.g. EXP-NBD104)")
+	libraryFlowcell = addCmd.Flags().String("flowcell", "", "Flowcell type the library is prepared for (e.g. FLO-MIN106)")
+	libraryInputDNA = addCmd.Flags().Float64("input-dna", 0, "Amount of input DNA for the library (ng)")
+	libraryOperator = addCmd.Flags().String("operator", "", "Who prepared the library")
+	librarySamples = addCmd.Flags().StringSlice("sample", []string{}, "Label of a sample in the library (can be repeated)")
+	sampleRun = addCmd.Flags().String("run", "", "Label of the run the sample is sequenced in")
+	sampleBarcode = addCmd.Flags().Int32("barcode", 0, "Barcode for the sample in the run (0 if the run is not multiplexed)")
 }
 
 // runAdd is the main block for the add subcommand
@@ -57,13 +114,46 @@ func runAdd(arg string) {
 
 	// check the arg is a known record type
 	switch arg {
-	case "run", "library":
+	case "run", "library", "sample":
 		break
 	default:
-		fmt.Printf("unrecognised argument (%v), use either run|library\n", arg)
+		fmt.Printf("unrecognised argument (%v), use either run|library|sample\n", arg)
 		os.Exit(1)
 	}
 
+	// check the record flags before doing anything else
+	var library *records.Library
+	var sample *records.Sample
+	switch arg {
+	case "run":
+		if err := checkRunFlags(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "library":
+		library = records.InitLibrary(*recordLabel, *libraryPrepKit, *libraryBarcodeKit, *libraryFlowcell)
+		library.InputDNA = *libraryInputDNA
+		library.Operator = *libraryOperator
+		library.Samples = *librarySamples
+		if len(*recordComment) != 0 {
+			library.AddComment(*recordComment)
+		}
+		if err := library.Validate(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "sample":
+		sample = records.InitSample(*recordLabel, *sampleBarcode)
+		sample.ParentExperiment = *sampleRun
+		if len(*recordComment) != 0 {
+			sample.AddComment(*recordComment)
+		}
+		if err := sample.Validate(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
 	// run the config checker to make sure we've got everything
 	if err := config.CheckConfig(); err != nil {
 		fmt.Println(err)
@@ -75,39 +165,15 @@ func runAdd(arg string) {
 	log.Info("starting the add subcommand...")
 	log.Infof("\tconfig file: %v", viper.ConfigFileUsed())
 
-	// configure the daemon
-	log.Info("configuring IPFS daemon...")
+	// get the config and start the node
 	config, err := config.DumpConfig2Mem()
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := backend.ConfigureDaemon(config); err != nil {
-		log.Fatal(err)
-	}
-	log.Infof("\tupdated IPFS daemon using the scribe config")
-
-	// check if the IPFS daemon is running (launch if not)
-	tmpShell := ipfs.NewShell(backend.GetAPI())
-	if !tmpShell.IsUp() {
-		log.Infof("\tlaunching daemon...")
-		if err := backend.LaunchDaemon(config); err != nil {
-			log.Fatal(err)
-		}
-	}
-	log.Infof("\tdaemon is running")
-
-	// init the node
-	log.Info("initialising the node...")
-	node, err := backend.InitNode(backend.GetAPI())
-	if err != nil {
-		log.Fatal(err)
-	}
-	nodeIdentity, err := node.Identity()
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Infof("\tAPI server listening on: %s", backend.GetAPI())
-	log.Infof("\tnode identity: %v", nodeIdentity.ID)
+	ctx, cancel := interruptContext()
+	defer cancel()
+	node, isOffline := startNode(ctx, config)
+	defer node.Close()
 	node.SetProject(config.Project)
 	log.Infof("\tregistered node with proejct: %v", node.GetProject())
 
@@ -117,8 +183,8 @@ func runAdd(arg string) {
 	if len(config.RemoteCID) != 0 {
 		log.Infof("\tremote CID found: %s", config.RemoteCID)
 		log.Info("\tpulling project database from IPFS...")
-		if err := db.Pull(node, config.RemoteCID); err != nil {
-			log.Fatal(err)
+		if err := db.Pull(ctx, node, config.RemoteCID); err != nil {
+			checkNodeErr(err)
 		}
 		log.Infof("\tnumber of projects added to local database: %d", db.GetNumProjects())
 	} else {
@@ -127,6 +193,7 @@ func runAdd(arg string) {
 
 	// check for the required project
 	log.Info("checking the local project database...")
+	previousCID := config.RemoteCID
 	proj, err := db.GetProject(config.Project)
 	switch err {
 	case nil:
@@ -134,24 +201,19 @@ func runAdd(arg string) {
 
 	case records.ErrNotFound:
 		log.Infof("\tproject not found for %v", config.Project)
-		log.Info("\tcreating project...")
+		log.Info("\tregistering project...")
 		proj = records.InitProject(config.Project)
-		log.Info("\tadding to the database...")
-		if err := db.AddProject(proj); err != nil {
-			log.Fatal(err)
-		}
-		log.Info("\tpushing database changes to IPFS...")
-		cid, err := db.Push(node)
-		if err != nil {
-			log.Fatal(err)
+		proj.Description = *projectDescription
+		proj.License = config.License
+		cid, err := proj.Register(ctx, node, db, previousCID)
+		switch {
+		case errors.Is(err, records.ErrNotAnnounced):
+			log.Warnf("\tcould not announce the project: %v", err)
+		case err != nil:
+			checkNodeErr(err)
 		}
-		log.Info("\tupdating CID...")
-		config.RemoteCID = cid
-		viper.Set("remoteCID", cid)
-		if err := viper.WriteConfig(); err != nil {
-			log.Fatal(err)
-		}
-		log.Infof("\tCID updated: %s", config.RemoteCID)
+		log.Infof("\tproject ID: %v", proj.GetId())
+		setRemoteCID(config, cid)
 		log.Infof("\tview on: %v", fmt.Sprintf("https://explore.ipld.io/#/explore/%s", config.RemoteCID))
 
 	default:
@@ -160,10 +222,131 @@ func runAdd(arg string) {
 
 	// work with the project
 	log.Infof("\tproject loaded: %v", proj.GetLabel())
+	switch arg {
+	case "run":
+		addRun(ctx, node, config, db, proj)
+	case "library":
+		addLibrary(ctx, node, config, db, proj, library)
+	case "sample":
+		addSample(ctx, node, config, db, proj, sample)
+	}
 
-	// test message
-	if err := node.Publish("just loaded the project over here..."); err != nil {
-		log.Fatal(err)
+	// announce any change to the database (pubsub is not available offline)
+	if config.RemoteCID != previousCID {
+		announceHead(ctx, node, config, previousCID, isOffline)
+	}
+}
+
+// checkRunFlags will check the run flags, making the directories absolute so that they make sense to other nodes
+func checkRunFlags() error {
+	if len(*recordLabel) == 0 {
+		return fmt.Errorf("a run needs a label (--label)")
+	}
+	if len(*runOutputDir) == 0 {
+		return fmt.Errorf("a run needs an output directory (--output-dir)")
+	}
+	if len(*runFast5Dir) == 0 {
+		return fmt.Errorf("a run needs a fast5 directory (--fast5-dir)")
+	}
+	if len(*runFastqDir) == 0 {
+		return fmt.Errorf("a run needs a fastq directory (--fastq-dir)")
+	}
+	for _, dir := range []*string{runOutputDir, runFast5Dir, runFastqDir} {
+		if err := helpers.CheckDirExists(*dir); err != nil {
+			return err
+		}
+		absDir, err := filepath.Abs(*dir)
+		if err != nil {
+			return err
+		}
+		*dir = absDir
+	}
+	return nil
+}
+
+// addRun will create a run from the flags, attach it to the project and push the database
+func addRun(ctx context.Context, node *backend.Node, conf *config.ScribeConfig, db *records.ProjectDatabase, proj *records.Project) {
+	log.Info("adding the run...")
+	if _, exists := proj.GetRuns()[*recordLabel]; exists {
+		log.Fatalf("run already in the project (label: %v)", *recordLabel)
 	}
+	run := records.InitRun(*recordLabel, *runOutputDir, *runFast5Dir, *runFastqDir)
+	run.ParentProjectLabel = proj.GetLabel()
+	run.Library = *runLibrary
+	if len(*recordComment) != 0 {
+		if err := run.AddComment(*recordComment); err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Infof("\trun label: %v", run.GetLabel())
+	log.Infof("\toutput directory: %v", run.GetOutputDirectory())
+	var tagged *records.Message
+	if len(*runTags) != 0 {
+		var err error
+		if tagged, err = run.AddTags(*runTags...); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("\ttags: %v", run.GetRequestOrder())
+	}
+	log.Info("\tpushing database changes to IPFS...")
+	cid, err := run.Sync(ctx, node, db, conf.RemoteCID)
+	switch {
+	case errors.Is(err, records.ErrNotAnnounced):
+		log.Warnf("\tcould not announce the run: %v", err)
+	case err != nil:
+		checkNodeErr(err)
+	}
+	log.Infof("\trun CID: %v", proj.GetRuns()[run.GetLabel()])
+	setRemoteCID(conf, cid)
+
+	// announce the tags once the run is in the project, so that the services can fetch it
+	if tagged != nil {
+		if err := records.AnnounceStatus(ctx, node, tagged); err != nil {
+			log.Warnf("\tcould not announce the tags: %v", err)
+		}
+	}
+}
 
+// addLibrary will attach a library to the project and push the database
+func addLibrary(ctx context.Context, node *backend.Node, conf *config.ScribeConfig, db *records.ProjectDatabase, proj *records.Project, library *records.Library) {
+	log.Info("adding the library...")
+	if _, exists := proj.GetLibraries()[library.GetLabel()]; exists {
+		log.Fatalf("library already in the project (label: %v)", library.GetLabel())
+	}
+	library.ParentProjectLabel = proj.GetLabel()
+	log.Infof("\tlibrary label: %v", library.GetLabel())
+	log.Infof("\tprep kit: %v", library.GetPrepKit())
+	log.Info("\tpushing database changes to IPFS...")
+	cid, err := library.Sync(ctx, node, db, conf.RemoteCID)
+	switch {
+	case errors.Is(err, records.ErrNotAnnounced):
+		log.Warnf("\tcould not announce the library: %v", err)
+	case err != nil:
+		checkNodeErr(err)
+	}
+	log.Infof("\tlibrary CID: %v", proj.GetLibraries()[library.GetLabel()])
+	setRemoteCID(conf, cid)
+}
+
+// addSample will attach a sample to the project and its run, and push the database
+func addSample(ctx context.Context, node *backend.Node, conf *config.ScribeConfig, db *records.ProjectDatabase, proj *records.Project, sample *records.Sample) {
+	log.Info("adding the sample...")
+	if _, exists := proj.GetSamples()[sample.GetLabel()]; exists {
+		log.Fatalf("sample already in the project (label: %v)", sample.GetLabel())
+	}
+	sample.ParentProjectLabel = proj.GetLabel()
+	log.Infof("\tsample label: %v", sample.GetLabel())
+	if len(sample.GetParentExperiment()) != 0 {
+		log.Infof("\trun: %v (barcode %d)", sample.GetParentExperiment(), sample.GetBarcode())
+	}
+	log.Info("\tpushing database changes to IPFS...")
+	cid, err := sample.Sync(ctx, node, db, conf.RemoteCID)
+	switch {
+	case errors.Is(err, records.ErrNotAnnounced):
+		log.Warnf("\tcould not announce the run update: %v", err)
+	case err != nil:
+		checkNodeErr(err)
+	}
+	log.Infof("\tsample CID: %v", proj.GetSamples()[sample.GetLabel()])
+	setRemoteCID(conf, cid)
 }
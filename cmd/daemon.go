@@ -0,0 +1,177 @@
+/*
+Copyright © 2020 Will Rowe <w.p.m.rowe@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/will-rowe/scribe/src/backend"
+	"github.com/will-rowe/scribe/src/config"
+)
+
+// set up the flags
+var (
+	logLines *int
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon <start|stop|status|logs|restart>",
+	Short: "Manage the IPFS daemon used by scribe",
+	Long: `Manage the IPFS daemon used by scribe.
+
+Scribe records the PID of any IPFS daemon it launches in the scribe data directory
+and writes the daemon output to log files there (rotated on each launch). Only
+daemons launched by scribe can be stopped or restarted with this command.`,
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"start", "stop", "status", "logs", "restart"},
+	Run: func(cmd *cobra.Command, args []string) {
+		runDaemon(args[0])
+	},
+}
+
+// init the subcommand
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	logLines = daemonCmd.Flags().IntP("lines", "n", 20, "Number of log lines to print (logs)")
+}
+
+// runDaemon is the main block for the daemon subcommand
+func runDaemon(arg string) {
+
+	// run the config checker to make sure we've got everything
+	if err := config.CheckConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	log.Info("------------SCRIBE------------")
+	log.Infof("starting the daemon subcommand (%v)...", arg)
+	log.Infof("\tconfig file: %v", viper.ConfigFileUsed())
+	config, err := config.DumpConfig2Mem()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch arg {
+	case "start":
+		daemonStart(config)
+	case "stop":
+		daemonStop(config)
+	case "restart":
+		daemonStop(config)
+		daemonStart(config)
+	case "logs":
+		daemonLogs(config)
+		return
+	}
+	daemonStatus(config)
+}
+
+// daemonStart will configure and launch the daemon if it isn't running
+func daemonStart(conf *config.ScribeConfig) {
+	if err := configureDaemon(conf); err != nil {
+		log.Fatal(err)
+	}
+	if daemonIsUp(context.Background(), conf) {
+		log.Info("IPFS daemon is already running")
+		return
+	}
+	log.Info("launching IPFS daemon...")
+	if err := backend.LaunchDaemon(conf); err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("\tlogging to: %v", backend.DaemonLogPath(conf))
+}
+
+// daemonStop will stop the daemon, if scribe launched it
+func daemonStop(conf *config.ScribeConfig) {
+	log.Info("stopping IPFS daemon...")
+	err := backend.StopDaemon(conf)
+	switch err {
+	case nil:
+		log.Info("\tstopped")
+	case backend.ErrNotManaged:
+		if daemonIsUp(context.Background(), conf) {
+			log.Warn("\tIPFS daemon is running but was not launched by scribe, leaving it alone")
+		} else {
+			log.Info("\tIPFS daemon is not running")
+		}
+	default:
+		log.Fatal(err)
+	}
+}
+
+// daemonStatus will report the state of the daemon
+func daemonStatus(conf *config.ScribeConfig) {
+	status, err := backend.GetDaemonStatus(conf)
+	if err != nil {
+		log.Warn(err)
+	}
+	log.Info("IPFS daemon status...")
+	if !status.Running {
+		log.Info("\trunning: false")
+		return
+	}
+	log.Info("\trunning: true")
+	if status.Managed {
+		log.Infof("\tlaunched by scribe: true (PID %d)", status.PID)
+	} else {
+		log.Info("\tlaunched by scribe: false")
+	}
+	log.Infof("\tnode identity: %v", status.PeerID)
+	log.Infof("\tAPI address: %v", status.API)
+	log.Infof("\tgateway address: %v", status.Gateway)
+	for _, addr := range status.Swarm {
+		log.Infof("\tswarm address: %v", addr)
+	}
+	log.Infof("\tconnected peers: %d", status.PeerCount)
+}
+
+// daemonLogs will print the end of the current daemon log
+func daemonLogs(conf *config.ScribeConfig) {
+	fh, err := os.Open(backend.DaemonLogPath(conf))
+	if err != nil {
+		log.Fatalf("no daemon log found (%v)", err)
+	}
+	defer fh.Close()
+	lines := []string{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > *logLines {
+			lines = lines[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2020 Will Rowe <w.p.m.rowe@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/records"
+)
+
+// diffJSON will print the diff as JSON
+var diffJSON *bool
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <from> [to]",
+	Short: "Show the changes between two versions of the project database",
+	Long: `Show the changes between two versions of the project database.
+
+Versions are given as CIDs or as references to the history of the project
+database in the config (HEAD, HEAD~1, HEAD~2...). If only one version is
+given, it is compared to HEAD.
+
+The projects and runs that were added or removed, changed run fields, new
+history comments and tag changes are printed as text, or as JSON with --json
+(in which case the log is sent to stderr).`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDiff(args)
+	},
+}
+
+// init the subcommand
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffJSON = diffCmd.Flags().Bool("json", false, "Print the diff as JSON")
+}
+
+// runDiff is the main block for the diff subcommand
+func runDiff(args []string) {
+
+	// keep stdout for the JSON, so that it can be parsed
+	if *diffJSON {
+		log.SetOutput(os.Stderr)
+	}
+
+	// run the config checker to make sure we've got everything
+	if err := config.CheckConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// start the subcommand
+	log.Info("------------SCRIBE------------")
+	log.Info("starting the diff subcommand...")
+	log.Infof("\tconfig file: %v", viper.ConfigFileUsed())
+
+	// get the config and start the node
+	config, err := config.DumpConfig2Mem()
+	if err != nil {
+		log.Fatal(err)
+	}
+	refs := []string{args[0], "HEAD"}
+	if len(args) == 2 {
+		refs[1] = args[1]
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	node, _ := startNode(ctx, config)
+	defer node.Close()
+
+	// resolve the references
+	cids := make([]string, len(refs))
+	for i, ref := range refs {
+		if cids[i], err = records.ResolveRef(ctx, node, config.RemoteCID, ref); err != nil {
+			checkNodeErr(err)
+		}
+		log.Infof("\t%v: %v", ref, cids[i])
+	}
+
+	// diff the versions and print the result
+	diff, err := records.Diff(ctx, node, cids[0], cids[1])
+	if err != nil {
+		checkNodeErr(err)
+	}
+	if *diffJSON {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	printDiff(diff)
+}
+
+// printDiff will print a diff as human-readable text
+func printDiff(diff *records.DatabaseDiff) {
+	fmt.Printf("diff %v..%v\n", diff.From, diff.To)
+	if diff.IsEmpty() {
+		fmt.Println("no changes")
+		return
+	}
+	for _, project := range diff.ProjectsAdded {
+		fmt.Printf("+ project %v\n", project)
+	}
+	for _, project := range diff.ProjectsRemoved {
+		fmt.Printf("- project %v\n", project)
+	}
+	for _, project := range diff.Projects {
+		fmt.Printf("~ project %v\n", project.Project)
+		printFieldChanges("    ", project.Fields)
+		for _, library := range project.LibrariesAdded {
+			fmt.Printf("    + library %v\n", library)
+		}
+		for _, library := range project.LibrariesRemoved {
+			fmt.Printf("    - library %v\n", library)
+		}
+		for _, library := range project.Libraries {
+			fmt.Printf("    ~ library %v: %v -> %v\n", library.Field, library.Old, library.New)
+		}
+		for _, sample := range project.SamplesAdded {
+			fmt.Printf("    + sample %v\n", sample)
+		}
+		for _, sample := range project.SamplesRemoved {
+			fmt.Printf("    - sample %v\n", sample)
+		}
+		for _, sample := range project.Samples {
+			fmt.Printf("    ~ sample %v: %v -> %v\n", sample.Field, sample.Old, sample.New)
+		}
+		for _, run := range project.RunsAdded {
+			fmt.Printf("    + run %v\n", run)
+		}
+		for _, run := range project.RunsRemoved {
+			fmt.Printf("    - run %v\n", run)
+		}
+		for _, run := range project.Runs {
+			fmt.Printf("    ~ run %v\n", run.Run)
+			printFieldChanges("        ", run.Fields)
+			for _, comment := range run.NewComments {
+				fmt.Printf("        + comment %v: %v\n", comment.Timestamp, comment.Text)
+			}
+			for _, tag := range run.Tags {
+				fmt.Printf("        ~ tag %v: %v -> %v\n", tag.Field, describeTagStatus(tag.Old), describeTagStatus(tag.New))
+			}
+		}
+	}
+}
+
+// printFieldChanges will print the changed fields of a project or run
+func printFieldChanges(indent string, changes []records.FieldChange) {
+	for _, change := range changes {
+		fmt.Printf("%v~ %v: %q -> %q\n", indent, change.Field, change.Old, change.New)
+	}
+}
+
+// describeTagStatus will describe the status of a tag in a diff
+func describeTagStatus(status string) string {
+	if len(status) == 0 {
+		return "untagged"
+	}
+	return status
+}
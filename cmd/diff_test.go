@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/will-rowe/scribe/src/backend"
+	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/records"
+)
+
+// TestDiffJSON
+func TestDiffJSON(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("./", "test-scribe-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// push two versions of a database to the local block store
+	conf := &config.ScribeConfig{
+		FileType:       config.DefaultType,
+		License:        config.DefaultLicense,
+		IpfsPath:       filepath.Join(dir, "ipfs"),
+		DataDir:        filepath.Join(dir, "data"),
+		APIAddress:     config.DefaultAPIAddress,
+		SwarmAddress:   config.DefaultSwarmAddress,
+		GatewayAddress: config.DefaultGatewayAddress,
+		Timeout:        config.DefaultTimeout,
+		Retries:        config.DefaultRetries,
+		Project:        config.DefaultProject,
+	}
+	store, err := backend.NewFileStore(conf.DataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := backend.InitNodeWithStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := records.InitDB()
+	if err := db.AddProject(records.InitProject("first project")); err != nil {
+		t.Fatal(err)
+	}
+	from, err := db.Push(ctx, node, "", "added first project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddProject(records.InitProject("second project")); err != nil {
+		t.Fatal(err)
+	}
+	if conf.RemoteCID, err = db.Push(ctx, node, from, "added second project"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "scribe.json")
+	if err := ioutil.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// run the command with stdout captured, logging to stdout as the command does by default
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	log.SetOutput(os.Stdout)
+	defer func() {
+		os.Stdout = stdout
+		log.SetOutput(os.Stdout)
+	}()
+	output := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		output <- data
+	}()
+	rootCmd.SetArgs([]string{"--config", configFile, "--offline", "diff", "--json", "HEAD~1"})
+	err = rootCmd.Execute()
+	w.Close()
+	printed := <-output
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check stdout only holds the diff
+	diff := &records.DatabaseDiff{}
+	if err := json.Unmarshal(printed, diff); err != nil {
+		t.Fatalf("could not parse the diff (%v): %s", err, printed)
+	}
+	if diff.From != from || diff.To != conf.RemoteCID || len(diff.ProjectsAdded) != 1 || diff.ProjectsAdded[0] != "second project" {
+		t.Fatalf("unexpected diff: %+v", diff)
+	}
+}
@@ -25,10 +25,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
+	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/golang/protobuf/ptypes"
 	ipfs "github.com/ipfs/go-ipfs-api"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -36,13 +37,32 @@ import (
 
 	"github.com/will-rowe/scribe/src/backend"
 	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/records"
 )
 
+// pushDelay is how long listen waits for more updates before pushing a changed database,
+// and shutdownPushTimeout is how long it waits for any outstanding push when shutting down
+const (
+	pushDelay           = 5 * time.Second
+	shutdownPushTimeout = 30 * time.Second
+)
+
+// listenProjects are the projects to listen for (defaults to the project in the config)
+var listenProjects *[]string
+
+// listenMessage is a pubsub message received for one of the listened projects
+type listenMessage struct {
+	project string
+	msg     *ipfs.Message
+}
+
 // listenCmd represents the listen command
 var listenCmd = &cobra.Command{
 	Use:   "listen",
 	Short: "Listen for project updates that are being pushed to the network",
 	Long: `Listen for project updates that are being pushed to the network.
+
+Several projects can be listened for at once by repeating the --project flag.
 	
 This command uses the pubsub protocol, which is currently an experimental IPFS
 feature.`,
@@ -54,6 +74,7 @@ feature.`,
 // init the subcommand
 func init() {
 	rootCmd.AddCommand(listenCmd)
+	listenProjects = listenCmd.Flags().StringSliceP("project", "p", nil, "Project to listen for (can be repeated, defaults to the config project)")
 }
 
 // runListen is the main block for the listen subcommand
@@ -75,97 +96,178 @@ func runListen() {
 		log.Infof("config file changed: %v", e.Name)
 	})
 
-	// configure the daemon
-	log.Info("configuring IPFS daemon...")
+	// get the config and start the node
 	config, err := config.DumpConfig2Mem()
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := backend.ConfigureDaemon(config); err != nil {
-		log.Fatal(err)
-	}
-	log.Infof("\tupdated IPFS daemon using the scribe config")
-
-	// check if the IPFS daemon is running (launch if not)
-	tmpShell := ipfs.NewShell(backend.GetAPI())
-	if !tmpShell.IsUp() {
-		log.Infof("\tlaunching daemon")
-		if err := backend.LaunchDaemon(config); err != nil {
-			log.Fatal(err)
-		}
-	}
-	log.Infof("\tdaemon is running")
-
-	// init the node
-	log.Info("initialising the node...")
-	node, err := backend.InitNode(backend.GetAPI())
-	if err != nil {
-		log.Fatal(err)
-	}
-	nodeIdentity, err := node.Identity()
-	if err != nil {
-		log.Fatal(err)
+	projects := *listenProjects
+	if len(projects) == 0 {
+		projects = []string{config.Project}
 	}
-	log.Infof("\tAPI server listening on: %s", backend.GetAPI())
-	log.Info("\tswarm and gateway info to go here...")
-	log.Infof("\tnode identity: %v", nodeIdentity.ID)
 
-	// create context
-	ctx, cancel := context.WithCancel(context.Background())
+	// create a context which is cancelled on an interrupt, for graceful close down
+	ctx, cancel := interruptContext()
 	defer cancel()
-
-	// subscribe to the requested project
-	log.Info("subscribing the node...")
-	if err := node.Subscribe(ctx, viper.GetString("project")); err != nil {
-		log.Fatal(err)
+	node, isOffline := startNode(ctx, config)
+	if isOffline {
+		log.Fatal("listening for project updates requires pubsub, which is not available offline")
 	}
 	defer func() {
-		if err := node.Unsubscribe(); err != nil {
-			log.Fatal(err)
+		if err := node.Close(); err != nil {
+			log.Warn(err)
 		}
 	}()
-	log.Infof("\tlistening for: %v", viper.GetString("project"))
 
-	// setup the pubsub listener
-	msgChan := make(chan *ipfs.Message)
-	errChan := make(chan error, 1)
-	sigChan := make(chan struct{})
-	go node.Listen(msgChan, errChan, sigChan)
+	// load the local copy of the project database, which the updates will be applied to
+	log.Info("loading the local project database...")
+	replica, err := records.NewReplica(ctx, node, config.RemoteCID)
+	if err != nil {
+		checkNodeErr(err)
+	}
+	log.Infof("\tnumber of projects in local database: %d", replica.DB.GetNumProjects())
 
-	// catch the os interupt for graceful close down
-	c := make(chan os.Signal, 2)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// subscribe to the requested projects, each of which will resubscribe if its subscription is lost
+	log.Info("subscribing the node...")
+	msgChan := make(chan listenMessage)
+	eventChan := make(chan backend.ListenerEvent)
+	var wg sync.WaitGroup
+	for _, project := range projects {
+		sub, err := node.Subscribe(ctx, project)
+		if err != nil {
+			checkNodeErr(err)
+		}
+		log.Infof("\tlistening for: %v", project)
+		wg.Add(1)
+		go func(sub *backend.ProjectSubscription) {
+			defer wg.Done()
+			forwardSubscription(sub, msgChan, eventChan)
+		}(sub)
+	}
+	done := make(chan struct{})
 	go func() {
-
-		// wait for interupt
-		<-c
-		log.Info("interrupt received - shutting down")
-
-		// quit the PubSub listener
-		close(sigChan)
-		os.Exit(0)
+		wg.Wait()
+		close(done)
 	}()
 
-	// process incoming messages
+	// process incoming messages until the subscriptions are cancelled, pushing
+	// the database once the updates have settled
+	var push <-chan time.Time
 	for {
 		select {
 
-		// collect any messages
-		case msg := <-msgChan:
+		// collect any messages and apply them to the local database
+		case received := <-msgChan:
+			msg := printMessage(received)
+			if msg == nil {
+				continue
+			}
+			changed, err := replica.Apply(ctx, msg)
+			if err != nil {
+				log.Warnf("\tcould not apply update: %v", err)
+				continue
+			}
+			if replica.Head != config.RemoteCID {
+				log.Info("\tmerged new project database")
+				setRemoteCID(config, replica.Head)
+			}
+			if changed && push == nil {
+				push = time.After(pushDelay)
+			}
 
-			// check the message over
-			log.Infof("\tmessage received from: %v", msg.From.Pretty())
-			log.Infof("\tcontent: %v", msg.Data)
+		// push the changed database
+		case <-push:
+			push = nil
+			if err := pushReplica(ctx, node, config, replica); err != nil {
+				log.Warnf("could not push the project database, will retry: %v", err)
+				push = time.After(pushDelay)
+			}
 
-			// handle it
-			//var doc map[string]interface{}
-			//json.Unmarshal([]byte(s), &doc)
-			//context, hasContext := doc["@context"]
+		// report any changes to the subscriptions
+		case event := <-eventChan:
+			switch event.State {
+			case backend.StateDisconnected:
+				log.Warnf("subscription to %v lost: %v", event.Project, event.Err)
+			case backend.StateReconnecting:
+				if event.Err != nil {
+					log.Warnf("\tresubscribe attempt failed: %v", event.Err)
+				}
+				log.Infof("resubscribing to %v (attempt %d)...", event.Project, event.Attempt)
+			case backend.StateConnected:
+				log.Infof("\tsubscription to %v connected", event.Project)
+			}
 
-		// collect any errors from the PubSub
-		case err := <-errChan:
-			log.Warn(err)
+		// wait for the subscriptions to finish
+		case <-done:
+			if push != nil {
+				pushCtx, cancel := context.WithTimeout(context.Background(), shutdownPushTimeout)
+				if err := pushReplica(pushCtx, node, config, replica); err != nil {
+					log.Warnf("could not push the project database: %v", err)
+				}
+				cancel()
+			}
+			log.Info("shutting down")
+			return
 		}
+	}
+}
 
+// forwardSubscription will multiplex the messages and events from a subscription onto shared channels, until it is cancelled
+func forwardSubscription(sub *backend.ProjectSubscription, msgChan chan<- listenMessage, eventChan chan<- backend.ListenerEvent) {
+	messages, events := sub.Messages(), sub.Events()
+	for messages != nil || events != nil {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				messages = nil
+				continue
+			}
+			msgChan <- listenMessage{project: sub.Project(), msg: msg}
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			eventChan <- event
+		}
+	}
+}
+
+// printMessage will decode a message envelope and log the event it describes
+//
+// It returns nil if the message can't be decoded or is not about the project it was received for.
+func printMessage(received listenMessage) *records.Message {
+	msg, err := records.DecodeMessage(received.msg.Data)
+	if err != nil {
+		log.Warnf("could not decode message for %v from %v: %v", received.project, received.msg.From.Pretty(), err)
+		return nil
+	}
+	log.Infof("message received for %v:", received.project)
+	log.Infof("\tevent: %v", msg.Describe())
+	log.Infof("\tsender: %v", msg.GetSender())
+	if timestamp, err := ptypes.Timestamp(msg.GetTimestamp()); err == nil {
+		log.Infof("\tsent: %v", timestamp.Local().Format(time.RFC3339))
+	}
+	log.Infof("\tid: %v", msg.GetId())
+	if msg.GetSender() != received.msg.From.Pretty() {
+		log.Warnf("\tsender does not match the message source: %v", received.msg.From.Pretty())
+	}
+	if msg.GetProject() != received.project {
+		log.Warnf("\tignoring message about a different project: %v", msg.GetProject())
+		return nil
+	}
+	return msg
+}
+
+// pushReplica will push the local copy of the project database, record the new CID and announce it
+func pushReplica(ctx context.Context, node *backend.Node, conf *config.ScribeConfig, replica *records.Replica) error {
+	log.Info("pushing database changes to IPFS...")
+	previousCID := replica.Head
+	cid, err := replica.Push(ctx)
+	if err != nil {
+		return err
 	}
+	setRemoteCID(conf, cid)
+	announceHead(ctx, node, conf, previousCID, false)
+	return nil
 }
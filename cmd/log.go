@@ -0,0 +1,122 @@
+/*
+Copyright © 2020 Will Rowe <w.p.m.rowe@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/records"
+)
+
+// historyLimit is the maximum number of versions to print (0 for all)
+var historyLimit *int
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:   "log [CID]",
+	Short: "Show the version history of the project database",
+	Long: `Show the version history of the project database.
+
+The history is walked back from the CID of the project database in the config,
+or from the provided CID, printing who made each change, when, and what it was.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runLog(args)
+	},
+}
+
+// init the subcommand
+func init() {
+	rootCmd.AddCommand(logCmd)
+	historyLimit = logCmd.Flags().IntP("number", "n", 0, "Maximum number of versions to show (0 for all)")
+}
+
+// runLog is the main block for the log subcommand
+func runLog(args []string) {
+
+	// run the config checker to make sure we've got everything
+	if err := config.CheckConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// start the subcommand
+	log.Info("------------SCRIBE------------")
+	log.Info("starting the log subcommand...")
+	log.Infof("\tconfig file: %v", viper.ConfigFileUsed())
+
+	// get the config and start the node
+	config, err := config.DumpConfig2Mem()
+	if err != nil {
+		log.Fatal(err)
+	}
+	head := config.RemoteCID
+	if len(args) != 0 {
+		head = args[0]
+	}
+	if len(head) == 0 {
+		log.Fatal("no project database CID found, use `scribe add` to create a database")
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	node, _ := startNode(ctx, config)
+	defer node.Close()
+
+	// walk the history and print it
+	log.Infof("walking the history from: %v", head)
+	history, err := records.History(ctx, node, head, *historyLimit)
+	for _, entry := range history {
+		printVersion(entry)
+	}
+	if err != nil {
+		checkNodeErr(err)
+	}
+}
+
+// printVersion will print a version of the project database, in the style of `git log`
+func printVersion(entry records.VersionEntry) {
+	fmt.Printf("version %v\n", entry.CID)
+	if entry.Version == nil {
+		fmt.Printf("\n    (no version recorded)\n\n")
+		return
+	}
+	if len(entry.Version.GetMergedCID()) != 0 {
+		fmt.Printf("Merge:  %v\n", entry.Version.GetMergedCID())
+	}
+	author := entry.Version.GetAuthor()
+	if len(author) == 0 {
+		author = "unknown (offline)"
+	}
+	fmt.Printf("Author: %v\n", author)
+	if timestamp, err := ptypes.Timestamp(entry.Version.GetTimestamp()); err == nil {
+		fmt.Printf("Date:   %v\n", timestamp.Local().Format(time.RFC1123Z))
+	}
+	fmt.Printf("\n    %v\n\n", entry.Version.GetSummary())
+}
@@ -0,0 +1,255 @@
+/*
+Copyright © 2020 Will Rowe <w.p.m.rowe@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/will-rowe/scribe/src/backend"
+	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/records"
+)
+
+// offline is set by the persistent --offline flag
+var offline bool
+
+// daemonCheckTimeout is how long to wait when checking if the IPFS daemon is running
+const daemonCheckTimeout = 5 * time.Second
+
+// startNode will start the IPFS node (either the daemon or the embedded node) and initialise a node that uses it
+//
+// If offline mode was requested, or the daemon can't be used, the node will use the local block
+// store in the scribe data directory instead. Content created offline can be uploaded later with
+// `scribe sync`. The returned bool is true if the node is using the local block store.
+func startNode(ctx context.Context, conf *config.ScribeConfig) (*backend.Node, bool) {
+	if offline {
+		log.Info("offline mode requested...")
+		return startLocalNode(ctx, conf), true
+	}
+
+	// use the in-process IPFS node if requested
+	var node *backend.Node
+	var err error
+	if conf.Embedded {
+		log.Info("starting the embedded IPFS node...")
+		store, err := backend.NewEmbeddedStore(conf)
+		if err != nil {
+			log.Warnf("\tcould not start the embedded IPFS node: %v", err)
+			return startLocalNode(ctx, conf), true
+		}
+		log.Infof("\tembedded node is running")
+		log.Info("initialising the node...")
+		if node, err = backend.InitNodeWithStore(ctx, store); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		node = startDaemonNode(ctx, conf)
+		if node == nil {
+			return startLocalNode(ctx, conf), true
+		}
+	}
+	node.SetRetryPolicy(retryPolicy(conf))
+	nodeIdentity, err := node.Identity(ctx)
+	if err != nil {
+		checkNodeErr(err)
+	}
+	log.Infof("\tnode identity: %v", nodeIdentity.ID)
+
+	// let the user know if there is offline content waiting
+	if store, err := backend.NewFileStore(conf.DataDir); err == nil {
+		if n, err := store.Unsynced(); err == nil && n != 0 {
+			log.Warnf("\t%d records were created offline, run `scribe sync` to upload them", n)
+		}
+	}
+	return node, false
+}
+
+// startDaemonNode will configure and launch the IPFS daemon if needed, then initialise a node that uses it
+//
+// It returns nil if the daemon can't be used.
+func startDaemonNode(ctx context.Context, conf *config.ScribeConfig) *backend.Node {
+
+	// configure the daemon
+	if err := configureDaemon(conf); err != nil {
+		log.Warnf("\tcould not configure the IPFS daemon: %v", err)
+		return nil
+	}
+
+	// check if the IPFS daemon is running (launch if not)
+	if !daemonIsUp(ctx, conf) {
+		log.Infof("\tlaunching daemon...")
+		if err := backend.LaunchDaemon(conf); err != nil {
+			log.Warnf("\tcould not launch the IPFS daemon: %v", err)
+			return nil
+		}
+	}
+	log.Infof("\tdaemon is running")
+
+	// init the node
+	log.Info("initialising the node...")
+	node, err := backend.InitNode(ctx, backend.GetAPI(conf))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("\tAPI server listening on: %s", backend.GetAPI(conf))
+	return node
+}
+
+// configureDaemon will reconcile the IPFS config with the scribe config, reporting any changes
+func configureDaemon(conf *config.ScribeConfig) error {
+	log.Info("configuring IPFS daemon...")
+	report, err := backend.ConfigureDaemon(conf)
+	if err != nil {
+		return err
+	}
+	if len(report.Changes) == 0 {
+		log.Info("\tIPFS config matches the scribe config")
+		return nil
+	}
+	for _, change := range report.Changes {
+		log.Infof("\tupdated %v", change)
+	}
+	if report.Restart {
+		log.Warn("\tthe running IPFS daemon needs restarting for these changes to take effect (`scribe daemon restart`)")
+	}
+	return nil
+}
+
+// startLocalNode will initialise a node that uses the local block store
+func startLocalNode(ctx context.Context, conf *config.ScribeConfig) *backend.Node {
+	log.Info("initialising the node using the local block store...")
+	store, err := backend.NewFileStore(conf.DataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if peerID, err := backend.RepoPeerID(conf); err == nil {
+		store.SetID(peerID)
+	}
+	node, err := backend.InitNodeWithStore(ctx, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+	node.SetRetryPolicy(retryPolicy(conf))
+	log.Infof("\tblock store: %v", conf.DataDir)
+	log.Info("\tpubsub is not available, run `scribe sync` once the IPFS daemon is up")
+	return node
+}
+
+// daemonIsUp checks if the IPFS daemon is answering on the configured API address
+func daemonIsUp(ctx context.Context, conf *config.ScribeConfig) bool {
+	ctx, cancel := context.WithTimeout(ctx, daemonCheckTimeout)
+	defer cancel()
+	return backend.NewShellStore(backend.GetAPI(conf)).IsUp(ctx)
+}
+
+// retryPolicy returns the node retry policy for the config
+func retryPolicy(conf *config.ScribeConfig) backend.RetryPolicy {
+	policy := backend.DefaultRetryPolicy
+	policy.Attempts = conf.Retries + 1
+
+	// the timeout has already been checked by config.CheckConfig
+	policy.Timeout, _ = time.ParseDuration(conf.Timeout)
+	return policy
+}
+
+// interruptContext returns a context that is cancelled if scribe is interrupted
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigs:
+			log.Warn("interrupt received - cancelling")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+	return ctx, cancel
+}
+
+// checkNodeErr will exit on a node error, giving the user a hint about what went wrong
+func checkNodeErr(err error) {
+	if err == nil {
+		return
+	}
+	switch {
+	case errors.Is(err, backend.ErrTimeout):
+		log.Warn("the IPFS node did not respond in time, check it with `scribe daemon status` or increase `scribe set --timeout`")
+	case errors.Is(err, backend.ErrOffline):
+		log.Warn("the IPFS node can't be reached, check it with `scribe daemon status` or use `--offline`")
+	case errors.Is(err, backend.ErrNotFound):
+		log.Warn("the requested content could not be found, check the remoteCID in the config")
+	case errors.Is(err, backend.ErrDaemon):
+		log.Warn("the IPFS node rejected the request, check `scribe daemon logs`")
+	}
+	log.Fatal(err)
+}
+
+// announce will publish a message envelope to its project, warning if it can't reach the network
+func announce(ctx context.Context, node *backend.Node, msg *records.Message) {
+	err := records.Announce(ctx, node, msg)
+	switch {
+	case err == nil:
+		log.Infof("\tannounced %v", msg.Describe())
+	case errors.Is(err, backend.ErrOffline), errors.Is(err, backend.ErrTimeout):
+		log.Warnf("\tcould not announce %v: %v", msg.Describe(), err)
+	default:
+		checkNodeErr(err)
+	}
+}
+
+// announceHead will announce a new head of the project database, unless the node is offline
+func announceHead(ctx context.Context, node *backend.Node, conf *config.ScribeConfig, previousCID string, isOffline bool) {
+	if isOffline {
+		log.Info("\tskipping announcement as node is offline")
+		return
+	}
+	msg := records.NewMessage(conf.Project)
+	msg.Payload = &records.Message_DatabaseHeadChanged{
+		DatabaseHeadChanged: &records.DatabaseHeadChanged{
+			CID:         conf.RemoteCID,
+			PreviousCID: previousCID,
+		},
+	}
+	announce(ctx, node, msg)
+}
+
+// setRemoteCID will record the CID of the project database in the config
+func setRemoteCID(conf *config.ScribeConfig, cid string) {
+	log.Info("\tupdating CID...")
+	conf.RemoteCID = cid
+	viper.Set("remoteCID", cid)
+	if err := viper.WriteConfig(); err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("\tCID updated: %s", conf.RemoteCID)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright © 2020 Will Rowe <w.p.m.rowe@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/will-rowe/scribe/src/backend"
+	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/records"
+)
+
+// maxAttempts is the number of times a tagged service is requested before a run fails
+var maxAttempts *uint32
+
+// orchestrateCmd represents the orchestrate command
+var orchestrateCmd = &cobra.Command{
+	Use:   "orchestrate",
+	Short: "Send the tagged runs of a project to their services",
+	Long: `Send the tagged runs of a project to their services.
+
+For each tagged run, a request is sent to the next service in the run's request
+order, which is then waited for before requesting the following service. Once
+every service is done, the run is announced. New versions of the project database
+announced by other nodes are merged before any changes are pushed.
+
+This command uses the pubsub protocol, which is currently an experimental IPFS
+feature.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runOrchestrate()
+	},
+}
+
+// init the subcommand
+func init() {
+	rootCmd.AddCommand(orchestrateCmd)
+	maxAttempts = orchestrateCmd.Flags().Uint32("max-attempts", records.DefaultMaxAttempts, "Number of times a service is requested before the run fails")
+}
+
+// runOrchestrate is the main block for the orchestrate subcommand
+func runOrchestrate() {
+
+	// run the config checker to make sure we've got everything
+	if err := config.CheckConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// start the subcommand
+	log.Info("------------SCRIBE------------")
+	log.Info("starting the orchestrate subcommand...")
+	log.Infof("\tconfig file: %v", viper.ConfigFileUsed())
+
+	// get the config and start the node
+	config, err := config.DumpConfig2Mem()
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	node, isOffline := startNode(ctx, config)
+	if isOffline {
+		log.Fatal("orchestrating runs requires pubsub, which is not available offline")
+	}
+	defer func() {
+		if err := node.Close(); err != nil {
+			log.Warn(err)
+		}
+	}()
+
+	// load the local copy of the project database
+	log.Info("loading the local project database...")
+	orchestrator, err := records.NewOrchestrator(ctx, node, config.RemoteCID, config.Project)
+	if err != nil {
+		checkNodeErr(err)
+	}
+	orchestrator.MaxAttempts = *maxAttempts
+	log.Infof("\torchestrating: %v", orchestrator.Project)
+
+	// subscribe before sending any requests, so that no replies are missed
+	log.Info("subscribing the node...")
+	sub, err := node.Subscribe(ctx, config.Project)
+	if err != nil {
+		checkNodeErr(err)
+	}
+	msgChan := make(chan listenMessage)
+	eventChan := make(chan backend.ListenerEvent)
+	done := make(chan struct{})
+	go func() {
+		forwardSubscription(sub, msgChan, eventChan)
+		close(done)
+	}()
+
+	// request the next service for each tagged run
+	log.Info("dispatching the tagged runs...")
+	checkOrchestratorErr(ctx, node, config, orchestrator, orchestrator.Dispatch(ctx), "")
+
+	// apply the replies from the services until the subscription is cancelled
+	for {
+		select {
+		case received := <-msgChan:
+			msg := printMessage(received)
+			if msg == nil {
+				continue
+			}
+			_, err := orchestrator.Apply(ctx, msg)
+			checkOrchestratorErr(ctx, node, config, orchestrator, err, msg.GetDatabaseHeadChanged().GetCID())
+
+		case event := <-eventChan:
+			switch event.State {
+			case backend.StateDisconnected:
+				log.Warnf("subscription to %v lost, replies may be missed: %v", event.Project, event.Err)
+			case backend.StateConnected:
+				log.Infof("\tsubscription to %v connected", event.Project)
+
+				// send any requests again, in case they were lost while the subscription was down
+				checkOrchestratorErr(ctx, node, config, orchestrator, orchestrator.Dispatch(ctx), "")
+			}
+
+		case <-done:
+			log.Info("shutting down")
+			return
+		}
+	}
+}
+
+// checkOrchestratorErr will report an orchestrator error and record any new head of the project database
+//
+// A new head is announced, unless it is the head that was announced by another node.
+func checkOrchestratorErr(ctx context.Context, node *backend.Node, conf *config.ScribeConfig, orchestrator *records.Orchestrator, err error, announcedCID string) {
+	switch {
+	case err == nil:
+	case errors.Is(err, records.ErrNotAnnounced):
+		log.Warnf("\tcould not announce the change: %v", err)
+	default:
+		log.Warnf("\tcould not orchestrate the runs: %v", err)
+	}
+	if orchestrator.Head != conf.RemoteCID {
+		previousCID := conf.RemoteCID
+		setRemoteCID(conf, orchestrator.Head)
+		if orchestrator.Head != announcedCID {
+			announceHead(ctx, node, conf, previousCID, false)
+		}
+	}
+}
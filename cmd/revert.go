@@ -0,0 +1,105 @@
+/*
+Copyright © 2020 Will Rowe <w.p.m.rowe@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/records"
+)
+
+// revertCmd represents the revert command
+var revertCmd = &cobra.Command{
+	Use:   "revert <version>",
+	Short: "Restore the project database to an earlier version",
+	Long: `Restore the project database to an earlier version.
+
+The version is given as a CID or as a reference to the history of the project
+database in the config (HEAD~1, HEAD~2...), and must be in that history.
+
+History is not rewritten; the earlier database is pushed as a new version on
+top of the current one, the config is updated and the new head is announced.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRevert(args[0])
+	},
+}
+
+// init the subcommand
+func init() {
+	rootCmd.AddCommand(revertCmd)
+}
+
+// runRevert is the main block for the revert subcommand
+func runRevert(ref string) {
+
+	// run the config checker to make sure we've got everything
+	if err := config.CheckConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// start the subcommand
+	log.Info("------------SCRIBE------------")
+	log.Info("starting the revert subcommand...")
+	log.Infof("\tconfig file: %v", viper.ConfigFileUsed())
+
+	// get the config and start the node
+	config, err := config.DumpConfig2Mem()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(config.RemoteCID) == 0 {
+		log.Fatal("no project database CID found, use `scribe add` to create a database")
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	node, isOffline := startNode(ctx, config)
+	defer node.Close()
+	node.SetProject(config.Project)
+
+	// resolve the version and revert to it
+	log.Info("reverting the project database...")
+	target, err := records.ResolveRef(ctx, node, config.RemoteCID, ref)
+	if err != nil {
+		checkNodeErr(err)
+	}
+	log.Infof("\tcurrent version: %v", config.RemoteCID)
+	log.Infof("\treverting to: %v", target)
+	previousCID := config.RemoteCID
+	db, cid, err := records.Revert(ctx, node, previousCID, target)
+	if err != nil {
+		checkNodeErr(err)
+	}
+	log.Infof("\tnumber of projects in the reverted database: %d", db.GetNumProjects())
+	setRemoteCID(config, cid)
+	log.Infof("\tview on: %v", fmt.Sprintf("https://explore.ipld.io/#/explore/%s", config.RemoteCID))
+
+	// announce the new head
+	announceHead(ctx, node, config, previousCID, isOffline)
+}
@@ -65,6 +65,7 @@ func init() {
 	// persistent flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.scribe)")
 	rootCmd.PersistentFlags().Bool("private", false, "run scribe in private mode")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "use the local block store instead of the IPFS daemon")
 
 	// bind flags to the config
 	viper.BindPFlag("private", rootCmd.PersistentFlags().Lookup("private")) // update config to private mode
@@ -108,6 +109,14 @@ func initConfig() {
 		viper.AddConfigPath(config.DefaultLocation)
 	}
 
+	// fill in defaults for any fields missing from older configs
+	viper.SetDefault("dataDir", config.DefaultDataDir)
+	viper.SetDefault("apiAddress", config.DefaultAPIAddress)
+	viper.SetDefault("swarmAddress", config.DefaultSwarmAddress)
+	viper.SetDefault("gatewayAddress", config.DefaultGatewayAddress)
+	viper.SetDefault("timeout", config.DefaultTimeout)
+	viper.SetDefault("retries", config.DefaultRetries)
+
 	// read in environment variables that match
 	viper.AutomaticEnv()
 
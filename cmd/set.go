@@ -27,18 +27,26 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/will-rowe/scribe/src/backend"
 	"github.com/will-rowe/scribe/src/config"
 )
 
 // set up the flags
 var (
-	reset      *bool
-	echo       *bool
-	ipfsPath   *string
-	storageMax *string
-	remoteCID  *string
-	pinning    *bool
-	project    *string
+	reset       *bool
+	echo        *bool
+	ipfsPath    *string
+	dataDir     *string
+	embedded    *bool
+	storageMax  *string
+	apiAddr     *string
+	swarmAddr   *string
+	gatewayAddr *string
+	timeout     *string
+	retries     *int
+	remoteCID   *string
+	pinning     *bool
+	project     *string
 )
 
 // setCmd represents the set command
@@ -50,7 +58,7 @@ var setCmd = &cobra.Command{
 You can edit the config directly but this subcommand offers some checks for the fields being set.
 An attempt will be made to create any directories that don't exist.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runSet()
+		runSet(cmd)
 	},
 }
 
@@ -62,21 +70,35 @@ func init() {
 	reset = setCmd.Flags().Bool("reset", false, "Reset the config to default by replacing any existing config file (any other command flags will be set afterwards)")
 	echo = setCmd.Flags().Bool("echo", false, "Print the config to screen after setting values")
 	ipfsPath = setCmd.Flags().String("ipfsPath", config.DefaultIpfsPath, "Path to the IPFS repository on this node")
+	dataDir = setCmd.Flags().String("dataDir", config.DefaultDataDir, "Path to the scribe data directory on this node (used for offline storage)")
+	embedded = setCmd.Flags().Bool("embedded", false, "Run an in-process IPFS node instead of using the ipfs binary (requires a build with `-tags embedded`)")
 	storageMax = setCmd.Flags().String("storageMax", config.DefaultStorageMax, "Maximum storage available for the IPFS repository")
+	apiAddr = setCmd.Flags().String("apiAddress", config.DefaultAPIAddress, "Multiaddr for the IPFS daemon API")
+	swarmAddr = setCmd.Flags().String("swarmAddress", config.DefaultSwarmAddress, "Multiaddr for the IPFS daemon to listen for peers on")
+	gatewayAddr = setCmd.Flags().String("gatewayAddress", config.DefaultGatewayAddress, "Multiaddr for the IPFS daemon gateway")
+	timeout = setCmd.Flags().String("timeout", config.DefaultTimeout, "Timeout for each request to the IPFS node (e.g. 30s, 0 for none)")
+	retries = setCmd.Flags().Int("retries", config.DefaultRetries, "Number of times to retry requests when the IPFS node can't be reached")
 	remoteCID = setCmd.Flags().String("remoteCID", "", "The CID of the remote project database")
 	pinning = setCmd.Flags().Bool("pinning", true, "Pin IPFS objects (which will prevent local garabage collection)")
 	project = setCmd.Flags().String("project", config.DefaultProject, "Project to operate on (add|update|listen)")
 
 	// bind local flags to the config
 	viper.BindPFlag("ipfsPath", setCmd.LocalFlags().Lookup("ipfsPath"))
+	viper.BindPFlag("dataDir", setCmd.LocalFlags().Lookup("dataDir"))
+	viper.BindPFlag("embedded", setCmd.LocalFlags().Lookup("embedded"))
 	viper.BindPFlag("storageMax", setCmd.LocalFlags().Lookup("storageMax"))
+	viper.BindPFlag("apiAddress", setCmd.LocalFlags().Lookup("apiAddress"))
+	viper.BindPFlag("swarmAddress", setCmd.LocalFlags().Lookup("swarmAddress"))
+	viper.BindPFlag("gatewayAddress", setCmd.LocalFlags().Lookup("gatewayAddress"))
+	viper.BindPFlag("timeout", setCmd.LocalFlags().Lookup("timeout"))
+	viper.BindPFlag("retries", setCmd.LocalFlags().Lookup("retries"))
 	viper.BindPFlag("remoteCID", setCmd.LocalFlags().Lookup("remoteCID"))
 	viper.BindPFlag("Pinning", setCmd.LocalFlags().Lookup("pinning"))
 	viper.BindPFlag("project", setCmd.LocalFlags().Lookup("project"))
 }
 
 // runSet is the main block for the set subcommand
-func runSet() {
+func runSet(cmd *cobra.Command) {
 
 	// reset takes precedence - it will replace any config currently on disk
 	if *reset {
@@ -91,12 +113,24 @@ func runSet() {
 	}
 
 	// the in-memory Viper config will have picked up any set flags
-	// run the config checker to make sure they are legit
-	if err := config.CheckConfig(); err != nil {
+	// run the config checker to make sure they are legit (and that any new addresses are free)
+	newAddresses := []string{}
+	for _, key := range config.AddressKeys {
+		if cmd.Flags().Changed(key) {
+			newAddresses = append(newAddresses, key)
+		}
+	}
+	if err := config.CheckConfig(newAddresses...); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
+	// the embedded IPFS node is only available in some builds
+	if viper.GetBool("embedded") && !backend.EmbeddedSupport {
+		fmt.Println(backend.ErrNoEmbedded)
+		os.Exit(1)
+	}
+
 	// now safe to update the config on disk
 	if err := viper.WriteConfig(); err != nil {
 		fmt.Println(err)
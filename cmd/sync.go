@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 Will Rowe <w.p.m.rowe@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/will-rowe/scribe/src/backend"
+	"github.com/will-rowe/scribe/src/config"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Upload records created offline to the IPFS",
+	Long: `Upload records created offline to the IPFS.
+
+When the IPFS daemon is not available, scribe stores records in a local block store
+in the scribe data directory. This command uploads every record created offline
+to the IPFS daemon, checking that the CIDs match the local ones.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runSync()
+	},
+}
+
+// init the subcommand
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
+
+// runSync is the main block for the sync subcommand
+func runSync() {
+
+	// run the config checker to make sure we've got everything
+	if err := config.CheckConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// start the subcommand
+	log.Info("------------SCRIBE------------")
+	log.Info("starting the sync subcommand...")
+	log.Infof("\tconfig file: %v", viper.ConfigFileUsed())
+
+	// open the local block store
+	config, err := config.DumpConfig2Mem()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Info("checking the local block store...")
+	localStore, err := backend.NewFileStore(config.DataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	unsynced, err := localStore.Unsynced()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("\trecords waiting to be synced: %d", unsynced)
+	if unsynced == 0 {
+		return
+	}
+
+	// start the node, syncing needs the daemon
+	if offline {
+		log.Fatal("can't sync in offline mode")
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	node, isOffline := startNode(ctx, config)
+	if isOffline {
+		log.Fatal("can't sync as the IPFS node is not available")
+	}
+	defer node.Close()
+
+	// upload the records
+	log.Info("syncing records...")
+	synced, err := localStore.Sync(ctx, node.GetStore(), config.Pinning)
+	log.Infof("\trecords synced: %d", synced)
+	if err != nil {
+		checkNodeErr(err)
+	}
+	log.Info("\tfinished")
+}
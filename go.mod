@@ -4,14 +4,25 @@ go 1.14
 
 require (
 	github.com/fsnotify/fsnotify v1.4.9
-	github.com/gogo/protobuf v1.2.1
 	github.com/golang/protobuf v1.3.4
+	github.com/ipfs/go-block-format v0.0.2
+	github.com/ipfs/go-cid v0.0.5
+	github.com/ipfs/go-ipfs v0.5.1
 	github.com/ipfs/go-ipfs-api v0.0.3
+	github.com/ipfs/go-ipfs-config v0.5.3
+	github.com/ipfs/go-ipfs-files v0.0.8
+	github.com/ipfs/go-ipld-cbor v0.0.4
+	github.com/ipfs/go-ipld-format v0.2.0
+	github.com/ipfs/interface-go-ipfs-core v0.2.7
+	github.com/libp2p/go-libp2p-peer v0.2.0
 	github.com/mitchellh/go-homedir v1.1.0
+	github.com/multiformats/go-multiaddr v0.2.1
+	github.com/multiformats/go-multiaddr-net v0.1.5
+	github.com/multiformats/go-multihash v0.0.13
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/cobra v0.0.6
 	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.6.2
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.5.1
 	gopkg.in/fsnotify.v1 v1.4.7
 )
@@ -0,0 +1,166 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// blockstore is used by the local Store implementations to hold content-addressed blocks
+type blockstore interface {
+	getBlock(ctx context.Context, c cid.Cid) ([]byte, error)
+	putBlock(ctx context.Context, c cid.Cid, data []byte) error
+}
+
+// addFile will chunk the content into a UnixFS DAG, store the blocks and return the root CID
+func addFile(ctx context.Context, bs blockstore, content []byte) (cid.Cid, error) {
+	blocks, err := layoutFile(content)
+	if err != nil {
+		return cid.Undef, err
+	}
+	for _, b := range blocks {
+		if err := bs.putBlock(ctx, b.cid, b.data); err != nil {
+			return cid.Undef, err
+		}
+	}
+	return blocks[len(blocks)-1].cid, nil
+}
+
+// catFile walks a UnixFS DAG and concatenates the leaves
+func catFile(ctx context.Context, bs blockstore, c cid.Cid) ([]byte, error) {
+	data, err := bs.getBlock(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Type() {
+	case cid.Raw:
+		return data, nil
+	case cid.DagProtobuf:
+		links, err := fileLinks(data)
+		if err != nil {
+			return nil, err
+		}
+		buf := new(bytes.Buffer)
+		for _, link := range links {
+			chunk, err := catFile(ctx, bs, link)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(chunk)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("can't cat a non-file node (%v)", c)
+	}
+}
+
+// dagPut will store an IPLD node and return its CID
+//
+// JSON and CBOR input encodings are supported, the output format must be CBOR
+func dagPut(ctx context.Context, bs blockstore, data []byte, encoding, format string) (cid.Cid, error) {
+	if format != "cbor" {
+		return cid.Undef, fmt.Errorf("unsupported DAG format for local store: %v", format)
+	}
+	var nd *cbornode.Node
+	var err error
+	switch encoding {
+	case "json":
+		nd, err = cbornode.FromJSON(bytes.NewReader(data), mh.Names[MultiHash], -1)
+	case "cbor", "raw":
+		nd, err = cbornode.Decode(data, mh.Names[MultiHash], -1)
+	default:
+		err = fmt.Errorf("unsupported input encoding for local store: %v", encoding)
+	}
+	if err != nil {
+		return cid.Undef, err
+	}
+	return nd.Cid(), bs.putBlock(ctx, nd.Cid(), nd.RawData())
+}
+
+// dagGet will resolve a reference (CID/path) and unmarshal the JSON representation into the output
+func dagGet(ctx context.Context, bs blockstore, ref string, output interface{}) error {
+	c, path, err := splitRef(ref)
+	if err != nil {
+		return err
+	}
+
+	// resolve the path, following links across nodes
+	var value interface{}
+	for value == nil {
+		if c.Type() != cid.DagCBOR {
+			return fmt.Errorf("unsupported DAG format for local store: %v", c)
+		}
+		data, err := bs.getBlock(ctx, c)
+		if err != nil {
+			return err
+		}
+		nd, err := cbornode.Decode(data, mh.Names[MultiHash], -1)
+		if err != nil {
+			return err
+		}
+		if len(path) == 0 {
+			value = nd
+			continue
+		}
+		resolved, rest, err := nd.Resolve(path)
+		if err != nil {
+			return err
+		}
+		link, isLink := resolved.(*ipld.Link)
+		switch {
+		case isLink:
+			c, path = link.Cid, rest
+		case resolved == nil:
+			value = json.RawMessage("null")
+		default:
+			value = resolved
+		}
+	}
+
+	// round trip via JSON, as the IPFS API does
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, output)
+}
+
+// blockPut will store a raw block and return its CID
+func blockPut(ctx context.Context, bs blockstore, block []byte, format, mhType string, mhLen int) (cid.Cid, error) {
+	hash, err := mh.Sum(block, mh.Names[mhType], mhLen)
+	if err != nil {
+		return cid.Undef, err
+	}
+	var c cid.Cid
+	switch format {
+	case "v0":
+		c = cid.NewCidV0(hash)
+	case "raw", "":
+		c = cid.NewCidV1(cid.Raw, hash)
+	default:
+		codec, ok := cid.Codecs[format]
+		if !ok {
+			return cid.Undef, fmt.Errorf("unknown block format: %v", format)
+		}
+		c = cid.NewCidV1(codec, hash)
+	}
+	return c, bs.putBlock(ctx, c, block)
+}
+
+// splitRef splits a reference into the root CID and the path segments
+func splitRef(ref string) (cid.Cid, []string, error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(ref, "/ipfs/"), "/"), "/")
+	c, err := cid.Decode(parts[0])
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return c, parts[1:], nil
+}
@@ -2,85 +2,279 @@
 package backend
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	ipfs "github.com/ipfs/go-ipfs-api"
+
 	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/helpers"
 )
 
-var (
-	apiAddress  = "127.0.0.1"
-	apiPort     = 5001
-	swarmPort   = 4001
-	gatewayPort = 8081
+const (
+	daemonPIDFile = "ipfs.pid" // the file in the data dir that holds the PID of the daemon launched by scribe
+	daemonLogDir  = "logs"     // the directory in the data dir that holds the daemon logs
+	daemonLogFile = "ipfs.log" // the current daemon log
+	maxDaemonLogs = 5          // the number of old daemon logs to keep
 )
 
-// GetAPI returns the API address the daemon will serve the API from
-func GetAPI() string {
-	return fmt.Sprintf("%s:%d", apiAddress, apiPort)
+// ErrNotManaged is returned when trying to manage a daemon that scribe did not launch
+var ErrNotManaged = errors.New("no IPFS daemon launched by scribe is running")
+
+// GetAPI returns the network address the daemon will serve the API from
+//
+// The config address should already have been validated by config.CheckConfig, an empty string is returned if not.
+func GetAPI(conf *config.ScribeConfig) string {
+	addr, err := config.ParseAddress(conf.APIAddress)
+	if err != nil {
+		return ""
+	}
+	return addr.String()
 }
 
-// ConfigureDaemon will configure the IPFS daemon
-func ConfigureDaemon(conf *config.ScribeConfig) error {
+// ConfigureDaemon will reconcile the IPFS config with the scribe config
+//
+// If the daemon is running, the config is read and updated via the HTTP API and the report will
+// say if the daemon needs restarting for the changes to take effect. This includes a daemon still
+// running on the API address in the repo config, from before the scribe API address changed.
+// Otherwise the repo config file is updated directly. Only values that differ from those required
+// by scribe are changed.
+func ConfigureDaemon(conf *config.ScribeConfig) (*ConfigReport, error) {
 
 	// point IPFS to the repo location
 	os.Setenv("IPFS_PATH", conf.IpfsPath)
 
-	// init the daemon
-	// this will return an error if daemon already inited, so ignore that for now
-	cmd := exec.Command("ipfs", "init")
-	cmd.Env = append(cmd.Env, fmt.Sprintf("IPFS_PATH=%s", conf.IpfsPath))
-	cmd.Run()
-
-	// set up the configure commands
-	script := [][]string{
-		{"ipfs", "config", "--json", "Experimental.Libp2pStreamMounting", "true"},
-		{"ipfs", "config", "Datastore.StorageMax", conf.StorageMax},
-		{"ipfs", "config", "Addresses.API", fmt.Sprintf("/ip4/%s/tcp/%d", apiAddress, apiPort)},
-		{"ipfs", "config", "--json", "Addresses.Swarm", fmt.Sprintf("[\"/ip4/%s/tcp/%d\"]", apiAddress, swarmPort)},
-		{"ipfs", "config", "Addresses.Gateway", fmt.Sprintf("/ip4/%s/tcp/%d", apiAddress, gatewayPort)},
-	}
-
-	// run the commands
-	for _, line := range script {
-		cmd := exec.Command(line[0], line[1:]...)
-		cmd.Env = append(cmd.Env, fmt.Sprintf("IPFS_PATH=%s", conf.IpfsPath))
-		err := cmd.Run()
-		if err != nil {
-			return fmt.Errorf("could not configure the IPFS daemon (%v: %v)", err, strings.Join(line, " "))
+	// init the repo if it doesn't have a config yet
+	if !helpers.CheckFileExists(filepath.Join(conf.IpfsPath, "config")) {
+		cmd := exec.Command("ipfs", "init")
+		cmd.Env = append(os.Environ(), fmt.Sprintf("IPFS_PATH=%s", conf.IpfsPath))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("could not init the IPFS repo (%v: %s)", err, strings.TrimSpace(string(out)))
 		}
 	}
-	return nil
+
+	// reconcile the config
+	sh := ipfs.NewShell(GetAPI(conf))
+	if sh.IsUp() {
+		return reconcileAPI(sh, conf)
+	}
+	if api := repoAPI(conf); len(api) != 0 && api != GetAPI(conf) {
+		if sh := ipfs.NewShell(api); sh.IsUp() {
+			return reconcileAPI(sh, conf)
+		}
+	}
+	return reconcileFile(conf)
 }
 
 // LaunchDaemon will attempt to launch the IPFS daemon
-// NOTE: daemon is left running and must be terminated by user
+//
+// The daemon is detached from scribe and left running. Its PID is recorded in the scribe data
+// directory and its output is written to a log file there (see DaemonLogPath), so that it can
+// be managed with StopDaemon and DaemonStatus.
 func LaunchDaemon(conf *config.ScribeConfig) error {
 
-	// launch the daemon if it's not already running
+	// set up the log file, rotating any old ones
+	if err := os.MkdirAll(filepath.Join(conf.DataDir, daemonLogDir), 0755); err != nil {
+		return err
+	}
+	logPath := DaemonLogPath(conf)
+	if err := rotateLogs(logPath, maxDaemonLogs); err != nil {
+		return fmt.Errorf("could not rotate the daemon logs (%v)", err)
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	// launch the daemon
 	daemonCmd := exec.Command("ipfs", "daemon", "--enable-pubsub-experiment")
-	daemonCmd.Env = append(daemonCmd.Env, fmt.Sprintf("IPFS_PATH=%s", conf.IpfsPath))
+	daemonCmd.Env = append(os.Environ(), fmt.Sprintf("IPFS_PATH=%s", conf.IpfsPath))
+	daemonCmd.Stdout = logFile
+	daemonCmd.Stderr = logFile
+	detach(daemonCmd)
 	if err := daemonCmd.Start(); err != nil {
 		return err
 	}
+	if err := helpers.WriteFileAtomic(pidPath(conf), []byte(strconv.Itoa(daemonCmd.Process.Pid))); err != nil {
+		daemonCmd.Process.Kill()
+		return fmt.Errorf("could not record the daemon PID (%v)", err)
+	}
+
+	// watch for the daemon exiting early (e.g. if the repo is locked)
+	exited := make(chan error, 1)
+	go func() {
+		exited <- daemonCmd.Wait()
+	}()
 
 	// wait until the daemon actually offers the API interface
-	connected := false
 	for tries := 0; tries < 200; tries++ {
-		conn, err := net.Dial("tcp", GetAPI())
+		select {
+		case err := <-exited:
+			os.Remove(pidPath(conf))
+			return fmt.Errorf("IPFS daemon exited during launch (%v), see log: %v", err, logPath)
+		default:
+		}
+		conn, err := net.Dial("tcp", GetAPI(conf))
 		if err == nil {
 			conn.Close()
-			connected = true
-			break
+			return nil
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
-	if !connected {
-		return fmt.Errorf("IPFS daemon is not offering the API interface")
+	return fmt.Errorf("IPFS daemon is not offering the API interface, see log: %v", logPath)
+}
+
+// DaemonStatus describes the state of the IPFS daemon
+type DaemonStatus struct {
+	Running   bool     // the API is reachable
+	Managed   bool     // the daemon was launched by scribe
+	PID       int      // the PID of the daemon (only set if managed)
+	API       string   // the API address
+	Gateway   string   // the gateway address
+	Swarm     []string // the swarm addresses reported by the daemon
+	PeerID    string   // the daemon's peer ID
+	PeerCount int      // the number of connected swarm peers
+}
+
+// GetDaemonStatus will report on the IPFS daemon
+func GetDaemonStatus(conf *config.ScribeConfig) (*DaemonStatus, error) {
+	status := &DaemonStatus{
+		API:     conf.APIAddress,
+		Gateway: conf.GatewayAddress,
+	}
+	if pid, err := managedPID(conf); err == nil {
+		status.Managed = true
+		status.PID = pid
+	}
+	sh := ipfs.NewShell(GetAPI(conf))
+	if !sh.IsUp() {
+		return status, nil
+	}
+	status.Running = true
+	id, err := sh.ID()
+	if err != nil {
+		return status, err
+	}
+	status.PeerID = id.ID
+	status.Swarm = id.Addresses
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	peers, err := sh.SwarmPeers(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.PeerCount = len(peers.Peers)
+	return status, nil
+}
+
+// StopDaemon will stop the IPFS daemon, as long as it was launched by scribe
+func StopDaemon(conf *config.ScribeConfig) error {
+	pid, err := managedPID(conf)
+	if err != nil {
+		return err
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	// ask the daemon to shut down cleanly, then kill it if it hasn't gone after a while
+	if err := interrupt(proc); err != nil {
+		return err
+	}
+	for tries := 0; tries < 100 && processAlive(pid); tries++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if processAlive(pid) {
+		if err := proc.Kill(); err != nil {
+			return err
+		}
+	}
+	return os.Remove(pidPath(conf))
+}
+
+// DaemonLogPath returns the path to the current log file for a daemon launched by scribe
+func DaemonLogPath(conf *config.ScribeConfig) string {
+	return filepath.Join(conf.DataDir, daemonLogDir, daemonLogFile)
+}
+
+// managedPID returns the PID of the daemon launched by scribe, or ErrNotManaged if it isn't running
+//
+// The PID file is removed if the process has gone or is no longer the daemon, e.g. after a reboot
+// or once the PID has been reused, so that an unrelated process is never signalled.
+func managedPID(conf *config.ScribeConfig) (int, error) {
+	data, err := ioutil.ReadFile(pidPath(conf))
+	if os.IsNotExist(err) {
+		return 0, ErrNotManaged
+	}
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("malformed PID file (%v)", err)
+	}
+
+	// clean up if the daemon has gone
+	if !processAlive(pid) || !isDaemon(conf, pid) {
+		os.Remove(pidPath(conf))
+		return 0, ErrNotManaged
+	}
+	return pid, nil
+}
+
+// isDaemon reports whether a process is an IPFS daemon for the scribe IPFS repo
+//
+// The repo is only checked if the environment of the process can be read.
+func isDaemon(conf *config.ScribeConfig, pid int) bool {
+	args, env, err := processCommand(pid)
+	if err != nil {
+		return false
+	}
+	launched := false
+	for i := 1; i < len(args); i++ {
+		name := strings.TrimSuffix(filepath.Base(args[i-1]), ".exe")
+		if name == "ipfs" && args[i] == "daemon" {
+			launched = true
+			break
+		}
+	}
+	if !launched || env == nil {
+		return launched
+	}
+	for _, variable := range env {
+		if strings.HasPrefix(variable, "IPFS_PATH=") {
+			return strings.TrimPrefix(variable, "IPFS_PATH=") == conf.IpfsPath
+		}
+	}
+	return false
+}
+
+// pidPath returns the path to the PID file for a daemon launched by scribe
+func pidPath(conf *config.ScribeConfig) string {
+	return filepath.Join(conf.DataDir, daemonPIDFile)
+}
+
+// rotateLogs shifts log -> log.1 -> log.2 etc., keeping at most `keep` old logs
+func rotateLogs(logPath string, keep int) error {
+	os.Remove(fmt.Sprintf("%s.%d", logPath, keep))
+	for i := keep - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", logPath, i), fmt.Sprintf("%s.%d", logPath, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(logPath, logPath+".1"); err != nil && !os.IsNotExist(err) {
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/will-rowe/scribe/src/config"
+)
+
+// TestRotateLogs
+func TestRotateLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("./", "test-scribe-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, daemonLogFile)
+
+	// rotating with no log should be fine
+	if err := rotateLogs(logPath, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// write and rotate a few logs, only the latest 2 old ones should be kept
+	for i := 0; i < 4; i++ {
+		if err := ioutil.WriteFile(logPath, []byte(fmt.Sprint(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := rotateLogs(logPath, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for suffix, expected := range map[string]string{".1": "3", ".2": "2"} {
+		data, err := ioutil.ReadFile(logPath + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("expected %v to hold log %v, got %v", suffix, expected, string(data))
+		}
+	}
+	for _, suffix := range []string{"", ".3"} {
+		if _, err := os.Stat(logPath + suffix); !os.IsNotExist(err) {
+			t.Fatalf("log %v should not exist", logPath+suffix)
+		}
+	}
+}
+
+// TestManagedPID
+func TestManagedPID(t *testing.T) {
+	dir, err := ioutil.TempDir("./", "test-scribe-pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := &config.ScribeConfig{DataDir: dir}
+
+	// no PID file means no managed daemon
+	if _, err := managedPID(conf); err != ErrNotManaged {
+		t.Fatalf("expected ErrNotManaged, got %v", err)
+	}
+	if err := StopDaemon(conf); err != ErrNotManaged {
+		t.Fatalf("expected ErrNotManaged, got %v", err)
+	}
+
+	// a live PID that isn't the daemon is stale (e.g. after a reboot)
+	if err := ioutil.WriteFile(pidPath(conf), []byte(fmt.Sprint(os.Getpid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := managedPID(conf); err != ErrNotManaged {
+		t.Fatalf("expected ErrNotManaged for an unrelated process, got %v", err)
+	}
+	if _, err := os.Stat(pidPath(conf)); !os.IsNotExist(err) {
+		t.Fatal("stale PID file was not removed")
+	}
+
+	// a daemon for the repo is reported
+	if runtime.GOOS == "windows" {
+		t.Skip("fake daemon needs a shell")
+	}
+	conf.IpfsPath = filepath.Join(dir, "ipfs")
+	script := filepath.Join(dir, "ipfs")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nsleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	daemon := exec.Command(script, "daemon")
+	daemon.Env = append(os.Environ(), fmt.Sprintf("IPFS_PATH=%s", conf.IpfsPath))
+	if err := daemon.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer daemon.Process.Kill()
+	if err := ioutil.WriteFile(pidPath(conf), []byte(fmt.Sprint(daemon.Process.Pid)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pid, err := managedPID(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != daemon.Process.Pid {
+		t.Fatalf("expected PID %d, got %d", daemon.Process.Pid, pid)
+	}
+
+	// a daemon for another repo is not (where its environment can be read)
+	if runtime.GOOS == "linux" {
+		conf.IpfsPath = filepath.Join(dir, "another repo")
+		if _, err := managedPID(conf); err != ErrNotManaged {
+			t.Fatalf("expected ErrNotManaged for another repo, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+//go:build embedded && !go1.15
+// +build embedded,!go1.15
+
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ipfs "github.com/ipfs/go-ipfs-api"
+	ipfsconfig "github.com/ipfs/go-ipfs-config"
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/core/coreapi"
+	"github.com/ipfs/go-ipfs/plugin/loader"
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+	iface "github.com/ipfs/interface-go-ipfs-core"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+	mh "github.com/multiformats/go-multihash"
+
+	"github.com/will-rowe/scribe/src/config"
+)
+
+// the go-ipfs plugins can only be loaded once per process
+var (
+	pluginOnce sync.Once
+	pluginErr  error
+)
+
+// EmbeddedSupport is true if scribe was built with the in-process IPFS node
+const EmbeddedSupport = true
+
+// EmbeddedStore is a Store that runs an in-process IPFS node, with pubsub enabled
+type EmbeddedStore struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	node   *core.IpfsNode
+	api    iface.CoreAPI
+}
+
+// NewEmbeddedStore will start an IPFS node in this process, using the repo at the config's IPFS path
+//
+// The repo is initialised if needed and then updated using the scribe config.
+func NewEmbeddedStore(conf *config.ScribeConfig) (*EmbeddedStore, error) {
+
+	// load the plugins (datastores, IPLD formats)
+	pluginOnce.Do(func() {
+		plugins, err := loader.NewPluginLoader("")
+		if err == nil {
+			err = plugins.Initialize()
+		}
+		if err == nil {
+			err = plugins.Inject()
+		}
+		pluginErr = err
+	})
+	if pluginErr != nil {
+		return nil, fmt.Errorf("could not load IPFS plugins (%v)", pluginErr)
+	}
+
+	// init the repo if needed
+	if !fsrepo.IsInitialized(conf.IpfsPath) {
+		repoConf, err := ipfsconfig.Init(ioutil.Discard, 2048)
+		if err != nil {
+			return nil, err
+		}
+		if err := fsrepo.Init(conf.IpfsPath, repoConf); err != nil {
+			return nil, fmt.Errorf("could not init the IPFS repo (%v)", err)
+		}
+	}
+	repo, err := fsrepo.Open(conf.IpfsPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the IPFS repo (%v)", err)
+	}
+
+	// update the repo config to match the scribe config
+	repoConf, err := repo.Config()
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+	repoConf.Experimental.Libp2pStreamMounting = true
+	repoConf.Datastore.StorageMax = conf.StorageMax
+	repoConf.Addresses.API = []string{conf.APIAddress}
+	repoConf.Addresses.Swarm = []string{conf.SwarmAddress}
+	repoConf.Addresses.Gateway = []string{conf.GatewayAddress}
+	if err := repo.SetConfig(repoConf); err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	// start the node
+	ctx, cancel := context.WithCancel(context.Background())
+	node, err := core.NewNode(ctx, &core.BuildCfg{
+		Online:    true,
+		Repo:      repo,
+		ExtraOpts: map[string]bool{"pubsub": true},
+	})
+	if err != nil {
+		cancel()
+		repo.Close()
+		return nil, fmt.Errorf("could not start the embedded IPFS node (%v)", err)
+	}
+	api, err := coreapi.NewCoreAPI(node)
+	if err != nil {
+		cancel()
+		node.Close()
+		return nil, err
+	}
+	return &EmbeddedStore{
+		ctx:    ctx,
+		cancel: cancel,
+		node:   node,
+		api:    api,
+	}, nil
+}
+
+// Close will stop the node
+func (store *EmbeddedStore) Close() error {
+	store.cancel()
+	return store.node.Close()
+}
+
+// IsUp checks if the node is running
+func (store *EmbeddedStore) IsUp(ctx context.Context) bool {
+	return store.node.IsOnline && store.ctx.Err() == nil
+}
+
+// ID returns the peer ID of the node
+func (store *EmbeddedStore) ID(ctx context.Context) (string, error) {
+	return store.node.Identity.Pretty(), nil
+}
+
+// Add will add the content to the IPFS, pinning it if instructed
+func (store *EmbeddedStore) Add(ctx context.Context, content []byte, pin bool) (string, error) {
+	resolved, err := store.api.Unixfs().Add(ctx, files.NewBytesFile(content),
+		options.Unixfs.Pin(pin),
+		options.Unixfs.CidVersion(1),
+		options.Unixfs.Hash(mh.Names[MultiHash]),
+	)
+	if err != nil {
+		return "", err
+	}
+	return resolved.Cid().String(), nil
+}
+
+// Cat will return the data for a given CID in the IPFS
+func (store *EmbeddedStore) Cat(ctx context.Context, ref string) ([]byte, error) {
+	nd, err := store.api.Unixfs().Get(ctx, path.New(ref))
+	if err != nil {
+		return nil, err
+	}
+	defer nd.Close()
+	file, ok := nd.(files.File)
+	if !ok {
+		return nil, fmt.Errorf("can't cat a non-file node (%v)", ref)
+	}
+	return ioutil.ReadAll(file)
+}
+
+// DagPut will add an IPLD node to the IPFS and return its CID
+func (store *EmbeddedStore) DagPut(ctx context.Context, data []byte, encoding, format string, pin bool) (string, error) {
+	c, err := dagPut(ctx, store, data, encoding, format)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), store.pin(ctx, c, pin)
+}
+
+// DagGet will resolve a reference (CID/path) and unmarshal the JSON representation into the output
+func (store *EmbeddedStore) DagGet(ctx context.Context, ref string, output interface{}) error {
+	return dagGet(ctx, store, ref, output)
+}
+
+// BlockPut will add a raw block to the IPFS and return its CID
+func (store *EmbeddedStore) BlockPut(ctx context.Context, block []byte, format, mhType string, mhLen int) (string, error) {
+	c, err := blockPut(ctx, store, block, format, mhType, mhLen)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// Publish will publish a message to a pubsub topic
+func (store *EmbeddedStore) Publish(ctx context.Context, topic, message string) error {
+	return store.api.PubSub().Publish(ctx, topic, []byte(message))
+}
+
+// Subscribe will subscribe the node to a pubsub topic, until the context is done
+func (store *EmbeddedStore) Subscribe(ctx context.Context, topic string) (Subscription, error) {
+	sub, err := store.api.PubSub().Subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	return &embeddedSubscription{ctx: ctx, sub: sub}, nil
+}
+
+// pin will recursively pin a root if instructed
+func (store *EmbeddedStore) pin(ctx context.Context, c cid.Cid, pin bool) error {
+	if !pin {
+		return nil
+	}
+	return store.api.Pin().Add(ctx, path.IpfsPath(c), options.Pin.Recursive(true))
+}
+
+// getBlock will get a block via the block service, fetching it from the network if needed
+func (store *EmbeddedStore) getBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	b, err := store.node.Blocks.GetBlock(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return b.RawData(), nil
+}
+
+// putBlock will add a block via the block service, announcing it to the network
+func (store *EmbeddedStore) putBlock(ctx context.Context, c cid.Cid, data []byte) error {
+	b, err := blocks.NewBlockWithCid(data, c)
+	if err != nil {
+		return err
+	}
+	return store.node.Blocks.AddBlock(b)
+}
+
+// embeddedSubscription wraps a subscription on the embedded node
+type embeddedSubscription struct {
+	ctx context.Context
+	sub iface.PubSubSubscription
+}
+
+// Next waits for the next message on the subscription
+func (sub *embeddedSubscription) Next() (*ipfs.Message, error) {
+	msg, err := sub.sub.Next(sub.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ipfs.Message{
+		From:     msg.From(),
+		Data:     msg.Data(),
+		Seqno:    msg.Seq(),
+		TopicIDs: msg.Topics(),
+	}, nil
+}
+
+// Cancel will close the subscription
+func (sub *embeddedSubscription) Cancel() error {
+	return sub.sub.Close()
+}
@@ -0,0 +1,30 @@
+//go:build !embedded || go1.15
+// +build !embedded go1.15
+
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"github.com/will-rowe/scribe/src/config"
+)
+
+// EmbeddedSupport is true if scribe was built with the in-process IPFS node
+const EmbeddedSupport = false
+
+// EmbeddedStore is a Store that runs an in-process IPFS node, with pubsub enabled
+type EmbeddedStore struct {
+	Store
+}
+
+// NewEmbeddedStore returns ErrNoEmbedded as scribe was built without the in-process IPFS node
+//
+// The node's QUIC transport (quic-go v0.15, from go-ipfs v0.5) panics at start up on Go 1.15 and
+// later, so the embedded build tag is ignored by newer toolchains.
+func NewEmbeddedStore(conf *config.ScribeConfig) (*EmbeddedStore, error) {
+	return nil, ErrNoEmbedded
+}
+
+// Close is a no-op as the node can't be started
+func (store *EmbeddedStore) Close() error {
+	return nil
+}
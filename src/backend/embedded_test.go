@@ -0,0 +1,89 @@
+//go:build embedded && !go1.15
+// +build embedded,!go1.15
+
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/will-rowe/scribe/src/config"
+)
+
+// TestEmbedded
+func TestEmbedded(t *testing.T) {
+	ctx := context.Background()
+
+	// start the embedded node in a temporary repo
+	ipfsPath, err := ioutil.TempDir("./", "test-scribe-embedded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(ipfsPath)
+	store, err := NewEmbeddedStore(&config.ScribeConfig{
+		IpfsPath:   ipfsPath,
+		StorageMax: config.DefaultStorageMax,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := InitNodeWithStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	// check the CIDs match the in-memory store
+	network := NewMemoryNetwork()
+	memStore, err := network.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(&testStruct{FieldA: fieldA, FieldB: fieldB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, put := range []func(Store) (string, error){
+		func(s Store) (string, error) { return s.Add(ctx, data, true) },
+		func(s Store) (string, error) { return s.DagPut(ctx, data, "json", "cbor", true) },
+	} {
+		embeddedCID, err := put(store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		memCID, err := put(memStore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if embeddedCID != memCID {
+			t.Fatalf("embedded and in-memory CIDs differ: %v vs %v", embeddedCID, memCID)
+		}
+	}
+
+	// check pubsub loops back to the node
+	self, err := node.Identity(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := node.Subscribe(ctx, testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.SetProject(testProject)
+	time.Sleep(time.Second)
+	if err := node.Publish(ctx, testMessage); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-sub.Messages():
+		if string(msg.Data) != testMessage || msg.From.Pretty() != self.ID {
+			t.Fatal("received message does not match the sent one")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
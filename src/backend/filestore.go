@@ -0,0 +1,303 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	"github.com/will-rowe/scribe/src/helpers"
+)
+
+const (
+	blocksDir   = "blocks"       // the directory in the data dir that holds the blocks
+	journalFile = "unsynced.log" // the file in the blocks dir that lists the roots created locally
+	rootFile    = "file"         // journal entry for content added with Add
+	rootDag     = "dag"          // journal entry for content added with DagPut
+	rootBlock   = "block"        // journal entry for content added with BlockPut
+)
+
+// FileStore is an offline Store that keeps blocks on the local filesystem
+//
+// CIDs are computed the same way as the IPFS daemon computes them. Every root created by the
+// store is journalled so that it can be uploaded once the IPFS daemon is available (see Sync).
+type FileStore struct {
+	sync.Mutex
+	dir string // the directory holding the blocks
+	id  string // the peer ID of the IPFS node the blocks will be synced to (if known)
+}
+
+// NewFileStore returns a FileStore that keeps its blocks in the data directory
+func NewFileStore(dataDir string) (*FileStore, error) {
+	dir := filepath.Join(dataDir, blocksDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("can't create block directory (%v)", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// IsUp returns true as the local filesystem is always available
+func (store *FileStore) IsUp(ctx context.Context) bool {
+	return true
+}
+
+// SetID will set the peer ID of the IPFS node the blocks will be synced to, which the store will then use as its ID
+func (store *FileStore) SetID(id string) {
+	store.Lock()
+	defer store.Unlock()
+	store.id = id
+}
+
+// ID returns the peer ID set for the store, or ErrOffline if there isn't one as the store is not part of a network
+func (store *FileStore) ID(ctx context.Context) (string, error) {
+	store.Lock()
+	defer store.Unlock()
+	if len(store.id) == 0 {
+		return "", ErrOffline
+	}
+	return store.id, nil
+}
+
+// Add will chunk the content into a UnixFS DAG and return the root CID
+func (store *FileStore) Add(ctx context.Context, content []byte, pin bool) (string, error) {
+	c, err := addFile(ctx, store, content)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), store.journal(rootFile, c)
+}
+
+// Cat will return the file for a given CID
+func (store *FileStore) Cat(ctx context.Context, ref string) ([]byte, error) {
+	c, _, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	return catFile(ctx, store, c)
+}
+
+// DagPut will add an IPLD node to the store and return its CID
+func (store *FileStore) DagPut(ctx context.Context, data []byte, encoding, format string, pin bool) (string, error) {
+	c, err := dagPut(ctx, store, data, encoding, format)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), store.journal(rootDag, c)
+}
+
+// DagGet will resolve a reference (CID/path) and unmarshal the JSON representation into the output
+func (store *FileStore) DagGet(ctx context.Context, ref string, output interface{}) error {
+	return dagGet(ctx, store, ref, output)
+}
+
+// BlockPut will add a raw block to the store and return its CID
+func (store *FileStore) BlockPut(ctx context.Context, block []byte, format, mhType string, mhLen int) (string, error) {
+	c, err := blockPut(ctx, store, block, format, mhType, mhLen)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), store.journal(rootBlock, c)
+}
+
+// Publish returns ErrOffline as pubsub needs the IPFS daemon
+func (store *FileStore) Publish(ctx context.Context, topic, message string) error {
+	return ErrOffline
+}
+
+// Subscribe returns ErrOffline as pubsub needs the IPFS daemon
+func (store *FileStore) Subscribe(ctx context.Context, topic string) (Subscription, error) {
+	return nil, ErrOffline
+}
+
+// Unsynced returns the number of locally created roots that have not been synced
+func (store *FileStore) Unsynced() (int, error) {
+	store.Lock()
+	defer store.Unlock()
+	entries, err := store.readJournal()
+	return len(entries), err
+}
+
+// Sync will upload every locally created root to the target Store
+//
+// The CID returned by the target is checked against the local one. Synced roots are removed from
+// the journal, so an interrupted sync can be resumed. It returns the number of roots synced.
+//
+// The store is only locked while the journal is read and trimmed, so it can still be used while
+// the roots are uploaded; roots created during the upload are left for the next sync.
+func (store *FileStore) Sync(ctx context.Context, target Store, pin bool) (int, error) {
+	if !target.IsUp(ctx) {
+		return 0, ErrOffline
+	}
+	store.Lock()
+	entries, err := store.readJournal()
+	store.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	synced := 0
+	var uploadErr error
+	for _, entry := range entries {
+		if uploadErr = store.upload(ctx, target, entry, pin); uploadErr != nil {
+			break
+		}
+		synced++
+	}
+	if err := store.trimJournal(entries[:synced]); err != nil {
+		return synced, err
+	}
+	return synced, uploadErr
+}
+
+// trimJournal will remove synced entries from the journal, keeping any added since it was read
+func (store *FileStore) trimJournal(synced []journalEntry) error {
+	if len(synced) == 0 {
+		return nil
+	}
+	store.Lock()
+	defer store.Unlock()
+	entries, err := store.readJournal()
+	if err != nil {
+		return err
+	}
+	done := make(map[journalEntry]struct{}, len(synced))
+	for _, entry := range synced {
+		done[entry] = struct{}{}
+	}
+	remaining := []journalEntry{}
+	for _, entry := range entries {
+		if _, ok := done[entry]; !ok {
+			remaining = append(remaining, entry)
+		}
+	}
+	return store.writeJournal(remaining)
+}
+
+// journalEntry is a locally created root
+type journalEntry struct {
+	kind string
+	cid  cid.Cid
+}
+
+// upload will send a single root to the target, checking the CIDs match
+func (store *FileStore) upload(ctx context.Context, target Store, entry journalEntry, pin bool) error {
+	var remote string
+	var err error
+	switch entry.kind {
+	case rootFile:
+		content, cErr := catFile(ctx, store, entry.cid)
+		if cErr != nil {
+			return cErr
+		}
+		remote, err = target.Add(ctx, content, pin)
+	case rootDag:
+		data, gErr := store.getBlock(ctx, entry.cid)
+		if gErr != nil {
+			return gErr
+		}
+		remote, err = target.DagPut(ctx, data, "cbor", "cbor", pin)
+	case rootBlock:
+		data, gErr := store.getBlock(ctx, entry.cid)
+		if gErr != nil {
+			return gErr
+		}
+		format := "v0"
+		if entry.cid.Version() != 0 {
+			format = cid.CodecToStr[entry.cid.Type()]
+		}
+		remote, err = target.BlockPut(ctx, data, format, mh.Codes[entry.cid.Prefix().MhType], -1)
+	default:
+		return fmt.Errorf("unknown journal entry: %v", entry.kind)
+	}
+	if err != nil {
+		return err
+	}
+	remoteCID, err := cid.Decode(remote)
+	if err != nil {
+		return err
+	}
+	if !remoteCID.Equals(entry.cid) {
+		return fmt.Errorf("CID mismatch during sync (local %v vs remote %v)", entry.cid, remoteCID)
+	}
+	return nil
+}
+
+// journal records a locally created root
+func (store *FileStore) journal(kind string, c cid.Cid) error {
+	store.Lock()
+	defer store.Unlock()
+	fh, err := os.OpenFile(filepath.Join(store.dir, journalFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(fh, "%s %s\n", kind, c); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// readJournal returns the unique journal entries, in the order they were created
+func (store *FileStore) readJournal() ([]journalEntry, error) {
+	fh, err := os.Open(filepath.Join(store.dir, journalFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	entries := []journalEntry{}
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed journal entry: %v", scanner.Text())
+		}
+		c, err := cid.Decode(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[scanner.Text()]; ok {
+			continue
+		}
+		seen[scanner.Text()] = struct{}{}
+		entries = append(entries, journalEntry{kind: fields[0], cid: c})
+	}
+	return entries, scanner.Err()
+}
+
+// writeJournal replaces the journal with the provided entries
+func (store *FileStore) writeJournal(entries []journalEntry) error {
+	var sb strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "%s %s\n", entry.kind, entry.cid)
+	}
+	return helpers.WriteFileAtomic(filepath.Join(store.dir, journalFile), []byte(sb.String()))
+}
+
+// getBlock will return a block from the filesystem
+func (store *FileStore) getBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(store.dir, c.String()))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w (%v)", ErrBlockNotFound, c)
+	}
+	return data, err
+}
+
+// putBlock will write a block to the filesystem
+func (store *FileStore) putBlock(ctx context.Context, c cid.Cid, data []byte) error {
+	path := filepath.Join(store.dir, c.String())
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return helpers.WriteFileAtomic(path, data)
+}
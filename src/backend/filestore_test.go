@@ -0,0 +1,156 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestFileStore
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dataDir, err := ioutil.TempDir("./", "test-scribe-filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	// set up an offline node
+	store, err := NewFileStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := InitNodeWithStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Publish(ctx, testMessage); err != ErrNoProject {
+		t.Fatalf("expected ErrNoProject before registering a project, got %v", err)
+	}
+	node.SetProject(testProject)
+	if err := node.Publish(ctx, testMessage); !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected ErrOffline from offline publish, got %v", err)
+	}
+
+	// add some content while offline
+	fileCID, err := node.Add(ctx, []byte(testMessage), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(&testStruct{FieldA: fieldA, FieldB: fieldB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dagCID, err := node.DagPut(ctx, data, "json", "cbor", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCopy := &testStruct{}
+	if err := node.DagGet(ctx, dagCID, "", testCopy); err != nil {
+		t.Fatal(err)
+	}
+	if (testCopy.FieldA != fieldA) || (testCopy.FieldB != fieldB) {
+		t.Fatal("retrieved struct does not match original")
+	}
+	if n, err := store.Unsynced(); err != nil || n != 2 {
+		t.Fatalf("expected 2 unsynced roots, got %d (%v)", n, err)
+	}
+
+	// sync to an in-memory store, which checks the CIDs match
+	network := NewMemoryNetwork()
+	remote, err := network.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	synced, err := store.Sync(ctx, remote, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if synced != 2 {
+		t.Fatalf("expected 2 roots to sync, got %d", synced)
+	}
+	if n, err := store.Unsynced(); err != nil || n != 0 {
+		t.Fatalf("expected no unsynced roots after sync, got %d (%v)", n, err)
+	}
+	retrievedData, err := remote.Cat(ctx, fileCID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(retrievedData) != testMessage {
+		t.Fatal("synced file does not match original")
+	}
+}
+
+// blockingStore is a Store that holds each DagPut until it is released
+type blockingStore struct {
+	Store
+	started chan struct{}
+	release chan struct{}
+}
+
+// DagPut will report the upload has started, then wait to be released
+func (store *blockingStore) DagPut(ctx context.Context, data []byte, encoding, format string, pin bool) (string, error) {
+	store.started <- struct{}{}
+	<-store.release
+	return store.Store.DagPut(ctx, data, encoding, format, pin)
+}
+
+// TestFileStoreSyncUnlocked
+func TestFileStoreSyncUnlocked(t *testing.T) {
+	ctx := context.Background()
+	dataDir, err := ioutil.TempDir("./", "test-scribe-filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	store, err := NewFileStore(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DagPut(ctx, []byte(`{"a": 1}`), "json", "cbor", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// start a sync that blocks during the upload
+	remote, err := NewMemoryNetwork().NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := &blockingStore{Store: remote, started: make(chan struct{}), release: make(chan struct{})}
+	type result struct {
+		synced int
+		err    error
+	}
+	done := make(chan result)
+	go func() {
+		synced, err := store.Sync(ctx, target, true)
+		done <- result{synced, err}
+	}()
+	<-target.started
+
+	// the store should still be usable, and the new root should be kept for the next sync
+	added := make(chan error)
+	go func() {
+		_, err := store.DagPut(ctx, []byte(`{"b": 2}`), "json", "cbor", true)
+		added <- err
+	}()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("store was locked during the upload")
+	}
+	close(target.release)
+	if res := <-done; res.err != nil || res.synced != 1 {
+		t.Fatalf("expected 1 root to sync, got %d (%v)", res.synced, res.err)
+	}
+	if n, err := store.Unsynced(); err != nil || n != 1 {
+		t.Fatalf("expected the new root to be unsynced, got %d (%v)", n, err)
+	}
+}
@@ -2,69 +2,52 @@
 package backend
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-
-	ipfs "github.com/ipfs/go-ipfs-api"
-	"github.com/ipfs/go-ipfs-api/options"
 )
 
 // Add will add the content to the IPFS, pinning it if instructed
-func (node *Node) Add(content []byte, pin bool) (string, error) {
-	if !node.IsOnline() {
-		return "", ErrOffline
-	}
-	return node.sh.Add(bytes.NewReader(content), ipfs.Pin(pin), ipfs.Hash(MultiHash), ipfs.CidVersion(1))
+func (node *Node) Add(ctx context.Context, content []byte, pin bool) (string, error) {
+	var cid string
+	err := node.do(ctx, "add", true, func(ctx context.Context) error {
+		var err error
+		cid, err = node.store.Add(ctx, content, pin)
+		return err
+	})
+	return cid, err
 }
 
 // Cat will return the data for a given CID in the IPFS
-func (node *Node) Cat(cid string) ([]byte, error) {
-	if !node.IsOnline() {
-		return nil, ErrOffline
-	}
-	resp, err := node.sh.Cat(cid)
-	if err != nil {
-		return nil, err
-	}
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(resp)
-	data := buf.Bytes()
-	err = nil
-	err = resp.Close()
+func (node *Node) Cat(ctx context.Context, cid string) ([]byte, error) {
+	var data []byte
+	err := node.do(ctx, "cat", true, func(ctx context.Context) error {
+		var err error
+		data, err = node.store.Cat(ctx, cid)
+		return err
+	})
 	return data, err
 }
 
 // DagPut wraps the DagPut API call
-func (node *Node) DagPut(data []byte, encoding, format string, pin bool) (string, error) {
-	if !node.IsOnline() {
-		return "", ErrOffline
-	}
-
-	// need to convert the pin bool to a string
-	pinVal := "false"
-	if pin {
-		pinVal = "true"
-	}
-
-	// call DagPut with options
-	return node.sh.DagPutWithOpts(data,
-		options.Dag.Pin(pinVal),
-		options.Dag.InputEnc(encoding),
-		options.Dag.Kind(format),
-		options.Dag.Hash(MultiHash),
-	)
+func (node *Node) DagPut(ctx context.Context, data []byte, encoding, format string, pin bool) (string, error) {
+	var cid string
+	err := node.do(ctx, "dag put", true, func(ctx context.Context) error {
+		var err error
+		cid, err = node.store.DagPut(ctx, data, encoding, format, pin)
+		return err
+	})
+	return cid, err
 }
 
 // DagGet wraps the DagGet API call
-func (node *Node) DagGet(cid, field string, output interface{}) error {
-	if !node.IsOnline() {
-		return ErrOffline
-	}
+func (node *Node) DagGet(ctx context.Context, cid, field string, output interface{}) error {
 	var ref string
 	if len(field) != 0 {
 		ref = fmt.Sprintf("%v/%v", cid, field)
 	} else {
 		ref = cid
 	}
-	return node.sh.DagGet(ref, output)
+	return node.do(ctx, "dag get", true, func(ctx context.Context) error {
+		return node.store.DagGet(ctx, ref, output)
+	})
 }
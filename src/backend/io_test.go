@@ -1,18 +1,20 @@
 package backend
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 )
 
 // TestIO
 func TestIO(t *testing.T) {
+	ctx := context.Background()
 
 	// launch IPFS and then run the test
 	WithIpfs(t, 1, func(t *testing.T, APIaddress string) {
 
 		// set up the node
-		node, err := InitNode(APIaddress)
+		node, err := InitNode(ctx, APIaddress)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -28,14 +30,14 @@ func TestIO(t *testing.T) {
 		}
 
 		// add to IPFS
-		cid, err := node.Add(data, true)
+		cid, err := node.Add(ctx, data, true)
 		if err != nil {
 			t.Fatal(err)
 		}
 		t.Logf("IPLD Explorer link: https://explore.ipld.io/#/explore/%s \n", string(cid+"\n"))
 
 		// cat it from the IPFS
-		retrievedData, err := node.Cat(cid)
+		retrievedData, err := node.Cat(ctx, cid)
 		if err != nil {
 			t.Fatal(err)
 		}
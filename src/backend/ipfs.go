@@ -5,9 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
-
-	ipfs "github.com/ipfs/go-ipfs-api"
 )
 
 // MultiHash is the multihash type used by IPFS
@@ -18,53 +18,95 @@ var (
 	// ErrOffline is returned by operations that need online support to work when a node is offline
 	ErrOffline   = errors.New("node is offline")
 	ErrNoProject = errors.New("node has no registered project")
+
+	// ErrSubscribed is returned when the node is already subscribed to a project
+	ErrSubscribed = errors.New("node is already subscribed to project")
+
+	// ErrTimeout is returned when the IPFS node does not respond in time
+	ErrTimeout = errors.New("IPFS node timed out")
+
+	// ErrNotFound is returned when the requested content can't be found
+	ErrNotFound = errors.New("content not found")
+
+	// ErrDaemon is returned when the IPFS node reports an error with a request
+	ErrDaemon = errors.New("IPFS node error")
+
+	// ErrNoEmbedded is returned when the in-process IPFS node is requested but scribe was built without it
+	ErrNoEmbedded = errors.New("scribe was built without the embedded IPFS node (rebuild with `-tags embedded` using Go 1.13 or 1.14)")
 )
 
-// Node wraps the IPFS shell
+// Node wraps the storage and pubsub backend
 type Node struct {
 	sync.Mutex
-	sh           *ipfs.Shell              // the IPFS shell
-	allowNetwork bool                     // controls the node's network connection
-	identity     string                   // the node's identifier
-	subscription *ipfs.PubSubSubscription // the PubSub subscription for this node
-	project      string                   // the PubSub project for this node
+	store         Store                           // the storage backend (IPFS shell or in-memory)
+	retry         RetryPolicy                     // controls retries of store operations
+	allowNetwork  bool                            // controls the node's network connection
+	identity      string                          // the node's identifier
+	subscriptions map[string]*ProjectSubscription // the PubSub subscriptions for this node, by project
+	project       string                          // the project this node publishes to
 }
 
 // InitNode will returns a HTTP based IPFS node
-func InitNode(ipfsAPIendpoint string) (*Node, error) {
+func InitNode(ctx context.Context, ipfsAPIendpoint string) (*Node, error) {
 
 	// use the API endpoint at the specified port
+	return InitNodeWithStore(ctx, NewShellStore(ipfsAPIendpoint))
+}
+
+// InitNodeWithStore will return a node that uses the provided storage backend
+func InitNodeWithStore(ctx context.Context, store Store) (*Node, error) {
 	newNode := &Node{
-		sh:           ipfs.NewShell(ipfsAPIendpoint),
-		allowNetwork: true,
-		identity:     "",
+		store:         store,
+		retry:         DefaultRetryPolicy,
+		allowNetwork:  true,
+		identity:      "",
+		subscriptions: make(map[string]*ProjectSubscription),
 	}
 
 	// check it's online
-	if !newNode.IsOnline() {
+	if !newNode.IsOnline(ctx) {
 		return nil, ErrOffline
 	}
 
 	return newNode, nil
 }
 
-// SetProject will register the node with a project
+// GetStore will return the storage backend used by the node
+func (node *Node) GetStore() Store {
+	return node.store
+}
+
+// Close will unsubscribe the node and release any resources held by the node's store (e.g. stop an embedded IPFS node)
+func (node *Node) Close() error {
+	if err := node.Unsubscribe(); err != nil {
+		return err
+	}
+	if closer, ok := node.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// SetProject will register the node with a project, which is used for publishing
 func (node *Node) SetProject(project string) {
+	node.Lock()
+	defer node.Unlock()
 	node.project = project
-	return
 }
 
 // GetProject will return the registered project for the node
 func (node *Node) GetProject() string {
+	node.Lock()
+	defer node.Unlock()
 	return node.project
 }
 
 // IsOnline returns true if the node is in online mode and the IPFS daemon is reachable
-func (node *Node) IsOnline() bool {
+func (node *Node) IsOnline(ctx context.Context) bool {
 	node.Lock()
 	allowNetwork := node.allowNetwork
 	node.Unlock()
-	return node.sh.IsUp() && allowNetwork
+	return allowNetwork && node.store.IsUp(ctx)
 }
 
 // Connect allows the node to connect to the network
@@ -83,65 +125,88 @@ func (node *Node) Disconnect() error {
 	return nil
 }
 
-// Subscribe will subscribe the node to a project
-func (node *Node) Subscribe(ctx context.Context, project string) error {
-	if !node.IsOnline() {
-		return ErrOffline
+// Subscribe will subscribe the node to a project, until the context is done or the subscription is cancelled
+//
+// A node can be subscribed to several projects at once, but only once to each project.
+func (node *Node) Subscribe(ctx context.Context, project string) (*ProjectSubscription, error) {
+	if len(project) == 0 {
+		return nil, ErrNoProject
+	}
+	node.Lock()
+	_, ok := node.subscriptions[project]
+	node.Unlock()
+	if ok {
+		return nil, fmt.Errorf("%w: %v", ErrSubscribed, project)
 	}
-	sub, err := node.sh.PubSubSubscribe(project)
-	if err != nil {
+	var sub Subscription
+	if err := node.do(ctx, "subscribe", false, func(ctx context.Context) error {
+		var err error
+		sub, err = node.store.Subscribe(ctx, project)
 		return err
+	}); err != nil {
+		return nil, err
 	}
-	node.subscription = sub
-	node.project = project
-	return nil
+
+	// check for a concurrent subscription to the same project before starting the listener
+	node.Lock()
+	defer node.Unlock()
+	if _, ok := node.subscriptions[project]; ok {
+		sub.Cancel()
+		return nil, fmt.Errorf("%w: %v", ErrSubscribed, project)
+	}
+	handle := newProjectSubscription(ctx, node, project, sub)
+	node.subscriptions[project] = handle
+	return handle, nil
 }
 
-// Unsubscribe will unsubscribe the node
-func (node *Node) Unsubscribe() error {
-	if !node.IsOnline() {
-		return ErrOffline
+// Subscriptions will return the projects the node is subscribed to
+func (node *Node) Subscriptions() []string {
+	node.Lock()
+	defer node.Unlock()
+	projects := make([]string, 0, len(node.subscriptions))
+	for project := range node.subscriptions {
+		projects = append(projects, project)
 	}
-	if node.project == "" {
-		return nil
+	sort.Strings(projects)
+	return projects
+}
+
+// Unsubscribe will cancel all of the node's subscriptions
+func (node *Node) Unsubscribe() error {
+	node.Lock()
+	handles := make([]*ProjectSubscription, 0, len(node.subscriptions))
+	for _, handle := range node.subscriptions {
+		handles = append(handles, handle)
 	}
-	err := node.subscription.Cancel()
-	if err != nil {
-		return err
+	node.Unlock()
+	for _, handle := range handles {
+		if err := handle.Cancel(); err != nil {
+			return err
+		}
 	}
-	node.subscription = nil
-	node.project = ""
 	return nil
 }
 
-// Publish will publish a message about the registered project
-func (node *Node) Publish(message string) error {
-	if !node.IsOnline() {
-		return ErrOffline
-	}
-	if len(node.project) == 0 {
-		return ErrNoProject
+// removeSubscription will forget a subscription once its listener has stopped
+func (node *Node) removeSubscription(handle *ProjectSubscription) {
+	node.Lock()
+	defer node.Unlock()
+	if node.subscriptions[handle.project] == handle {
+		delete(node.subscriptions, handle.project)
 	}
-	return node.sh.PubSubPublish(node.project, message)
 }
 
-// Listen will wait for messages on the PubSub subscription
-//
-// msgChan is to send back received messages to the caller
-// errChan is to send back any errors to the the caller
-// sigChan is to terminate the listener
-func (node *Node) Listen(msgChan chan *ipfs.Message, errChan chan error, sigChan chan struct{}) {
-	for {
-		select {
-		case <-sigChan:
-			return
-		default:
-			message, err := node.subscription.Next()
-			if err != nil {
-				errChan <- fmt.Errorf("failed to wait for pubsub message: %v", err)
-			} else {
-				msgChan <- message
-			}
-		}
+// Publish will publish a message about the registered project
+func (node *Node) Publish(ctx context.Context, message string) error {
+	return node.PublishProject(ctx, node.GetProject(), message)
+}
+
+// PublishProject will publish a message about a project, which need not be the registered project
+func (node *Node) PublishProject(ctx context.Context, project, message string) error {
+	if len(project) == 0 {
+		return ErrNoProject
 	}
+	return node.do(ctx, "publish", true, func(ctx context.Context) error {
+		return node.store.Publish(ctx, project, message)
+	})
 }
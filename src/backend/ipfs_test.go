@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"testing"
 )
 
@@ -19,19 +20,20 @@ var (
 
 // TestNode will test the node init
 func TestNode(t *testing.T) {
+	ctx := context.Background()
 
 	// launch IPFS and then run the test
 	WithIpfs(t, 1, func(t *testing.T, APIaddress string) {
-		node, err := InitNode(APIaddress)
+		node, err := InitNode(ctx, APIaddress)
 		if err != nil {
 			t.Fatal(err)
 		}
 		node.Disconnect()
-		if node.IsOnline() {
+		if node.IsOnline(ctx) {
 			t.Fatal("node registered as online after disconnect")
 		}
 		node.Connect()
-		if !node.IsOnline() {
+		if !node.IsOnline(ctx) {
 			t.Fatal("node registered as offline after connect")
 		}
 	})
@@ -0,0 +1,231 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ipfs "github.com/ipfs/go-ipfs-api"
+)
+
+// the listener checks the IPFS node is still responding at this interval, as a
+// subscription to a hung daemon won't report an error
+var (
+	listenerHealthInterval = 30 * time.Second
+	listenerHealthTimeout  = 10 * time.Second
+)
+
+// eventBuffer is the number of listener events held for a subscription before new ones are dropped
+const eventBuffer = 16
+
+// ListenerState is the state of the pubsub listener's subscription
+type ListenerState int
+
+const (
+	// StateConnected means the subscription is receiving messages
+	StateConnected ListenerState = iota
+
+	// StateDisconnected means the subscription has been lost
+	StateDisconnected
+
+	// StateReconnecting means the listener is trying to resubscribe
+	StateReconnecting
+)
+
+// String returns the name of the state
+func (state ListenerState) String() string {
+	switch state {
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateReconnecting:
+		return "reconnecting"
+	}
+	return "unknown"
+}
+
+// ListenerEvent is sent by the listener when the state of its subscription changes
+type ListenerEvent struct {
+	Project string        // the project the subscription is for
+	State   ListenerState // the new state
+	Attempt int           // the resubscribe attempt (when reconnecting)
+	Err     error         // the reason the subscription was lost (when disconnected) or a resubscribe failed (when reconnecting)
+}
+
+// ProjectSubscription is a node's subscription to the PubSub topic for a project
+//
+// Each ProjectSubscription runs its own listener, which forwards messages to the Messages
+// channel. If the subscription is lost (e.g. the IPFS daemon restarts or stops responding),
+// the listener will resubscribe to the project, backing off between attempts according to
+// the node's retry policy, and report the changes on the Events channel.
+type ProjectSubscription struct {
+	sync.Mutex
+	node     *Node
+	project  string
+	sub      Subscription // the current store subscription, replaced on resubscribe
+	messages chan *ipfs.Message
+	events   chan ListenerEvent
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// newProjectSubscription will start a listener for a store subscription to a project
+func newProjectSubscription(ctx context.Context, node *Node, project string, sub Subscription) *ProjectSubscription {
+	ctx, cancel := context.WithCancel(ctx)
+	handle := &ProjectSubscription{
+		node:     node,
+		project:  project,
+		sub:      sub,
+		messages: make(chan *ipfs.Message),
+		events:   make(chan ListenerEvent, eventBuffer),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go handle.listen(ctx)
+	return handle
+}
+
+// Project returns the project the subscription is for
+func (handle *ProjectSubscription) Project() string {
+	return handle.project
+}
+
+// Messages returns the channel that messages for the project are sent to
+//
+// The channel is closed once the subscription is cancelled.
+func (handle *ProjectSubscription) Messages() <-chan *ipfs.Message {
+	return handle.messages
+}
+
+// Events returns the channel that changes to the subscription state are sent to
+//
+// Events are dropped if the channel is not being read. The channel is closed once the
+// subscription is cancelled.
+func (handle *ProjectSubscription) Events() <-chan ListenerEvent {
+	return handle.events
+}
+
+// Cancel will stop the listener and unsubscribe from the project
+func (handle *ProjectSubscription) Cancel() error {
+	handle.cancel()
+	<-handle.done
+	return nil
+}
+
+// listen will receive messages until the context is done, replacing the subscription if it is lost
+func (handle *ProjectSubscription) listen(ctx context.Context) {
+	defer func() {
+		handle.node.removeSubscription(handle)
+		close(handle.messages)
+		close(handle.events)
+		close(handle.done)
+	}()
+	handle.node.Lock()
+	policy := handle.node.retry
+	handle.node.Unlock()
+	for {
+		handle.Lock()
+		sub := handle.sub
+		handle.Unlock()
+		err := handle.receive(ctx, sub)
+		if ctx.Err() != nil {
+			return
+		}
+		handle.sendEvent(ListenerEvent{State: StateDisconnected, Err: err})
+		if !handle.resubscribe(ctx, policy) {
+			return
+		}
+		handle.sendEvent(ListenerEvent{State: StateConnected})
+	}
+}
+
+// receive will forward messages from the subscription until it fails or the context is done
+//
+// The subscription is cancelled if the context is done or the store stops responding.
+func (handle *ProjectSubscription) receive(ctx context.Context, sub Subscription) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	dead := make(chan error, 1)
+	interval, timeout := listenerHealthInterval, listenerHealthTimeout
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				sub.Cancel()
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				healthCtx, cancel := context.WithTimeout(ctx, timeout)
+				up := handle.node.store.IsUp(healthCtx)
+				cancel()
+				if !up && ctx.Err() == nil {
+					dead <- ErrOffline
+					sub.Cancel()
+					return
+				}
+			}
+		}
+	}()
+	for {
+		msg, err := sub.Next()
+		if err != nil {
+			select {
+			case deadErr := <-dead:
+				return deadErr
+			default:
+				return err
+			}
+		}
+		select {
+		case handle.messages <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// resubscribe will keep trying to replace a lost subscription to the project
+//
+// It returns false if the context is done before a new subscription is made.
+func (handle *ProjectSubscription) resubscribe(ctx context.Context, policy RetryPolicy) bool {
+	backoff := policy.Backoff
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return false
+		}
+		handle.sendEvent(ListenerEvent{State: StateReconnecting, Attempt: attempt, Err: lastErr})
+		sub, err := handle.node.store.Subscribe(ctx, handle.project)
+		if err == nil {
+			if ctx.Err() != nil {
+				sub.Cancel()
+				return false
+			}
+			handle.Lock()
+			handle.sub = sub
+			handle.Unlock()
+			return true
+		}
+		lastErr = &NodeError{Op: "subscribe", Kind: classifyError(err), Attempts: attempt, Err: err}
+		backoff *= 2
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// sendEvent will send an event to the caller, dropping it if the event channel is full
+func (handle *ProjectSubscription) sendEvent(event ListenerEvent) {
+	event.Project = handle.project
+	select {
+	case handle.events <- event:
+	default:
+	}
+}
@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ipfs "github.com/ipfs/go-ipfs-api"
+)
+
+// downStore is a MemoryStore which can be taken offline
+type downStore struct {
+	*MemoryStore
+	down int32
+}
+
+// IsUp will report false while the store is down
+func (store *downStore) IsUp(ctx context.Context) bool {
+	return atomic.LoadInt32(&store.down) == 0 && store.MemoryStore.IsUp(ctx)
+}
+
+// Subscribe will fail while the store is down
+func (store *downStore) Subscribe(ctx context.Context, topic string) (Subscription, error) {
+	if atomic.LoadInt32(&store.down) != 0 {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return store.MemoryStore.Subscribe(ctx, topic)
+}
+
+// waitForState will read listener events until one with the requested state arrives
+func waitForState(t *testing.T, events <-chan ListenerEvent, state ListenerState) ListenerEvent {
+	t.Helper()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("listener stopped before it was %v", state)
+			}
+			if event.State == state {
+				return event
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for listener to be %v", state)
+		}
+	}
+}
+
+// checkDelivery will publish a message from the sender and wait for it on the message channel
+func checkDelivery(t *testing.T, sender *Node, msgChan <-chan *ipfs.Message) {
+	t.Helper()
+	if err := sender.Publish(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-msgChan:
+		if string(msg.Data) != testMessage {
+			t.Fatal("received message does not match the sent one")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+// TestListener
+func TestListener(t *testing.T) {
+	ctx := context.Background()
+	defer func(interval time.Duration) { listenerHealthInterval = interval }(listenerHealthInterval)
+	listenerHealthInterval = 10 * time.Millisecond
+
+	// set up a sender and a receiver which can be taken offline
+	network := NewMemoryNetwork()
+	senderStore, err := network.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := InitNodeWithStore(ctx, senderStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.SetProject(testProject)
+	memStore, err := network.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &downStore{MemoryStore: memStore}
+	receiver, err := InitNodeWithStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver.SetRetryPolicy(testRetryPolicy)
+
+	// start the listener
+	sub, err := receiver.Subscribe(ctx, testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDelivery(t, sender, sub.Messages())
+
+	// a dropped subscription should be replaced
+	sub.Lock()
+	sub.sub.Cancel()
+	sub.Unlock()
+	waitForState(t, sub.Events(), StateDisconnected)
+	if event := waitForState(t, sub.Events(), StateReconnecting); event.Attempt != 1 || event.Project != testProject {
+		t.Fatalf("expected first resubscribe attempt for %v, got %+v", testProject, event)
+	}
+	waitForState(t, sub.Events(), StateConnected)
+	checkDelivery(t, sender, sub.Messages())
+
+	// an unresponsive store should be noticed by the health check and retried until it is back
+	atomic.StoreInt32(&store.down, 1)
+	if event := waitForState(t, sub.Events(), StateDisconnected); !errors.Is(event.Err, ErrOffline) {
+		t.Fatalf("expected the listener to be offline, got %v", event.Err)
+	}
+	waitForState(t, sub.Events(), StateReconnecting)
+	if event := waitForState(t, sub.Events(), StateReconnecting); !errors.Is(event.Err, ErrOffline) {
+		t.Fatalf("expected the failed resubscribe to be reported, got %v", event.Err)
+	}
+	atomic.StoreInt32(&store.down, 0)
+	waitForState(t, sub.Events(), StateConnected)
+	checkDelivery(t, sender, sub.Messages())
+
+	// cancelling the subscription should stop the listener promptly
+	cancelled := make(chan error, 1)
+	go func() {
+		cancelled <- sub.Cancel()
+	}()
+	select {
+	case err := <-cancelled:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("listener did not stop after cancelling")
+	}
+	if projects := receiver.Subscriptions(); len(projects) != 0 {
+		t.Fatalf("cancelled subscription is still registered: %v", projects)
+	}
+
+	// cancelling the context should stop the listener, even whilst reconnecting
+	listenCtx, cancel := context.WithCancel(ctx)
+	sub, err = receiver.Subscribe(listenCtx, testProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&store.down, 1)
+	waitForState(t, sub.Events(), StateReconnecting)
+	cancel()
+	select {
+	case <-sub.done:
+	case <-time.After(time.Second):
+		t.Fatal("listener did not stop after the context was cancelled")
+	}
+}
@@ -0,0 +1,260 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	ipfs "github.com/ipfs/go-ipfs-api"
+	peer "github.com/libp2p/go-libp2p-peer"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// error messages
+var (
+	// ErrBlockNotFound is returned when a CID can't be found in the store or on the network
+	ErrBlockNotFound = errors.New("block not found")
+
+	// ErrSubscriptionCancelled is returned when waiting on a cancelled subscription
+	ErrSubscriptionCancelled = errors.New("subscription cancelled")
+)
+
+// subscriptionBuffer is the number of messages a subscription will hold before dropping new ones
+const subscriptionBuffer = 64
+
+// MemoryNetwork connects MemoryStores so that they can exchange blocks and pubsub messages
+type MemoryNetwork struct {
+	sync.RWMutex
+	stores        []*MemoryStore                              // the stores on the network
+	subscriptions map[string]map[*memorySubscription]struct{} // the subscriptions for each topic
+}
+
+// NewMemoryNetwork returns an empty network
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		stores:        []*MemoryStore{},
+		subscriptions: make(map[string]map[*memorySubscription]struct{}),
+	}
+}
+
+// NewStore creates a MemoryStore and joins it to the network
+func (network *MemoryNetwork) NewStore() (*MemoryStore, error) {
+
+	// give the store a random peer ID
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	hash, err := mh.Sum(seed, mh.SHA2_256, -1)
+	if err != nil {
+		return nil, err
+	}
+	store := &MemoryStore{
+		network: network,
+		id:      peer.ID(hash),
+		blocks:  make(map[string][]byte),
+	}
+
+	// join the network
+	network.Lock()
+	network.stores = append(network.stores, store)
+	network.Unlock()
+	return store, nil
+}
+
+// fetch will look for a block on any store in the network
+func (network *MemoryNetwork) fetch(c cid.Cid) ([]byte, error) {
+	network.RLock()
+	defer network.RUnlock()
+	for _, store := range network.stores {
+		if data, ok := store.getLocal(c); ok {
+			return data, nil
+		}
+	}
+	return nil, ErrBlockNotFound
+}
+
+// broadcast will send a message to every subscription to the topic
+func (network *MemoryNetwork) broadcast(topic string, msg *ipfs.Message) {
+	network.RLock()
+	defer network.RUnlock()
+	for sub := range network.subscriptions[topic] {
+		sub.deliver(msg)
+	}
+}
+
+// MemoryStore is an in-process Store that keeps blocks in memory
+//
+// CIDs are computed the same way as the IPFS daemon computes them, so content can be moved between backends
+type MemoryStore struct {
+	sync.RWMutex
+	network *MemoryNetwork    // the network this store is joined to
+	id      peer.ID           // the peer ID of this store
+	blocks  map[string][]byte // the blocks held by this store
+	seqno   uint64            // the pubsub sequence number
+}
+
+// IsUp returns true as the in-memory store is always available
+func (store *MemoryStore) IsUp(ctx context.Context) bool {
+	return true
+}
+
+// ID returns the peer ID of the store
+func (store *MemoryStore) ID(ctx context.Context) (string, error) {
+	return store.id.Pretty(), nil
+}
+
+// Add will chunk the content into a UnixFS DAG and return the root CID
+func (store *MemoryStore) Add(ctx context.Context, content []byte, pin bool) (string, error) {
+	c, err := addFile(ctx, store, content)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// Cat will return the file for a given CID
+func (store *MemoryStore) Cat(ctx context.Context, ref string) ([]byte, error) {
+	c, _, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	return catFile(ctx, store, c)
+}
+
+// DagPut will add an IPLD node to the store and return its CID
+func (store *MemoryStore) DagPut(ctx context.Context, data []byte, encoding, format string, pin bool) (string, error) {
+	c, err := dagPut(ctx, store, data, encoding, format)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// DagGet will resolve a reference (CID/path) and unmarshal the JSON representation into the output
+func (store *MemoryStore) DagGet(ctx context.Context, ref string, output interface{}) error {
+	return dagGet(ctx, store, ref, output)
+}
+
+// BlockPut will add a raw block to the store and return its CID
+func (store *MemoryStore) BlockPut(ctx context.Context, block []byte, format, mhType string, mhLen int) (string, error) {
+	c, err := blockPut(ctx, store, block, format, mhType, mhLen)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// Publish will send a message to all subscribers of the topic on the network
+func (store *MemoryStore) Publish(ctx context.Context, topic, message string) error {
+	store.Lock()
+	store.seqno++
+	seqno := make([]byte, 8)
+	binary.BigEndian.PutUint64(seqno, store.seqno)
+	store.Unlock()
+	store.network.broadcast(topic, &ipfs.Message{
+		From:     store.id,
+		Data:     []byte(message),
+		Seqno:    seqno,
+		TopicIDs: []string{topic},
+	})
+	return nil
+}
+
+// Subscribe will subscribe the store to a topic on the network, until the context is done
+func (store *MemoryStore) Subscribe(ctx context.Context, topic string) (Subscription, error) {
+	sub := &memorySubscription{
+		network:  store.network,
+		topic:    topic,
+		messages: make(chan *ipfs.Message, subscriptionBuffer),
+		done:     make(chan struct{}),
+	}
+	store.network.Lock()
+	if _, ok := store.network.subscriptions[topic]; !ok {
+		store.network.subscriptions[topic] = make(map[*memorySubscription]struct{})
+	}
+	store.network.subscriptions[topic][sub] = struct{}{}
+	store.network.Unlock()
+
+	// cancel the subscription when the context is done
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Cancel()
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// getBlock will return a block from the store, fetching it from the network if needed
+func (store *MemoryStore) getBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	if data, ok := store.getLocal(c); ok {
+		return data, nil
+	}
+	data, err := store.network.fetch(c)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", err, c)
+	}
+	store.putBlock(ctx, c, data)
+	return data, nil
+}
+
+// getLocal will return a block if it is held by this store
+func (store *MemoryStore) getLocal(c cid.Cid) ([]byte, bool) {
+	store.RLock()
+	defer store.RUnlock()
+	data, ok := store.blocks[c.KeyString()]
+	return data, ok
+}
+
+// putBlock will add a block to this store
+func (store *MemoryStore) putBlock(ctx context.Context, c cid.Cid, data []byte) error {
+	store.Lock()
+	defer store.Unlock()
+	store.blocks[c.KeyString()] = data
+	return nil
+}
+
+// memorySubscription is a subscription to a topic on a MemoryNetwork
+type memorySubscription struct {
+	network  *MemoryNetwork
+	topic    string
+	messages chan *ipfs.Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+// deliver will queue a message on the subscription, dropping it if the subscriber is not keeping up
+func (sub *memorySubscription) deliver(msg *ipfs.Message) {
+	select {
+	case sub.messages <- msg:
+	default:
+	}
+}
+
+// Next waits for the next message on the subscription
+func (sub *memorySubscription) Next() (*ipfs.Message, error) {
+	select {
+	case msg := <-sub.messages:
+		return msg, nil
+	case <-sub.done:
+		return nil, ErrSubscriptionCancelled
+	}
+}
+
+// Cancel will remove the subscription from the network
+func (sub *memorySubscription) Cancel() error {
+	sub.once.Do(func() {
+		sub.network.Lock()
+		delete(sub.network.subscriptions[sub.topic], sub)
+		sub.network.Unlock()
+		close(sub.done)
+	})
+	return nil
+}
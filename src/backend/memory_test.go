@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// TestMemoryIO
+func TestMemoryIO(t *testing.T) {
+	ctx := context.Background()
+	WithMemory(t, 2, func(t *testing.T, nodes []*Node) {
+
+		// add a small file and check it is a raw CIDv1 of the content
+		cidStr, err := nodes[0].Add(ctx, []byte(testMessage), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := cid.Decode(cidStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := mh.Sum([]byte(testMessage), mh.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.Equals(cid.NewCidV1(cid.Raw, hash)) {
+			t.Fatalf("unexpected CID for added content: %v", c)
+		}
+
+		// add a multi-chunk file and cat it from the other node
+		bigFile := bytes.Repeat([]byte(testMessage), (2*chunkSize)/len(testMessage))
+		cidStr, err = nodes[0].Add(ctx, bigFile, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := cid.Decode(cidStr); c.Type() != cid.DagProtobuf {
+			t.Fatalf("multi-chunk file does not have a dag-pb root: %v", cidStr)
+		}
+		retrievedData, err := nodes[1].Cat(ctx, cidStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(retrievedData, bigFile) {
+			t.Fatal("retrieved file does not match original")
+		}
+
+		// put a DAG node, nesting the CID of another one
+		data, err := json.Marshal(&testStruct{FieldA: fieldA, FieldB: fieldB})
+		if err != nil {
+			t.Fatal(err)
+		}
+		child, err := nodes[0].DagPut(ctx, data, "json", "cbor", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := cid.Decode(child); c.Type() != cid.DagCBOR {
+			t.Fatalf("DAG node is not dag-cbor: %v", child)
+		}
+		parent, err := nodes[0].DagPut(ctx, []byte(`{"child":{"/":"`+child+`"}}`), "json", "cbor", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// resolve across the link from the other node
+		testCopy := &testStruct{}
+		if err := nodes[1].DagGet(ctx, parent, "child", testCopy); err != nil {
+			t.Fatal(err)
+		}
+		if (testCopy.FieldA != fieldA) || (testCopy.FieldB != fieldB) {
+			t.Fatal("retrieved struct does not match original")
+		}
+		var fieldCopy string
+		if err := nodes[1].DagGet(ctx, parent, "child/fieldA", &fieldCopy); err != nil {
+			t.Fatal(err)
+		}
+		if fieldCopy != fieldA {
+			t.Fatalf("retrieved field does not match original: %v", fieldCopy)
+		}
+	})
+}
+
+// TestMemoryPubSub
+func TestMemoryPubSub(t *testing.T) {
+	ctx := context.Background()
+	WithMemory(t, 2, func(t *testing.T, nodes []*Node) {
+		sender, receiver := nodes[0], nodes[1]
+		self, err := sender.Identity(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// subscribe the receiver to two projects
+		subA, err := receiver.Subscribe(ctx, testProject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subB, err := receiver.Subscribe(ctx, testProject+"-b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := receiver.Subscribe(ctx, testProject); !errors.Is(err, ErrSubscribed) {
+			t.Fatalf("expected ErrSubscribed, got %v", err)
+		}
+		if projects := receiver.Subscriptions(); len(projects) != 2 || projects[0] != testProject {
+			t.Fatalf("unexpected subscriptions: %v", projects)
+		}
+
+		// publish to each project from the sender and check the messages are kept apart
+		for _, sub := range []*ProjectSubscription{subA, subB} {
+			sender.SetProject(sub.Project())
+			if err := sender.Publish(ctx, sub.Project()); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case msg := <-sub.Messages():
+				if string(msg.Data) != sub.Project() {
+					t.Fatal("received message does not match the sent one")
+				}
+				if msg.From.Pretty() != self.ID {
+					t.Fatalf("source address does not match sender address (%v vs %v)", self.ID, msg.From.Pretty())
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for message")
+			}
+		}
+
+		// cancelling one subscription should leave the other
+		if err := subA.Cancel(); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := <-subA.Messages(); ok {
+			t.Fatal("message channel still open after cancelling")
+		}
+		if projects := receiver.Subscriptions(); len(projects) != 1 || projects[0] != subB.Project() {
+			t.Fatalf("unexpected subscriptions: %v", projects)
+		}
+		if err := receiver.Unsubscribe(); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := <-subB.Messages(); ok {
+			t.Fatal("message channel still open after unsubscribing")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+//go:build !windows
+// +build !windows
+
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// detach will start the command in its own session, so it isn't killed along with scribe
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// interrupt will ask the process to shut down cleanly
+func interrupt(proc *os.Process) error {
+	return proc.Signal(os.Interrupt)
+}
+
+// processAlive checks if a process with the PID is running
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// processCommand returns the command line of a running process, along with its environment if it can be read
+//
+// The /proc filesystem is used where it is available, otherwise the command line is read with ps and no environment is returned.
+func processCommand(pid int) ([]string, []string, error) {
+	procDir := fmt.Sprintf("/proc/%d", pid)
+	if cmdline, err := ioutil.ReadFile(procDir + "/cmdline"); err == nil {
+		environ, err := ioutil.ReadFile(procDir + "/environ")
+		if err != nil {
+			return splitNull(cmdline), nil, nil
+		}
+		return splitNull(cmdline), splitNull(environ), nil
+	}
+	out, err := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return nil, nil, err
+	}
+	return strings.Fields(string(out)), nil, nil
+}
+
+// splitNull splits the null separated contents of a /proc file
+func splitNull(data []byte) []string {
+	fields := []string{}
+	for _, field := range bytes.Split(bytes.TrimRight(data, "\x00"), []byte{0}) {
+		fields = append(fields, string(field))
+	}
+	return fields
+}
@@ -0,0 +1,50 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// processQueryLimitedInformation is the access right needed to check a process is running
+const processQueryLimitedInformation = 0x1000
+
+// stillActive is the exit code reported by running processes
+const stillActive = 259
+
+// detach will start the command in a new process group, so it isn't killed along with scribe
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// interrupt will stop the process (Windows has no interrupt signal for other processes)
+func interrupt(proc *os.Process) error {
+	return proc.Kill()
+}
+
+// processAlive checks if a process with the PID is running
+func processAlive(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// processCommand returns the command line of a running process (the environment of another process can't be read)
+func processCommand(pid int) ([]string, []string, error) {
+	query := fmt.Sprintf("(Get-CimInstance Win32_Process -Filter 'ProcessId=%d').CommandLine", pid)
+	out, err := exec.Command("powershell", "-NoProfile", "-Command", query).Output()
+	if err != nil {
+		return nil, nil, err
+	}
+	return strings.Fields(strings.ReplaceAll(string(out), `"`, "")), nil, nil
+}
@@ -5,42 +5,36 @@ import (
 	"fmt"
 	"testing"
 	"time"
-
-	ipfs "github.com/ipfs/go-ipfs-api"
 )
 
 func TestPubSub(t *testing.T) {
+	ctx := context.Background()
 
 	// launch IPFS and then run the test
 	WithIpfs(t, 1, func(t *testing.T, APIaddress string) {
 
 		// init the node
-		node, err := InitNode(APIaddress)
+		node, err := InitNode(ctx, APIaddress)
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		// get the node's identity
-		self, err := node.Identity()
+		self, err := node.Identity(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		// subsribe the node
-		ctx := context.Background()
-		err = node.Subscribe(ctx, testProject)
+		sub, err := node.Subscribe(ctx, testProject)
 		if err != nil {
 			t.Fatal(err)
 		}
+		node.SetProject(testProject)
 
 		// collect all test errors via chan
 		testErrs := make(chan error)
-
-		// start the listener
-		msgChan := make(chan *ipfs.Message)
 		errChan := make(chan error, 1)
-		sigChan := make(chan struct{})
-		go node.Listen(msgChan, errChan, sigChan)
 
 		// process any messages
 		go func() {
@@ -48,7 +42,7 @@ func TestPubSub(t *testing.T) {
 				select {
 
 				// collect any messages
-				case msg := <-msgChan:
+				case msg := <-sub.Messages():
 
 					// check the received message matches the sent one
 					if testMessage != string(msg.Data) {
@@ -71,12 +65,11 @@ func TestPubSub(t *testing.T) {
 		// wait a second and then publish some test message to the network
 		time.Sleep(1 * time.Second)
 		go func() {
-			if err := node.Publish(testMessage); err != nil {
+			if err := node.Publish(ctx, testMessage); err != nil {
 				testErrs <- err
 			}
 
 			// one message is enough for test, signal the close
-			close(sigChan)
 			close(errChan)
 		}()
 
@@ -0,0 +1,226 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	ipfs "github.com/ipfs/go-ipfs-api"
+
+	"github.com/will-rowe/scribe/src/config"
+	"github.com/will-rowe/scribe/src/helpers"
+)
+
+// PubsubFlag is the ConfigChange key used when the running daemon needs pubsub enabling
+// (pubsub is enabled by a daemon flag rather than the IPFS config)
+const PubsubFlag = "--enable-pubsub-experiment"
+
+// apiTimeout is how long to wait for the IPFS API when reconciling the config
+const apiTimeout = 10 * time.Second
+
+// ConfigChange is a change to the IPFS config made by scribe
+type ConfigChange struct {
+	Key string      // the IPFS config key (e.g. Addresses.API)
+	Old interface{} // the value before the change (nil if it was unset)
+	New interface{} // the value required by scribe
+}
+
+// String returns a summary of the change
+func (change ConfigChange) String() string {
+	oldVal, _ := json.Marshal(change.Old)
+	newVal, _ := json.Marshal(change.New)
+	return fmt.Sprintf("%v: %s -> %s", change.Key, oldVal, newVal)
+}
+
+// ConfigReport describes the result of reconciling the IPFS config with the scribe config
+type ConfigReport struct {
+	Changes []ConfigChange // the changes that were made
+	Restart bool           // the running daemon must be restarted for the changes to take effect
+}
+
+// requiredConfig returns the IPFS config values that scribe needs
+func requiredConfig(conf *config.ScribeConfig) []ConfigChange {
+	return []ConfigChange{
+		{Key: "Experimental.Libp2pStreamMounting", New: true},
+		{Key: "Datastore.StorageMax", New: conf.StorageMax},
+		{Key: "Addresses.API", New: conf.APIAddress},
+		{Key: "Addresses.Swarm", New: []string{conf.SwarmAddress}},
+		{Key: "Addresses.Gateway", New: conf.GatewayAddress},
+	}
+}
+
+// reconcileAPI will update the config of a running daemon via the HTTP API
+func reconcileAPI(sh *ipfs.Shell, conf *config.ScribeConfig) (*ConfigReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	// get the current config and work out what needs changing
+	current := make(map[string]interface{})
+	if err := sh.Request("config/show").Exec(ctx, &current); err != nil {
+		return nil, fmt.Errorf("could not read the IPFS config (%v)", err)
+	}
+	report := &ConfigReport{
+		Changes: diffConfig(current, requiredConfig(conf)),
+	}
+
+	// apply the changes, the daemon only reads these values at startup
+	for _, change := range report.Changes {
+		value, err := json.Marshal(change.New)
+		if err != nil {
+			return nil, err
+		}
+		if err := sh.Request("config", change.Key, string(value)).Option("json", true).Exec(ctx, nil); err != nil {
+			return nil, fmt.Errorf("could not set %v in the IPFS config (%v)", change.Key, err)
+		}
+		report.Restart = true
+	}
+
+	// check pubsub is enabled
+	if err := sh.Request("pubsub/ls").Exec(ctx, nil); err != nil {
+		report.Changes = append(report.Changes, ConfigChange{Key: PubsubFlag, Old: false, New: true})
+		report.Restart = true
+	}
+	return report, nil
+}
+
+// reconcileFile will update the config file of an IPFS repo that is not in use by a daemon
+func reconcileFile(conf *config.ScribeConfig) (*ConfigReport, error) {
+	current, err := readRepoConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	report := &ConfigReport{
+		Changes: diffConfig(current, requiredConfig(conf)),
+	}
+	if len(report.Changes) == 0 {
+		return report, nil
+	}
+	for _, change := range report.Changes {
+		if err := setConfigKey(current, change.Key, change.New); err != nil {
+			return nil, err
+		}
+	}
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return report, helpers.WriteFileAtomic(filepath.Join(conf.IpfsPath, "config"), data)
+}
+
+// readRepoConfig will read the config file of an IPFS repo
+func readRepoConfig(conf *config.ScribeConfig) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(filepath.Join(conf.IpfsPath, "config"))
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]interface{})
+	if err := json.Unmarshal(data, &current); err != nil {
+		return nil, fmt.Errorf("could not read the IPFS config (%v)", err)
+	}
+	return current, nil
+}
+
+// repoAPI returns the network address of the API in the config file of an IPFS repo, or an empty string if it can't be read
+//
+// This is where a daemon that was started before the scribe config changed will be serving the API from.
+func repoAPI(conf *config.ScribeConfig) string {
+	current, err := readRepoConfig(conf)
+	if err != nil {
+		return ""
+	}
+	value := getConfigKey(current, "Addresses.API")
+	if list, ok := value.([]interface{}); ok && len(list) != 0 {
+		value = list[0]
+	}
+	address, ok := value.(string)
+	if !ok {
+		return ""
+	}
+	addr, err := config.ParseAddress(address)
+	if err != nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// RepoPeerID will read the peer ID from the config file of an IPFS repo, without needing a daemon
+func RepoPeerID(conf *config.ScribeConfig) (string, error) {
+	current, err := readRepoConfig(conf)
+	if err != nil {
+		return "", err
+	}
+	peerID, ok := getConfigKey(current, "Identity.PeerID").(string)
+	if !ok || len(peerID) == 0 {
+		return "", fmt.Errorf("no peer ID in the IPFS config")
+	}
+	return peerID, nil
+}
+
+// diffConfig returns the required values that don't match the current config, filling in the old values
+func diffConfig(current map[string]interface{}, required []ConfigChange) []ConfigChange {
+	changes := []ConfigChange{}
+	for _, change := range required {
+		change.Old = getConfigKey(current, change.Key)
+		if !configEqual(change.Old, change.New) {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// configEqual compares a value decoded from the IPFS config with a required value
+//
+// Some IPFS config fields (e.g. Addresses.API) accept either a string or a list of strings,
+// so a single string is treated as equal to a list holding just that string.
+func configEqual(current, required interface{}) bool {
+	data, err := json.Marshal(required)
+	if err != nil {
+		return false
+	}
+	var normalised interface{}
+	if err := json.Unmarshal(data, &normalised); err != nil {
+		return false
+	}
+	if list, ok := current.([]interface{}); ok && len(list) == 1 {
+		if _, ok := normalised.(string); ok {
+			current = list[0]
+		}
+	}
+	return reflect.DeepEqual(current, normalised)
+}
+
+// getConfigKey returns the value of a dotted key in the IPFS config (or nil if it isn't set)
+func getConfigKey(cfg map[string]interface{}, key string) interface{} {
+	fields := strings.Split(key, ".")
+	for _, field := range fields[:len(fields)-1] {
+		next, ok := cfg[field].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cfg = next
+	}
+	return cfg[fields[len(fields)-1]]
+}
+
+// setConfigKey sets the value of a dotted key in the IPFS config, creating any missing sections
+func setConfigKey(cfg map[string]interface{}, key string, value interface{}) error {
+	fields := strings.Split(key, ".")
+	for _, field := range fields[:len(fields)-1] {
+		if cfg[field] == nil {
+			cfg[field] = make(map[string]interface{})
+		}
+		next, ok := cfg[field].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("can't set %v in the IPFS config, %v is not a section", key, field)
+		}
+		cfg = next
+	}
+	cfg[fields[len(fields)-1]] = value
+	return nil
+}
@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ipfs "github.com/ipfs/go-ipfs-api"
+
+	"github.com/will-rowe/scribe/src/config"
+)
+
+// testIpfsConfig is a cut down IPFS config, with an API address in list form
+var testIpfsConfig = `{
+  "Addresses": {
+    "API": ["/ip4/127.0.0.1/tcp/5001"],
+    "Gateway": "/ip4/127.0.0.1/tcp/8080",
+    "Swarm": ["/ip4/0.0.0.0/tcp/4001"]
+  },
+  "Datastore": {
+    "StorageMax": "10GB"
+  },
+  "Identity": {
+    "PeerID": "QmTest"
+  }
+}`
+
+// testReconcileConfig returns a scribe config which needs the gateway, swarm, storage and stream mounting updating
+func testReconcileConfig(ipfsPath string) *config.ScribeConfig {
+	return &config.ScribeConfig{
+		IpfsPath:       ipfsPath,
+		StorageMax:     config.DefaultStorageMax,
+		APIAddress:     config.DefaultAPIAddress,
+		SwarmAddress:   config.DefaultSwarmAddress,
+		GatewayAddress: config.DefaultGatewayAddress,
+	}
+}
+
+// TestReconcileFile
+func TestReconcileFile(t *testing.T) {
+	ipfsPath, err := ioutil.TempDir("./", "test-scribe-reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(ipfsPath)
+	if err := ioutil.WriteFile(filepath.Join(ipfsPath, "config"), []byte(testIpfsConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf := testReconcileConfig(ipfsPath)
+
+	// the first run should change everything but the API address
+	report, err := reconcileFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 4 || report.Restart {
+		t.Fatalf("unexpected changes: %v", report.Changes)
+	}
+	for _, change := range report.Changes {
+		if change.Key == "Addresses.API" {
+			t.Fatal("API address already matches and should not be changed")
+		}
+	}
+
+	// the second run should be a no-op
+	report, err = reconcileFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 0 {
+		t.Fatalf("unexpected changes: %v", report.Changes)
+	}
+
+	// unrelated fields should be untouched
+	data, err := ioutil.ReadFile(filepath.Join(ipfsPath, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := make(map[string]interface{})
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if getConfigKey(cfg, "Identity.PeerID") != "QmTest" {
+		t.Fatal("reconcile lost the peer ID")
+	}
+	if peerID, err := RepoPeerID(conf); err != nil || peerID != "QmTest" {
+		t.Fatalf("could not read the peer ID from the repo (%v)", err)
+	}
+}
+
+// TestReconcileAPI
+func TestReconcileAPI(t *testing.T) {
+	current := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(testIpfsConfig), &current); err != nil {
+		t.Fatal(err)
+	}
+
+	// serve a fake IPFS API which has pubsub disabled
+	set := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/config/show":
+			json.NewEncoder(w).Encode(current)
+		case "/api/v0/config":
+			args := r.URL.Query()["arg"]
+			var value interface{}
+			if len(args) != 2 || json.Unmarshal([]byte(args[1]), &value) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			setConfigKey(current, args[0], value)
+			set = append(set, args[0])
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"Message": "experimental pubsub feature not enabled", "Code": 0}`))
+		}
+	}))
+	defer server.Close()
+	sh := ipfs.NewShell(strings.TrimPrefix(server.URL, "http://"))
+	conf := testReconcileConfig("")
+
+	// changes need a restart, as does enabling pubsub
+	report, err := reconcileAPI(sh, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Restart {
+		t.Fatal("running daemon should need a restart")
+	}
+	if len(set) != 4 || len(report.Changes) != 5 || report.Changes[4].Key != PubsubFlag {
+		t.Fatalf("unexpected changes: %v", report.Changes)
+	}
+
+	// the config should now match
+	report, err = reconcileAPI(sh, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 4 || len(report.Changes) != 1 {
+		t.Fatalf("unexpected changes: %v", report.Changes)
+	}
+}
+
+// TestReconcileMovedAPI
+func TestReconcileMovedAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("./", "test-scribe-ipfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// serve a fake IPFS API on the address in the repo config
+	set := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/config/show":
+			w.Write([]byte(testIpfsConfig))
+		case "/api/v0/config":
+			set = append(set, r.URL.Query()["arg"][0])
+			w.Write([]byte("{}"))
+		default:
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoConfig := strings.Replace(testIpfsConfig, "/tcp/5001", "/tcp/"+port, 1)
+	if err := ioutil.WriteFile(filepath.Join(dir, "config"), []byte(repoConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// moving the API address should reconfigure the running daemon rather than its repo
+	conf := testReconcileConfig(dir)
+	report, err := ConfigureDaemon(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Restart || len(set) == 0 {
+		t.Fatalf("running daemon on the old address was not reconfigured: %v", report.Changes)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != repoConfig {
+		t.Fatal("repo config was rewritten under the running daemon")
+	}
+}
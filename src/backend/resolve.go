@@ -2,23 +2,27 @@
 package backend
 
 import (
+	"context"
+
 	ipfs "github.com/ipfs/go-ipfs-api"
 	log "github.com/sirupsen/logrus"
 )
 
 // PublishName will announce `name` to the network and make the node discoverable
-func (node *Node) PublishName(name string) error {
-	if !node.IsOnline() {
-		return ErrOffline
-	}
+func (node *Node) PublishName(ctx context.Context, name string) error {
 	fullName := "scribe:" + string(name)
-	key, err := node.sh.BlockPut([]byte(fullName), "v0", MultiHash, -1)
+	var key string
+	err := node.do(ctx, "publish name", true, func(ctx context.Context) error {
+		var err error
+		key, err = node.store.BlockPut(ctx, []byte(fullName), "v0", MultiHash, -1)
+		return err
+	})
 	log.Debugf("published name: »%s« (key %s)", name, key)
 	return err
 }
 
 // Identity gets the node's identity. It will cache the identity after the initial request
-func (node *Node) Identity() (ipfs.PeerInfo, error) {
+func (node *Node) Identity(ctx context.Context) (ipfs.PeerInfo, error) {
 	node.Lock()
 
 	// check the cached identity first
@@ -32,15 +36,19 @@ func (node *Node) Identity() (ipfs.PeerInfo, error) {
 
 	// don't hold the lock during network operations
 	node.Unlock()
-	id, err := node.sh.ID()
-	if err != nil {
+	var id string
+	if err := node.do(ctx, "identity", true, func(ctx context.Context) error {
+		var err error
+		id, err = node.store.ID(ctx)
+		return err
+	}); err != nil {
 		return ipfs.PeerInfo{}, err
 	}
 	node.Lock()
-	node.identity = id.ID
+	node.identity = id
 	node.Unlock()
 	return ipfs.PeerInfo{
-		Addrs: []string{id.ID},
-		ID:    id.ID,
+		Addrs: []string{id},
+		ID:    id,
 	}, nil
 }
@@ -0,0 +1,142 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	ipfs "github.com/ipfs/go-ipfs-api"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+)
+
+// RetryPolicy controls how Node operations are retried when the IPFS daemon can't be reached
+type RetryPolicy struct {
+	Attempts   int           // the maximum number of attempts (1 means no retries)
+	Timeout    time.Duration // the timeout for each attempt (0 means no timeout)
+	Backoff    time.Duration // the wait before the first retry, doubled for each retry after
+	MaxBackoff time.Duration // the maximum wait between retries
+}
+
+// DefaultRetryPolicy is used by new nodes
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Timeout:    30 * time.Second,
+	Backoff:    500 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// NodeError is returned when a Node operation fails
+//
+// The Kind is one of ErrOffline, ErrTimeout, ErrNotFound or ErrDaemon (or nil if the error
+// couldn't be classified) and can be checked with errors.Is.
+type NodeError struct {
+	Op       string // the operation that failed
+	Kind     error  // the type of failure
+	Attempts int    // the number of attempts made
+	Err      error  // the underlying error
+}
+
+// Error returns the error message
+func (e *NodeError) Error() string {
+	if e.Attempts > 1 {
+		return fmt.Sprintf("%v failed after %d attempts: %v", e.Op, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("%v failed: %v", e.Op, e.Err)
+}
+
+// Is reports if the error is of the target kind
+func (e *NodeError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// Unwrap returns the underlying error
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// SetRetryPolicy will set the retry policy used by the node
+func (node *Node) SetRetryPolicy(policy RetryPolicy) {
+	node.Lock()
+	defer node.Unlock()
+	node.retry = policy
+}
+
+// do will run an operation on the node's store, retrying transient failures according to the retry policy
+//
+// If timeout is false, the policy timeout isn't applied to the attempts (e.g. for subscriptions,
+// which last as long as the context).
+func (node *Node) do(ctx context.Context, op string, timeout bool, fn func(ctx context.Context) error) error {
+	node.Lock()
+	policy := node.retry
+	allowNetwork := node.allowNetwork
+	node.Unlock()
+	if !allowNetwork {
+		return &NodeError{Op: op, Kind: ErrOffline, Err: ErrOffline}
+	}
+	backoff := policy.Backoff
+	attempt := 0
+	for {
+		attempt++
+		attemptCtx, cancel := ctx, func() {}
+		if timeout && policy.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		}
+		err := fn(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		// give up if the error isn't transient, or we are out of attempts or time
+		if !isTransient(err) || attempt >= policy.Attempts || ctx.Err() != nil {
+			return &NodeError{Op: op, Kind: classifyError(err), Attempts: attempt, Err: err}
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return &NodeError{Op: op, Kind: classifyError(ctx.Err()), Attempts: attempt, Err: err}
+		}
+		backoff *= 2
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// isTransient reports if an error is worth retrying (i.e. the daemon could not be reached or the connection dropped)
+func isTransient(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// classifyError returns the kind of error for a NodeError
+func classifyError(err error) error {
+	var apiErr *ipfs.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrOffline):
+		return ErrOffline
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, context.Canceled):
+		return nil
+	case errors.Is(err, ErrBlockNotFound), errors.Is(err, cbornode.ErrNoSuchLink):
+		return ErrNotFound
+	case errors.As(err, &apiErr):
+		msg := strings.ToLower(apiErr.Message)
+		if strings.Contains(msg, "not found") || strings.Contains(msg, "no link named") {
+			return ErrNotFound
+		}
+		return ErrDaemon
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.As(err, &netErr):
+		return ErrOffline
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+// testRetryPolicy keeps the test quick
+var testRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Timeout:    100 * time.Millisecond,
+	Backoff:    time.Millisecond,
+	MaxBackoff: 5 * time.Millisecond,
+}
+
+// flakyStore is a MemoryStore which can't be reached for the first few calls to Add
+type flakyStore struct {
+	*MemoryStore
+	failures int
+	calls    int
+}
+
+// Add will fail with a connection error until the failures are used up
+func (store *flakyStore) Add(ctx context.Context, content []byte, pin bool) (string, error) {
+	store.calls++
+	if store.calls <= store.failures {
+		return "", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return store.MemoryStore.Add(ctx, content, pin)
+}
+
+// TestRetry
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	memStore, err := NewMemoryNetwork().NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// transient errors should be retried
+	store := &flakyStore{MemoryStore: memStore, failures: 2}
+	node, err := InitNodeWithStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.SetRetryPolicy(testRetryPolicy)
+	if _, err := node.Add(ctx, []byte(testMessage), true); err != nil {
+		t.Fatal(err)
+	}
+	if store.calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", store.calls)
+	}
+
+	// running out of attempts should give an offline error
+	store.calls, store.failures = 0, 5
+	_, err = node.Add(ctx, []byte(testMessage), true)
+	if !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.Attempts != 3 || nodeErr.Op != "add" {
+		t.Fatalf("unexpected node error: %#v", err)
+	}
+
+	// missing content should not be retried
+	missing, err := sumBlock([]byte("missing"), cid.DagCBOR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output interface{}
+	err = node.DagGet(ctx, missing.String(), "", &output)
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &nodeErr) || nodeErr.Attempts != 1 {
+		t.Fatalf("expected a single ErrNotFound attempt, got %v", err)
+	}
+
+	// a disconnected node should not try the store
+	store.calls = 0
+	node.Disconnect()
+	if _, err := node.Add(ctx, []byte(testMessage), true); !errors.Is(err, ErrOffline) || store.calls != 0 {
+		t.Fatalf("expected ErrOffline without any attempts, got %v", err)
+	}
+}
+
+// TestDaemonErrors
+func TestDaemonErrors(t *testing.T) {
+	ctx := context.Background()
+
+	// serve a fake IPFS API which stalls on add and rejects other requests
+	stall := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/id":
+			w.Write([]byte(`{"ID": "QmTest"}`))
+		case "/api/v0/add":
+			io.Copy(ioutil.Discard, r.Body)
+			select {
+			case <-stall:
+			case <-r.Context().Done():
+			}
+		case "/api/v0/dag/get":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"Message": "merkledag: not found", "Code": 0}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"Message": "unsupported", "Code": 0}`))
+		}
+	}))
+	defer server.Close()
+	defer close(stall)
+	node, err := InitNode(ctx, strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.SetRetryPolicy(testRetryPolicy)
+
+	// a stalled daemon should time out after all the attempts
+	start := time.Now()
+	if _, err := node.Add(ctx, []byte(testMessage), true); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if time.Since(start) < time.Duration(testRetryPolicy.Attempts)*testRetryPolicy.Timeout {
+		t.Fatal("add gave up before using all the attempts")
+	}
+
+	// cancelling the context should stop the call
+	cancelCtx, cancel := context.WithCancel(ctx)
+	node.SetRetryPolicy(RetryPolicy{Attempts: 1})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := node.Add(cancelCtx, []byte(testMessage), true); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled add, got %v", err)
+	}
+
+	// daemon errors should be classified
+	var output interface{}
+	if err := node.DagGet(ctx, "bafyreigdmqpykrgxyaxtlafqpqhzrb7qy2rh75nldvfd4tucqmqqme5yje", "", &output); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := node.Publish(ctx, testMessage); err != ErrNoProject {
+		t.Fatalf("expected ErrNoProject, got %v", err)
+	}
+	node.SetProject(testProject)
+	if err := node.Publish(ctx, testMessage); !errors.Is(err, ErrDaemon) {
+		t.Fatalf("expected ErrDaemon, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	ipfs "github.com/ipfs/go-ipfs-api"
+	files "github.com/ipfs/go-ipfs-files"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Store is the content-addressed storage and messaging layer that sits behind a Node
+//
+// The IPFS shell is the default implementation, MemoryStore offers an in-process alternative.
+// Every call takes a context, cancelling it will abandon the call.
+type Store interface {
+	IsUp(ctx context.Context) bool                                                                // reports if the store is available
+	ID(ctx context.Context) (string, error)                                                       // returns the peer ID of the store
+	Add(ctx context.Context, content []byte, pin bool) (string, error)                            // adds a file, returning its CID
+	Cat(ctx context.Context, cid string) ([]byte, error)                                          // returns the file for a CID
+	DagPut(ctx context.Context, data []byte, encoding, format string, pin bool) (string, error)   // adds an IPLD node, returning its CID
+	DagGet(ctx context.Context, ref string, output interface{}) error                             // unmarshals the IPLD node at a reference (CID/path)
+	BlockPut(ctx context.Context, block []byte, format, mhType string, mhLen int) (string, error) // adds a raw block, returning its CID
+	Publish(ctx context.Context, topic, message string) error                                     // publishes a message to a pubsub topic
+	Subscribe(ctx context.Context, topic string) (Subscription, error)                            // subscribes to a pubsub topic until the context is done
+}
+
+// Subscription is a subscription to a pubsub topic
+type Subscription interface {
+	Next() (*ipfs.Message, error) // blocks until the next message is received
+	Cancel() error                // cancels the subscription
+}
+
+// shellStore is the Store implementation that uses the HTTP API of an IPFS daemon
+type shellStore struct {
+	sh *ipfs.Shell
+}
+
+// NewShellStore returns a Store that uses the IPFS daemon serving the API endpoint
+func NewShellStore(ipfsAPIendpoint string) Store {
+	return &shellStore{
+		sh: ipfs.NewShell(ipfsAPIendpoint),
+	}
+}
+
+// IsUp checks if the daemon is reachable
+func (store *shellStore) IsUp(ctx context.Context) bool {
+	_, err := store.ID(ctx)
+	return err == nil
+}
+
+// ID returns the peer ID of the daemon
+func (store *shellStore) ID(ctx context.Context) (string, error) {
+	var out ipfs.IdOutput
+	if err := store.sh.Request("id").Exec(ctx, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+// Add will add the content to the IPFS, pinning it if instructed
+func (store *shellStore) Add(ctx context.Context, content []byte, pin bool) (string, error) {
+	var out struct {
+		Hash string
+	}
+	return out.Hash, store.sh.Request("add").
+		Option("pin", pin).
+		Option("hash", MultiHash).
+		Option("cid-version", 1).
+		Body(fileBody(content)).
+		Exec(ctx, &out)
+}
+
+// Cat will return the data for a given CID in the IPFS
+func (store *shellStore) Cat(ctx context.Context, cid string) ([]byte, error) {
+	resp, err := store.sh.Request("cat", cid).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return ioutil.ReadAll(resp.Output)
+}
+
+// DagPut wraps the DagPut API call
+func (store *shellStore) DagPut(ctx context.Context, data []byte, encoding, format string, pin bool) (string, error) {
+	var out struct {
+		Cid struct {
+			Target string `json:"/"`
+		}
+	}
+	return out.Cid.Target, store.sh.Request("dag/put").
+		Option("input-enc", encoding).
+		Option("format", format).
+		Option("pin", pin).
+		Option("hash", MultiHash).
+		Body(fileBody(data)).
+		Exec(ctx, &out)
+}
+
+// DagGet wraps the DagGet API call
+func (store *shellStore) DagGet(ctx context.Context, ref string, output interface{}) error {
+	return store.sh.Request("dag/get", ref).Exec(ctx, output)
+}
+
+// BlockPut wraps the BlockPut API call
+func (store *shellStore) BlockPut(ctx context.Context, block []byte, format, mhType string, mhLen int) (string, error) {
+	var out struct {
+		Key string
+	}
+	return out.Key, store.sh.Request("block/put").
+		Option("mhtype", mhType).
+		Option("format", format).
+		Option("mhlen", mhLen).
+		Body(fileBody(block)).
+		Exec(ctx, &out)
+}
+
+// Publish wraps the PubSubPublish API call
+func (store *shellStore) Publish(ctx context.Context, topic, message string) error {
+	return store.sh.Request("pubsub/pub", topic, message).Exec(ctx, nil)
+}
+
+// Subscribe wraps the PubSubSubscribe API call, the subscription is closed when the context is done
+func (store *shellStore) Subscribe(ctx context.Context, topic string) (Subscription, error) {
+	resp, err := store.sh.Request("pubsub/sub", topic).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		resp.Close()
+		return nil, resp.Error
+	}
+	return &shellSubscription{
+		resp: resp.Output,
+		dec:  json.NewDecoder(resp.Output),
+	}, nil
+}
+
+// fileBody wraps data as a multipart file, which is how the API expects uploads
+func fileBody(data []byte) io.Reader {
+	dir := files.NewSliceDirectory([]files.DirEntry{files.FileEntry("", files.NewBytesFile(data))})
+	return files.NewMultiFileReader(dir, true)
+}
+
+// shellSubscription is a subscription to a topic via the HTTP API
+type shellSubscription struct {
+	resp io.Closer
+	dec  *json.Decoder
+}
+
+// Next waits for the next message on the subscription
+func (sub *shellSubscription) Next() (*ipfs.Message, error) {
+	var msg struct {
+		From     []byte   `json:"from,omitempty"`
+		Data     []byte   `json:"data,omitempty"`
+		Seqno    []byte   `json:"seqno,omitempty"`
+		TopicIDs []string `json:"topicIDs,omitempty"`
+	}
+	if err := sub.dec.Decode(&msg); err != nil {
+		return nil, err
+	}
+	from, err := peer.IDFromBytes(msg.From)
+	if err != nil {
+		return nil, err
+	}
+	return &ipfs.Message{
+		From:     from,
+		Data:     msg.Data,
+		Seqno:    msg.Seqno,
+		TopicIDs: msg.TopicIDs,
+	}, nil
+}
+
+// Cancel will close the subscription
+func (sub *shellSubscription) Cancel() error {
+	return sub.resp.Close()
+}
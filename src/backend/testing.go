@@ -2,14 +2,15 @@
 package backend
 
 import (
-	"fmt"
+	"context"
 	"io/ioutil"
 	"net"
 	"os"
-	"os/exec"
-	"strings"
 	"testing"
-	"time"
+
+	manet "github.com/multiformats/go-multiaddr-net"
+
+	"github.com/will-rowe/scribe/src/config"
 )
 
 // WithIpfs starts a new IPFS instance and calls `fn` with the API port to it.
@@ -23,56 +24,62 @@ func WithIpfs(t *testing.T, portOff int, fn func(t *testing.T, APIaddress string
 	}
 	defer os.RemoveAll(ipfsPath)
 
-	// add some ports
-	tgatewayPort := gatewayPort + portOff
-	tswarmPort := swarmPort + portOff
-	tapiPort := apiPort + portOff
-
-	APIaddress := fmt.Sprintf("%s:%d", apiAddress, tapiPort)
+	// set up a config using the default addresses plus the port offset
+	conf := &config.ScribeConfig{
+		IpfsPath:       ipfsPath,
+		DataDir:        ipfsPath,
+		StorageMax:     config.DefaultStorageMax,
+		APIAddress:     offsetAddress(t, config.DefaultAPIAddress, portOff),
+		SwarmAddress:   offsetAddress(t, config.DefaultSwarmAddress, portOff),
+		GatewayAddress: offsetAddress(t, config.DefaultGatewayAddress, portOff),
+	}
 
 	// run the IPFS daemon
-	os.Setenv("IPFS_PATH", ipfsPath)
-	script := [][]string{
-		{"ipfs", "init"},
-		{"ipfs", "config", "--json", "Experimental.Libp2pStreamMounting", "true"},
-		{"ipfs", "config", "Addresses.API", fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", tapiPort)},
-		{"ipfs", "config", "--json", "Addresses.Swarm", fmt.Sprintf("[\"/ip4/127.0.0.1/tcp/%d\"]", tswarmPort)},
-		{"ipfs", "config", "Addresses.Gateway", fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", tgatewayPort)},
-	}
-	for _, line := range script {
-		cmd := exec.Command(line[0], line[1:]...)
-		cmd.Env = append(cmd.Env, fmt.Sprintf("IPFS_PATH=%s", ipfsPath))
-		err := cmd.Run()
-		if err != nil {
-			t.Fatalf("%v: %v", err, strings.Join(line, " "))
-		}
+	if _, err := ConfigureDaemon(conf); err != nil {
+		t.Fatal(err)
 	}
-	daemonCmd := exec.Command("ipfs", "daemon", "--enable-pubsub-experiment")
-	daemonCmd.Env = append(daemonCmd.Env, fmt.Sprintf("IPFS_PATH=%s", ipfsPath))
-	if err := daemonCmd.Start(); err != nil {
+	if err := LaunchDaemon(conf); err != nil {
 		t.Fatal(err)
 	}
 	defer func() {
-		if err := daemonCmd.Process.Kill(); err != nil {
+		if err := StopDaemon(conf); err != nil {
 			t.Fatal(err)
 		}
 	}()
 
-	// wait until the daemon actually offers the API interface
-	connected := false
-	for tries := 0; tries < 200; tries++ {
-		conn, err := net.Dial("tcp", APIaddress)
-		if err == nil {
-			conn.Close()
-			connected = true
-			break
-		}
-		time.Sleep(100 * time.Millisecond)
+	// run the test
+	fn(t, GetAPI(conf))
+}
+
+// offsetAddress adds an offset to the port of a TCP multiaddr
+func offsetAddress(t *testing.T, address string, portOff int) string {
+	addr, err := config.ParseAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpAddr := addr.(*net.TCPAddr)
+	maddr, err := manet.FromNetAddr(&net.TCPAddr{IP: tcpAddr.IP, Port: tcpAddr.Port + portOff})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !connected {
-		t.Fatal("could not connect to api")
+	return maddr.String()
+}
+
+// WithMemory creates `numNodes` in-memory nodes which share a network and calls `fn` with them.
+// It allows record workflows to be tested without an IPFS daemon.
+func WithMemory(t *testing.T, numNodes int, fn func(t *testing.T, nodes []*Node)) {
+	network := NewMemoryNetwork()
+	nodes := make([]*Node, numNodes)
+	for i := range nodes {
+		store, err := network.NewStore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodes[i], err = InitNodeWithStore(context.Background(), store); err != nil {
+			t.Fatal(err)
+		}
 	}
 
 	// run the test
-	fn(t, APIaddress)
+	fn(t, nodes)
 }
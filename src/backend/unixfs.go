@@ -0,0 +1,176 @@
+// Package backend interfaces with the Go IPFS API and enables pubsub for Scribe data. Inspiration taken from https://github.com/sahib/brig and https://github.com/planet-ethereum/relay-network
+package backend
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// these values match the defaults used by `ipfs add --cid-version=1`
+const (
+	chunkSize   = 262144 // the size-262144 chunker
+	maxLinks    = 174    // the number of links per node in the balanced layout
+	unixfsFile  = 2      // the UnixFS Data_File type
+	pbLinksTag  = 2      // the PBNode field holding links
+	pbDataTag   = 1      // the PBNode field holding data
+	wireVarint  = 0      // protobuf varint wire type
+	wireLenDelm = 2      // protobuf length delimited wire type
+)
+
+// block is a content-addressed block of data
+type block struct {
+	cid  cid.Cid
+	data []byte
+}
+
+// fileNode is a node in the UnixFS DAG built by layoutFile
+type fileNode struct {
+	block
+	fileSize  uint64 // the number of file bytes below this node
+	totalSize uint64 // the cumulative size of this node and all its children
+}
+
+// sumBlock returns the CIDv1 for the data, using the multicodec and the scribe MultiHash
+func sumBlock(data []byte, codec uint64) (cid.Cid, error) {
+	hash, err := mh.Sum(data, mh.Names[MultiHash], -1)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(codec, hash), nil
+}
+
+// layoutFile chunks the content and builds the balanced UnixFS DAG, using raw leaves
+//
+// It returns the blocks in the order they were created; the root is the final block
+func layoutFile(content []byte) ([]block, error) {
+
+	// create the raw leaves (a single empty leaf is used for empty content)
+	blocks := []block{}
+	level := []*fileNode{}
+	for offset := 0; offset == 0 || offset < len(content); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(content) {
+			end = len(content)
+		}
+		chunk := content[offset:end]
+		c, err := sumBlock(chunk, cid.Raw)
+		if err != nil {
+			return nil, err
+		}
+		leaf := &fileNode{
+			block:     block{cid: c, data: chunk},
+			fileSize:  uint64(len(chunk)),
+			totalSize: uint64(len(chunk)),
+		}
+		blocks = append(blocks, leaf.block)
+		level = append(level, leaf)
+	}
+
+	// build up the tree until there is a single root
+	for len(level) > 1 {
+		parents := []*fileNode{}
+		for start := 0; start < len(level); start += maxLinks {
+			end := start + maxLinks
+			if end > len(level) {
+				end = len(level)
+			}
+			parent, err := newFileNode(level[start:end])
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, parent.block)
+			parents = append(parents, parent)
+		}
+		level = parents
+	}
+	return blocks, nil
+}
+
+// newFileNode creates a dag-pb UnixFS file node that links to the children
+func newFileNode(children []*fileNode) (*fileNode, error) {
+
+	// encode the UnixFS data
+	var fileSize uint64
+	unixfs := proto.NewBuffer(nil)
+	unixfs.EncodeVarint(1<<3 | wireVarint)
+	unixfs.EncodeVarint(unixfsFile)
+	for _, child := range children {
+		fileSize += child.fileSize
+	}
+	unixfs.EncodeVarint(3<<3 | wireVarint)
+	unixfs.EncodeVarint(fileSize)
+	for _, child := range children {
+		unixfs.EncodeVarint(4<<3 | wireVarint)
+		unixfs.EncodeVarint(child.fileSize)
+	}
+
+	// encode the PBNode, links are written before the data
+	pbNode := proto.NewBuffer(nil)
+	totalSize := uint64(0)
+	for _, child := range children {
+		link := proto.NewBuffer(nil)
+		link.EncodeVarint(1<<3 | wireLenDelm)
+		link.EncodeRawBytes(child.cid.Bytes())
+		link.EncodeVarint(2<<3 | wireLenDelm)
+		link.EncodeRawBytes([]byte{})
+		link.EncodeVarint(3<<3 | wireVarint)
+		link.EncodeVarint(child.totalSize)
+		pbNode.EncodeVarint(pbLinksTag<<3 | wireLenDelm)
+		pbNode.EncodeRawBytes(link.Bytes())
+		totalSize += child.totalSize
+	}
+	pbNode.EncodeVarint(pbDataTag<<3 | wireLenDelm)
+	pbNode.EncodeRawBytes(unixfs.Bytes())
+	data := pbNode.Bytes()
+	c, err := sumBlock(data, cid.DagProtobuf)
+	if err != nil {
+		return nil, err
+	}
+	return &fileNode{
+		block:     block{cid: c, data: data},
+		fileSize:  fileSize,
+		totalSize: totalSize + uint64(len(data)),
+	}, nil
+}
+
+// fileLinks returns the CIDs linked to by a dag-pb node
+func fileLinks(data []byte) ([]cid.Cid, error) {
+	links := []cid.Cid{}
+	pbNode := proto.NewBuffer(data)
+	for {
+		key, err := pbNode.DecodeVarint()
+		if err != nil {
+			break
+		}
+		switch {
+		case key == pbLinksTag<<3|wireLenDelm:
+			raw, err := pbNode.DecodeRawBytes(false)
+			if err != nil {
+				return nil, err
+			}
+			link := proto.NewBuffer(raw)
+			if tag, err := link.DecodeVarint(); err != nil || tag != 1<<3|wireLenDelm {
+				return nil, fmt.Errorf("malformed dag-pb link")
+			}
+			hash, err := link.DecodeRawBytes(false)
+			if err != nil {
+				return nil, err
+			}
+			c, err := cid.Cast(hash)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, c)
+		case key == pbDataTag<<3|wireLenDelm:
+			if _, err := pbNode.DecodeRawBytes(false); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("malformed dag-pb node")
+		}
+	}
+	return links, nil
+}
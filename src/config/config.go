@@ -4,9 +4,13 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
 	"github.com/spf13/viper"
 
 	"github.com/will-rowe/scribe/src/helpers"
@@ -29,31 +33,60 @@ var (
 	// DefaultIpfsPath for storing IPFS files on this node
 	DefaultIpfsPath = ""
 
+	// DefaultDataDir for storing scribe data on this node
+	DefaultDataDir = ""
+
 	// DefaultStorageMax is the maximum storage available for the IPFS repo
 	DefaultStorageMax = "1GB"
 
 	// DefaultProject to operate on
 	DefaultProject = "scribe-test-project"
+
+	// DefaultAPIAddress is the multiaddr the IPFS daemon serves the API from
+	DefaultAPIAddress = "/ip4/127.0.0.1/tcp/5001"
+
+	// DefaultSwarmAddress is the multiaddr the IPFS daemon listens for peers on
+	DefaultSwarmAddress = "/ip4/127.0.0.1/tcp/4001"
+
+	// DefaultGatewayAddress is the multiaddr the IPFS daemon serves the gateway from
+	DefaultGatewayAddress = "/ip4/127.0.0.1/tcp/8081"
+
+	// DefaultTimeout for each request to the IPFS node
+	DefaultTimeout = "30s"
+
+	// DefaultRetries for requests to the IPFS node that fail to connect
+	DefaultRetries = 2
+
+	// AddressKeys are the config fields that hold the IPFS daemon addresses
+	AddressKeys = []string{"apiAddress", "swarmAddress", "gatewayAddress"}
 )
 
 // ScribeConfig is a struct to hold the config data
 type ScribeConfig struct {
-	FileName     string `json:"fileName"`
-	FileLocation string `json:"fileLocation"`
-	FileType     string `json:"fileType"`
-	License      string `json:"license"`
-	Private      bool   `json:"private"`
-	IpfsPath     string `json:"ipfsPath"`
-	StorageMax   string `json:"storageMax"`
-	Pinning      bool   `json:"pinning"`
-	RemoteCID    string `json:"remoteCID"`
-	Project      string `json:"project"`
+	FileName       string `json:"fileName"`
+	FileLocation   string `json:"fileLocation"`
+	FileType       string `json:"fileType"`
+	License        string `json:"license"`
+	Private        bool   `json:"private"`
+	IpfsPath       string `json:"ipfsPath"`
+	Embedded       bool   `json:"embedded"`
+	DataDir        string `json:"dataDir"`
+	StorageMax     string `json:"storageMax"`
+	APIAddress     string `json:"apiAddress"`
+	SwarmAddress   string `json:"swarmAddress"`
+	GatewayAddress string `json:"gatewayAddress"`
+	Timeout        string `json:"timeout"`
+	Retries        int    `json:"retries"`
+	Pinning        bool   `json:"pinning"`
+	RemoteCID      string `json:"remoteCID"`
+	Project        string `json:"project"`
 }
 
 // init the default config filepaths
 func init() {
 	DefaultLocation, _ = homedir.Dir()
 	DefaultIpfsPath = fmt.Sprintf("%v/.ipfs", DefaultLocation)
+	DefaultDataDir = fmt.Sprintf("%v/.scribe", DefaultLocation)
 }
 
 // GenerateDefault will generate the default config on disk
@@ -61,16 +94,23 @@ func GenerateDefault() error {
 
 	// set up the default config data
 	defaultConfig := &ScribeConfig{
-		FileName:     DefaultName,
-		FileLocation: DefaultLocation,
-		FileType:     DefaultType,
-		License:      DefaultLicense,
-		Private:      false,
-		IpfsPath:     DefaultIpfsPath,
-		StorageMax:   DefaultStorageMax,
-		Pinning:      false,
-		RemoteCID:    "",
-		Project:      DefaultProject,
+		FileName:       DefaultName,
+		FileLocation:   DefaultLocation,
+		FileType:       DefaultType,
+		License:        DefaultLicense,
+		Private:        false,
+		IpfsPath:       DefaultIpfsPath,
+		Embedded:       false,
+		DataDir:        DefaultDataDir,
+		StorageMax:     DefaultStorageMax,
+		APIAddress:     DefaultAPIAddress,
+		SwarmAddress:   DefaultSwarmAddress,
+		GatewayAddress: DefaultGatewayAddress,
+		Timeout:        DefaultTimeout,
+		Retries:        DefaultRetries,
+		Pinning:        false,
+		RemoteCID:      "",
+		Project:        DefaultProject,
 	}
 
 	// create the file
@@ -128,7 +168,8 @@ func DumpConfig2JSON() (string, error) {
 
 // CheckConfig will check the fields of the in-memory Viper config
 // it will attempt to make any directories that don't exist
-func CheckConfig() error {
+// any address fields listed in checkFree are also checked to be available on this machine
+func CheckConfig(checkFree ...string) error {
 
 	// TODO: check for the config file? not actually needed
 
@@ -139,7 +180,69 @@ func CheckConfig() error {
 		}
 	}
 
+	// check the data directory exists, try making it if needed
+	if err := helpers.CheckDirExists(viper.GetString("dataDir")); err != nil {
+		if err := os.MkdirAll(viper.GetString("dataDir"), 0755); err != nil {
+			return fmt.Errorf("can't create new directory for scribe data (%v)", err)
+		}
+	}
+
+	// check the IPFS daemon addresses are valid and don't clash
+	ports := make(map[string]string)
+	for _, key := range AddressKeys {
+		addr, err := ParseAddress(viper.GetString(key))
+		if err != nil {
+			return fmt.Errorf("invalid %v (%v)", key, err)
+		}
+		_, port, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return err
+		}
+		if clash, ok := ports[port]; ok {
+			return fmt.Errorf("%v and %v are both set to port %v", clash, key, port)
+		}
+		ports[port] = key
+	}
+	for _, key := range checkFree {
+		if err := CheckAddressFree(viper.GetString(key)); err != nil {
+			return fmt.Errorf("can't use %v (%v)", key, err)
+		}
+	}
+
+	// check the request settings
+	if timeout, err := time.ParseDuration(viper.GetString("timeout")); err != nil || timeout < 0 {
+		return fmt.Errorf("invalid timeout: %v", viper.GetString("timeout"))
+	}
+	if viper.GetInt("retries") < 0 {
+		return fmt.Errorf("retries can't be negative")
+	}
+
 	// TODO: add more checks as we work on the config
 
 	return nil
 }
+
+// ParseAddress will check a multiaddr is a valid TCP address and return it as a network address
+func ParseAddress(address string) (net.Addr, error) {
+	maddr, err := ma.NewMultiaddr(address)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := maddr.ValueForProtocol(ma.P_TCP); err != nil {
+		return nil, fmt.Errorf("not a TCP address: %v", address)
+	}
+	return manet.ToNetAddr(maddr)
+}
+
+// CheckAddressFree will check that nothing is already listening on a multiaddr
+func CheckAddressFree(address string) error {
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen(addr.Network(), addr.String())
+	if err != nil {
+		return fmt.Errorf("address is not free: %v", address)
+	}
+	return listener.Close()
+}
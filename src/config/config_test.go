@@ -1,9 +1,39 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"testing"
 )
 
 func TestConfig(t *testing.T) {
 
 }
+
+func TestAddresses(t *testing.T) {
+	for _, address := range []string{DefaultAPIAddress, DefaultSwarmAddress, DefaultGatewayAddress, "/ip6/::1/tcp/5001"} {
+		if _, err := ParseAddress(address); err != nil {
+			t.Fatalf("could not parse %v: %v", address, err)
+		}
+	}
+	for _, address := range []string{"", "127.0.0.1:5001", "/ip4/127.0.0.1/udp/4001/quic"} {
+		if _, err := ParseAddress(address); err == nil {
+			t.Fatalf("should not parse %v", address)
+		}
+	}
+
+	// check a bound port is reported as in use
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	address := fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", listener.Addr().(*net.TCPAddr).Port)
+	if err := CheckAddressFree(address); err == nil {
+		t.Fatal("address in use should not be free")
+	}
+	listener.Close()
+	if err := CheckAddressFree(address); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,7 +3,9 @@ package helpers
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 // CheckDirExists checks a directory exists
@@ -27,3 +29,21 @@ func CheckFileExists(filename string) bool {
 	}
 	return !info.IsDir()
 }
+
+// WriteFileAtomic writes to a temporary file and then renames it, so readers never see partial data
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,992 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// CRDTVersion is the version of the CRDT database format written by this version of Scribe
+//
+// Version 1 databases held the tags as complete statuses, and are migrated when they are pulled.
+// Version 2 databases didn't hold the project details, libraries, samples or tag completions.
+const CRDTVersion = 3
+
+// CRDTDatabase is a conflict-free replicated version of the ProjectDatabase
+//
+// Each replica records its changes in its own copy and the copies are combined with Merge, which is
+// deterministic: replicas that have merged the same changes hold identical databases
+// and push identical root CIDs, regardless of the order the changes arrived in.
+//
+// Projects, Runs, Libraries and Samples are kept in observed-remove maps (a concurrent add and remove
+// keeps the entry), Run histories are grow-only logs, Run tags keep the state that has progressed
+// furthest and project owners are a grow-only set. The details set when a project is registered are
+// first-writer-wins registers, and the other fields are last-writer-wins registers ordered by a
+// hybrid clock and the replica ID.
+type CRDTDatabase struct {
+	Pin      bool // pin the database when pushing it to the IPFS
+	replica  string
+	clock    clockTime
+	projects orSet
+	states   map[string]*crdtProject
+}
+
+// clockTime is a time in nanoseconds from the hybrid clock
+//
+// Times are stored as strings, as IPLD can't hold nanosecond times as JSON numbers.
+type clockTime int64
+
+// MarshalJSON will encode the time as a string
+func (clock clockTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(clock), 10))
+}
+
+// UnmarshalJSON will decode the time from a string
+func (clock *clockTime) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	*clock = clockTime(parsed)
+	return err
+}
+
+// timestamp will return the time as a protobuf timestamp
+func (clock clockTime) timestamp() *timestamp.Timestamp {
+	ts, err := ptypes.TimestampProto(time.Unix(0, int64(clock)))
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+// crdtStamp orders changes made by the replicas, by time and then by replica ID
+type crdtStamp struct {
+	Time    clockTime `json:"time"`
+	Replica string    `json:"replica"`
+}
+
+// after reports whether the stamp is ordered after another one
+func (stamp crdtStamp) after(other crdtStamp) bool {
+	return stamp.Time > other.Time || (stamp.Time == other.Time && stamp.Replica > other.Replica)
+}
+
+// messageStamp will return the stamp for the change in a message envelope, which is made by the sender at the time it was sent
+//
+// Replicas that apply the same message record the same change, whenever they receive it.
+func messageStamp(msg *Message) crdtStamp {
+	stamp := crdtStamp{Replica: msg.GetSender()}
+	if sent, err := ptypes.Timestamp(msg.GetTimestamp()); err == nil {
+		stamp.Time = clockTime(sent.UnixNano())
+	}
+	return stamp
+}
+
+// versionStamp will return the stamp for the changes in a database that was pushed without its replicated state
+//
+// The changes are made by the author of the version at the time it was pushed, so replicas that pull the
+// same database record the same changes. Databases pushed before versions were recorded are ordered before
+// any other change, and are told apart by their CID.
+func versionStamp(version *DatabaseVersion, cid string) crdtStamp {
+	stamp := crdtStamp{Replica: version.GetAuthor()}
+	if pushed, err := ptypes.Timestamp(version.GetTimestamp()); err == nil {
+		stamp.Time = clockTime(pushed.UnixNano())
+	}
+	if stamp.Time <= 0 {
+		stamp.Time = 1
+	}
+	if len(stamp.Replica) == 0 {
+		stamp.Replica = cid
+	}
+	return stamp
+}
+
+// lwwRegister is a last-writer-wins register holding a string
+type lwwRegister struct {
+	Value string    `json:"value,omitempty"`
+	Stamp crdtStamp `json:"stamp"`
+}
+
+// merge will keep the most recently written value, reporting whether the register has changed
+func (reg *lwwRegister) merge(other lwwRegister) bool {
+	if !other.Stamp.after(reg.Stamp) {
+		return false
+	}
+	*reg = other
+	return true
+}
+
+// fwwRegister is a first-writer-wins register holding a string, for details that are set once (e.g. when a project is registered)
+//
+// Empty values aren't written, so the register keeps the first value that was set.
+type fwwRegister lwwRegister
+
+// merge will keep the earliest written value, reporting whether the register has changed
+func (reg *fwwRegister) merge(other fwwRegister) bool {
+	if len(other.Value) == 0 || (len(reg.Value) != 0 && !reg.Stamp.after(other.Stamp)) {
+		return false
+	}
+	*reg = other
+	return true
+}
+
+// lwwList is a last-writer-wins register holding a list of strings
+type lwwList struct {
+	Values []string  `json:"values,omitempty"`
+	Stamp  crdtStamp `json:"stamp"`
+}
+
+// merge will keep the most recently written list, reporting whether the register has changed
+func (reg *lwwList) merge(other lwwList) bool {
+	if !other.Stamp.after(reg.Stamp) {
+		return false
+	}
+	reg.Values, reg.Stamp = append([]string(nil), other.Values...), other.Stamp
+	return true
+}
+
+// orSet is an observed-remove set, recording the time of each replica's latest add and remove of an element
+//
+// An element is in the set if a replica has added it since that replica's add was last removed,
+// so a remove only affects the adds it has seen.
+type orSet struct {
+	Adds    map[string]map[string]clockTime `json:"adds,omitempty"`    // element -> replica -> time
+	Removes map[string]map[string]clockTime `json:"removes,omitempty"` // element -> replica -> time of the removed add
+}
+
+// add will add an element to the set
+func (set *orSet) add(element string, stamp crdtStamp) {
+	set.Adds = setClock(set.Adds, element, stamp.Replica, stamp.Time)
+}
+
+// remove will remove an element from the set
+func (set *orSet) remove(element string) {
+	for replica, added := range set.Adds[element] {
+		set.Removes = setClock(set.Removes, element, replica, added)
+	}
+}
+
+// contains reports whether an element is in the set
+func (set *orSet) contains(element string) bool {
+	for replica, added := range set.Adds[element] {
+		if added > set.Removes[element][replica] {
+			return true
+		}
+	}
+	return false
+}
+
+// elements returns the sorted elements in the set
+func (set *orSet) elements() []string {
+	elements := []string{}
+	for element := range set.Adds {
+		if set.contains(element) {
+			elements = append(elements, element)
+		}
+	}
+	sort.Strings(elements)
+	return elements
+}
+
+// merge will combine the adds and removes of two sets
+func (set *orSet) merge(other orSet) {
+	for element, replicas := range other.Adds {
+		for replica, added := range replicas {
+			set.Adds = setClock(set.Adds, element, replica, added)
+		}
+	}
+	for element, replicas := range other.Removes {
+		for replica, removed := range replicas {
+			set.Removes = setClock(set.Removes, element, replica, removed)
+		}
+	}
+}
+
+// setClock will advance the time recorded for a replica in a set
+func setClock(clocks map[string]map[string]clockTime, element, replica string, at clockTime) map[string]map[string]clockTime {
+	if clocks == nil {
+		clocks = make(map[string]map[string]clockTime)
+	}
+	if clocks[element] == nil {
+		clocks[element] = make(map[string]clockTime)
+	}
+	if at > clocks[element][replica] {
+		clocks[element][replica] = at
+	}
+	return clocks
+}
+
+// orMap is an observed-remove map of record labels to CIDs, holding each CID in a last-writer-wins register
+type orMap struct {
+	Labels orSet                  `json:"labels"`
+	CIDs   map[string]lwwRegister `json:"CIDs,omitempty"`
+}
+
+// put will add a record to the map, or update its CID
+func (records *orMap) put(label, cid string, stamp crdtStamp) {
+	records.Labels.add(label, stamp)
+	records.write(label, lwwRegister{Value: cid, Stamp: stamp})
+}
+
+// write will merge a CID into the register for a label
+func (records *orMap) write(label string, cid lwwRegister) {
+	if records.CIDs == nil {
+		records.CIDs = make(map[string]lwwRegister)
+	}
+	current := records.CIDs[label]
+	if current.merge(cid) {
+		records.CIDs[label] = current
+	}
+}
+
+// entries returns the CIDs of the records in the map, or nil if there are none
+func (records *orMap) entries() map[string]string {
+	labels := records.Labels.elements()
+	if len(labels) == 0 {
+		return nil
+	}
+	entries := make(map[string]string, len(labels))
+	for _, label := range labels {
+		entries[label] = records.CIDs[label].Value
+	}
+	return entries
+}
+
+// merge will combine the records of two maps
+func (records *orMap) merge(other orMap) {
+	records.Labels.merge(other.Labels)
+	for label, cid := range other.CIDs {
+		records.write(label, cid)
+	}
+}
+
+// crdtComment is an entry in a grow-only history log
+type crdtComment struct {
+	Time clockTime `json:"time"`
+	Text string    `json:"text"`
+}
+
+// crdtCompletion records that a tagged service has completed, at the time of its reply
+type crdtCompletion struct {
+	ResultCID string    `json:"resultCID,omitempty"`
+	Stamp     crdtStamp `json:"stamp"`
+}
+
+// crdtRun is the replicated state of a Run
+type crdtRun struct {
+	Created              lwwRegister               `json:"created"`
+	ParentProjectLabel   lwwRegister               `json:"parentProjectLabel"`
+	ParentProjectCID     lwwRegister               `json:"parentProjectCID"`
+	Status               lwwRegister               `json:"status"`
+	RequestOrder         lwwList                   `json:"requestOrder"`
+	OutputDirectory      lwwRegister               `json:"outputDirectory"`
+	Fast5OutputDirectory lwwRegister               `json:"fast5OutputDirectory"`
+	FastqOutputDirectory lwwRegister               `json:"fastqOutputDirectory"`
+	Library              lwwRegister               `json:"library"`
+	Samples              lwwList                   `json:"samples"`
+	History              map[string]crdtComment    `json:"history,omitempty"` // keyed by content, so the same comment is only recorded once
+	Tags                 map[string]crdtTag        `json:"tags,omitempty"`
+	Completions          map[string]crdtCompletion `json:"completions,omitempty"` // the latest reply from each tagged service
+	recordCID            string                    // the CID of the Run built from this state (empty if it has changed since)
+	nodeCID              string                    // the CID of the IPLD node holding this state (empty if it has changed since)
+}
+
+// touch will record that the state has changed, so that it is stored again
+func (run *crdtRun) touch() {
+	run.recordCID, run.nodeCID = "", ""
+}
+
+// put will record the fields of a run that differ from a previous version of it (or every field if there is no previous version)
+//
+// Comments are added to the history and tags are merged, so neither can be removed. It reports whether the state has changed.
+func (run *crdtRun) put(record, previous *Run, stamp crdtStamp) (bool, error) {
+	all, changed := previous == nil, false
+	write := func(reg *lwwRegister, value, previousValue string) {
+		if all || value != previousValue {
+			changed = reg.merge(lwwRegister{Value: value, Stamp: stamp}) || changed
+		}
+	}
+	writeList := func(reg *lwwList, values, previousValues []string) {
+		if all || !stringsEqual(values, previousValues) {
+			changed = reg.merge(lwwList{Values: values, Stamp: stamp}) || changed
+		}
+	}
+	write(&run.Created, registerTime(record.GetCreated()), registerTime(previous.GetCreated()))
+	write(&run.ParentProjectLabel, record.GetParentProjectLabel(), previous.GetParentProjectLabel())
+	write(&run.ParentProjectCID, record.GetParentProjectCID(), previous.GetParentProjectCID())
+	write(&run.Status, record.GetStatus().String(), previous.GetStatus().String())
+	writeList(&run.RequestOrder, record.GetRequestOrder(), previous.GetRequestOrder())
+	write(&run.OutputDirectory, record.GetOutputDirectory(), previous.GetOutputDirectory())
+	write(&run.Fast5OutputDirectory, record.GetFast5OutputDirectory(), previous.GetFast5OutputDirectory())
+	write(&run.FastqOutputDirectory, record.GetFastqOutputDirectory(), previous.GetFastqOutputDirectory())
+	write(&run.Library, record.GetLibrary(), previous.GetLibrary())
+	writeList(&run.Samples, record.GetSamples(), previous.GetSamples())
+	for _, comment := range record.GetHistory() {
+		changed = run.addComment(crdtComment{Time: stampTime(comment.GetTimestamp()), Text: comment.GetText()}) || changed
+	}
+	for label, state := range record.GetTags() {
+		if !all && proto.Equal(state, previous.GetTags()[label]) {
+			continue
+		}
+		tag, err := newCRDTTag(state, stamp)
+		if err != nil {
+			return changed, err
+		}
+		changed = run.mergeTag(label, tag) || changed
+	}
+	if changed {
+		run.touch()
+	}
+	return changed, nil
+}
+
+// merge will combine the state of two runs, reporting whether the state has changed
+func (run *crdtRun) merge(other *crdtRun) bool {
+	changed := run.Created.merge(other.Created)
+	changed = run.ParentProjectLabel.merge(other.ParentProjectLabel) || changed
+	changed = run.ParentProjectCID.merge(other.ParentProjectCID) || changed
+	changed = run.Status.merge(other.Status) || changed
+	changed = run.RequestOrder.merge(other.RequestOrder) || changed
+	changed = run.OutputDirectory.merge(other.OutputDirectory) || changed
+	changed = run.Fast5OutputDirectory.merge(other.Fast5OutputDirectory) || changed
+	changed = run.FastqOutputDirectory.merge(other.FastqOutputDirectory) || changed
+	changed = run.Library.merge(other.Library) || changed
+	changed = run.Samples.merge(other.Samples) || changed
+	for _, comment := range other.History {
+		changed = run.addComment(comment) || changed
+	}
+	for label, tag := range other.Tags {
+		changed = run.mergeTag(label, tag) || changed
+	}
+	for label, completion := range other.Completions {
+		changed = run.mergeCompletion(label, completion) || changed
+	}
+	if changed {
+		run.touch()
+	}
+	return changed
+}
+
+// addComment will add a comment to the history log, reporting whether it is new
+func (run *crdtRun) addComment(comment crdtComment) bool {
+	key := fmt.Sprintf("%d-%x", comment.Time, sha256.Sum256([]byte(comment.Text)))
+	if _, ok := run.History[key]; ok {
+		return false
+	}
+	if run.History == nil {
+		run.History = make(map[string]crdtComment)
+	}
+	run.History[key] = comment
+	return true
+}
+
+// mergeTag will keep the tag state that has progressed furthest, or the most recently written one, reporting whether the tag has changed
+func (run *crdtRun) mergeTag(label string, tag crdtTag) bool {
+	if run.Tags == nil {
+		run.Tags = make(map[string]crdtTag)
+	}
+	current, ok := run.Tags[label]
+	if ok {
+		order := compareTags(tag.tagState(), current.tagState())
+		if order < 0 || (order == 0 && !tag.Stamp.after(current.Stamp)) {
+			return false
+		}
+	}
+	run.Tags[label] = tag
+	return true
+}
+
+// mergeCompletion will keep the most recent completion of a tag, reporting whether it has changed
+func (run *crdtRun) mergeCompletion(label string, completion crdtCompletion) bool {
+	if current, ok := run.Completions[label]; ok && !completion.Stamp.after(current.Stamp) {
+		return false
+	}
+	if run.Completions == nil {
+		run.Completions = make(map[string]crdtCompletion)
+	}
+	run.Completions[label] = completion
+	return true
+}
+
+// view will build the Run held by the state
+//
+// The Run has the empty history, tags and request order of a run from InitRun, so that a stored run
+// is built with the same CID. A tag that has completed since its state was last written has succeeded,
+// finishing at the time of the completion.
+func (run *crdtRun) view(label string) (*Run, error) {
+	record := &Run{
+		Label:                label,
+		ParentProjectLabel:   run.ParentProjectLabel.Value,
+		ParentProjectCID:     run.ParentProjectCID.Value,
+		History:              []*Comment{},
+		Status:               Status(Status_value[run.Status.Value]),
+		Tags:                 make(map[string]*TagState, len(run.Tags)),
+		RequestOrder:         append([]string{}, run.RequestOrder.Values...),
+		OutputDirectory:      run.OutputDirectory.Value,
+		Fast5OutputDirectory: run.Fast5OutputDirectory.Value,
+		FastqOutputDirectory: run.FastqOutputDirectory.Value,
+		Library:              run.Library.Value,
+		Samples:              append([]string(nil), run.Samples.Values...),
+	}
+	var err error
+	if record.Created, err = parseRegisterTime(run.Created.Value); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(run.History))
+	for key := range run.History {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := run.History[keys[i]], run.History[keys[j]]
+		return a.Time < b.Time || (a.Time == b.Time && keys[i] < keys[j])
+	})
+	for _, key := range keys {
+		record.History = append(record.History, &Comment{Timestamp: run.History[key].Time.timestamp(), Text: run.History[key].Text})
+	}
+	for label, tag := range run.Tags {
+		state := tag.tagState()
+		if completion, ok := run.Completions[label]; ok && completion.Stamp.after(tag.Stamp) && state.GetState() != TagState_succeeded {
+			state.State, state.Finished, state.ResultCID = TagState_succeeded, completion.Stamp.Time.timestamp(), completion.ResultCID
+		}
+		record.Tags[label] = state
+	}
+	return record, nil
+}
+
+// crdtTag is the replicated state of a tag, holding a TagState as json
+type crdtTag struct {
+	State string    `json:"state"`
+	Stamp crdtStamp `json:"stamp"`
+}
+
+// newCRDTTag will record a tag state written at a stamp
+func newCRDTTag(state *TagState, stamp crdtStamp) (crdtTag, error) {
+	data, err := marshalRecord(state)
+	if err != nil {
+		return crdtTag{}, err
+	}
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, data); err != nil {
+		return crdtTag{}, err
+	}
+	return crdtTag{State: compacted.String(), Stamp: stamp}, nil
+}
+
+// tagState will return the TagState held by the tag
+func (tag crdtTag) tagState() *TagState {
+	state := NewTagState()
+	unmarshalRecord([]byte(tag.State), state)
+	return state
+}
+
+// UnmarshalJSON will decode a tag, migrating the complete status of tags from version 1 databases
+func (tag *crdtTag) UnmarshalJSON(data []byte) error {
+	var complete bool
+	if err := json.Unmarshal(data, &complete); err == nil {
+		migrated, err := newCRDTTag(legacyTagState(complete), crdtStamp{})
+		*tag = migrated
+		return err
+	}
+	type plainTag crdtTag
+	return json.Unmarshal(data, (*plainTag)(tag))
+}
+
+// crdtProject is the replicated state of a Project
+type crdtProject struct {
+	ID          fwwRegister     `json:"id"`
+	Created     fwwRegister     `json:"created"`
+	Description fwwRegister     `json:"description"`
+	License     fwwRegister     `json:"license"`
+	Owners      map[string]bool `json:"owners,omitempty"`
+	Runs        orSet           `json:"runs"`
+	Libraries   orMap           `json:"libraries"`
+	Samples     orMap           `json:"samples"`
+	states      map[string]*crdtRun
+}
+
+// merge will combine the state of two projects
+func (project *crdtProject) merge(other *crdtProject) {
+	project.ID.merge(other.ID)
+	project.Created.merge(other.Created)
+	project.Description.merge(other.Description)
+	project.License.merge(other.License)
+	for owner := range other.Owners {
+		project.addOwner(owner)
+	}
+	project.Runs.merge(other.Runs)
+	project.Libraries.merge(other.Libraries)
+	project.Samples.merge(other.Samples)
+	for label, state := range other.states {
+		if _, ok := project.states[label]; !ok {
+
+			// a run that is new to the project is built and stored the same as the other replica's
+			run := project.run(label)
+			run.merge(state)
+			run.recordCID, run.nodeCID = state.recordCID, state.nodeCID
+			continue
+		}
+		project.run(label).merge(state)
+	}
+}
+
+// addOwner will add an owner to the project
+func (project *crdtProject) addOwner(owner string) {
+	if project.Owners == nil {
+		project.Owners = make(map[string]bool)
+	}
+	project.Owners[owner] = true
+}
+
+// run will return the state of a run, creating it if needed
+func (project *crdtProject) run(label string) *crdtRun {
+	if project.states == nil {
+		project.states = make(map[string]*crdtRun)
+	}
+	if project.states[label] == nil {
+		project.states[label] = &crdtRun{}
+	}
+	return project.states[label]
+}
+
+// NewCRDTDatabase will create an empty replicated database for a replica (e.g. the IPFS peer ID of the node)
+func NewCRDTDatabase(replica string) *CRDTDatabase {
+	return &CRDTDatabase{
+		Pin:     true,
+		replica: replica,
+		states:  make(map[string]*crdtProject),
+	}
+}
+
+// next will return a stamp for a new change, which is ordered after every change seen by the replica
+func (db *CRDTDatabase) next() crdtStamp {
+	now := clockTime(time.Now().UnixNano())
+	if now <= db.clock {
+		now = db.clock + 1
+	}
+	db.clock = now
+	return crdtStamp{Time: now, Replica: db.replica}
+}
+
+// observe will advance the clock to a change made by another replica, so that the next change is ordered after it
+func (db *CRDTDatabase) observe(stamp crdtStamp) {
+	if stamp.Time > db.clock {
+		db.clock = stamp.Time
+	}
+}
+
+// project will return the state of a project, creating it if needed
+func (db *CRDTDatabase) project(label string) *crdtProject {
+	if db.states[label] == nil {
+		db.states[label] = &crdtProject{}
+	}
+	return db.states[label]
+}
+
+// Projects returns the labels of the projects in the database
+func (db *CRDTDatabase) Projects() []string {
+	return db.projects.elements()
+}
+
+// Runs returns the labels of the runs in a project
+func (db *CRDTDatabase) Runs(project string) []string {
+	if !db.projects.contains(project) {
+		return []string{}
+	}
+	return db.states[project].Runs.elements()
+}
+
+// Run will return a run from a project
+func (db *CRDTDatabase) Run(project, label string) (*Run, error) {
+	if !db.projects.contains(project) || !db.states[project].Runs.contains(label) {
+		return nil, ErrNotFound
+	}
+	return db.states[project].run(label).view(label)
+}
+
+// putProject will add a project to the database, recording the details and records that differ from a previous version of it (if there is one)
+//
+// Records that are in the previous version but not the project are removed.
+func (db *CRDTDatabase) putProject(ctx context.Context, node *backend.Node, project, previous *Project, stamp crdtStamp) error {
+	label := project.GetLabel()
+	db.projects.add(label, stamp)
+	db.observe(stamp)
+	state := db.project(label)
+	state.ID.merge(fwwRegister{Value: project.GetId(), Stamp: stamp})
+	state.Created.merge(fwwRegister{Value: registerTime(project.GetCreated()), Stamp: stamp})
+	state.Description.merge(fwwRegister{Value: project.GetDescription(), Stamp: stamp})
+	state.License.merge(fwwRegister{Value: project.GetLicense(), Stamp: stamp})
+	for _, owner := range project.GetOwners() {
+		state.addOwner(owner)
+	}
+	for runLabel, cid := range project.GetRuns() {
+		if previousCID := previous.GetRuns()[runLabel]; cid != previousCID {
+			if err := db.putRun(ctx, node, label, runLabel, cid, previousCID, stamp); err != nil {
+				return err
+			}
+		}
+	}
+	for runLabel := range previous.GetRuns() {
+		if _, ok := project.GetRuns()[runLabel]; !ok {
+			state.Runs.remove(runLabel)
+		}
+	}
+	putRecords(&state.Libraries, project.GetLibraries(), previous.GetLibraries(), stamp)
+	putRecords(&state.Samples, project.GetSamples(), previous.GetSamples(), stamp)
+	return nil
+}
+
+// putRecords will record the CIDs that differ from a previous version of a project's records, and remove the records that are no longer there
+func putRecords(records *orMap, cids, previous map[string]string, stamp crdtStamp) {
+	for label, cid := range cids {
+		if cid != previous[label] {
+			records.put(label, cid, stamp)
+		}
+	}
+	for label := range previous {
+		if _, ok := cids[label]; !ok {
+			records.Labels.remove(label)
+		}
+	}
+}
+
+// putRun will add a run stored at a CID to a project, recording the fields that differ from the version at the previous CID (if there is one)
+//
+// An update counts as an add, so it will be kept over a concurrent removal of the run.
+func (db *CRDTDatabase) putRun(ctx context.Context, node *backend.Node, project, label, cid, previousCID string, stamp crdtStamp) error {
+	record := &Run{}
+	if err := getRecord(ctx, node, cid, record); err != nil {
+		return err
+	}
+	var previous *Run
+	if len(previousCID) != 0 {
+		previous = &Run{}
+		if err := getRecord(ctx, node, previousCID, previous); err != nil {
+			return err
+		}
+	}
+	db.projects.add(project, stamp)
+	state := db.project(project)
+	state.Runs.add(label, stamp)
+	if _, err := state.run(label).put(record, previous, stamp); err != nil {
+		return err
+	}
+	db.observe(stamp)
+	return nil
+}
+
+// putLibrary will add a library stored at a CID to a project, or update its CID
+func (db *CRDTDatabase) putLibrary(project, label, cid string, stamp crdtStamp) {
+	db.projects.add(project, stamp)
+	db.project(project).Libraries.put(label, cid, stamp)
+	db.observe(stamp)
+}
+
+// completeTag will record that a tagged service has completed a run
+//
+// Completions for runs or tags that aren't in the database are rejected, so that services can't add tags to a run.
+func (db *CRDTDatabase) completeTag(project, label, tag, resultCID string, stamp crdtStamp) error {
+	if !db.projects.contains(project) {
+		return ErrNotFound
+	}
+	state := db.states[project]
+	if !state.Runs.contains(label) {
+		return fmt.Errorf("run not found in %v: %v", project, label)
+	}
+	run := state.run(label)
+	if _, ok := run.Tags[tag]; !ok {
+		return fmt.Errorf("tag not found on run %v: %v", label, tag)
+	}
+	if run.mergeCompletion(tag, crdtCompletion{ResultCID: resultCID, Stamp: stamp}) {
+		run.touch()
+	}
+	db.observe(stamp)
+	return nil
+}
+
+// record will record the changes from one version of a database to another, as changes made at a stamp
+func (db *CRDTDatabase) record(ctx context.Context, node *backend.Node, from, to *ProjectDatabase, stamp crdtStamp) error {
+	for label, project := range to.GetProjects() {
+		previous, ok := from.GetProjects()[label]
+		if ok && sameProject(project, previous) {
+			continue
+		}
+		project = proto.Clone(project).(*Project)
+		project.Label = label
+		if err := db.putProject(ctx, node, project, previous, stamp); err != nil {
+			return err
+		}
+	}
+	for label := range from.GetProjects() {
+		if _, ok := to.GetProjects()[label]; !ok {
+			db.projects.remove(label)
+		}
+	}
+	return nil
+}
+
+// build will update a database to hold the projects in the replicated state, storing the runs that have changed since they were last built
+//
+// Projects that are already in the database are updated in place, so that references to them stay valid.
+func (db *CRDTDatabase) build(ctx context.Context, node *backend.Node, out *ProjectDatabase) error {
+	projects := make(map[string]*Project)
+	for _, label := range db.projects.elements() {
+		project, err := db.buildProject(ctx, node, label)
+		if err != nil {
+			return err
+		}
+		if existing, ok := out.GetProjects()[label]; ok {
+			*existing = *project
+			project = existing
+		}
+		projects[label] = project
+	}
+	out.Projects = projects
+	return nil
+}
+
+// buildProject will build a Project from the replicated state, storing the runs that have changed since they were last built
+func (db *CRDTDatabase) buildProject(ctx context.Context, node *backend.Node, label string) (*Project, error) {
+	state := db.project(label)
+	project := &Project{
+		Label:       label,
+		Id:          state.ID.Value,
+		Description: state.Description.Value,
+		License:     state.License.Value,
+		Libraries:   state.Libraries.entries(),
+		Samples:     state.Samples.entries(),
+	}
+	var err error
+	if project.Created, err = parseRegisterTime(state.Created.Value); err != nil {
+		return nil, err
+	}
+	for owner := range state.Owners {
+		project.Owners = append(project.Owners, owner)
+	}
+	sort.Strings(project.Owners)
+	for _, runLabel := range state.Runs.elements() {
+		run := state.run(runLabel)
+		if len(run.recordCID) == 0 {
+			record, err := run.view(runLabel)
+			if err != nil {
+				return nil, err
+			}
+			if run.recordCID, err = putRecord(ctx, node, record, db.Pin); err != nil {
+				return nil, err
+			}
+		}
+		if project.Runs == nil {
+			project.Runs = make(map[string]string)
+		}
+		project.Runs[runLabel] = run.recordCID
+	}
+	return project, nil
+}
+
+// adopt will record the CIDs of the runs in a database that was built from the replicated state, so that they aren't stored again
+func (db *CRDTDatabase) adopt(built *ProjectDatabase) {
+	for label, project := range built.GetProjects() {
+		state, ok := db.states[label]
+		if !ok {
+			continue
+		}
+		for runLabel, cid := range project.GetRuns() {
+			if run, ok := state.states[runLabel]; ok && len(run.recordCID) == 0 {
+				run.recordCID = cid
+			}
+		}
+	}
+}
+
+// Merge will combine the changes from another replica into the database
+func (db *CRDTDatabase) Merge(other *CRDTDatabase) {
+	db.observe(crdtStamp{Time: other.clock})
+	db.projects.merge(other.projects)
+	for label, state := range other.states {
+		db.project(label).merge(state)
+	}
+}
+
+// encode will marshal the whole of the replicated state as json, so that states can be compared
+func (db *CRDTDatabase) encode() ([]byte, error) {
+	type projectState struct {
+		Project *crdtProject        `json:"project"`
+		Runs    map[string]*crdtRun `json:"runs"`
+	}
+	states := make(map[string]projectState, len(db.states))
+	for label, state := range db.states {
+		states[label] = projectState{state, state.states}
+	}
+	return json.Marshal(struct {
+		Clock    clockTime               `json:"clock"`
+		Projects orSet                   `json:"projects"`
+		States   map[string]projectState `json:"states"`
+	}{db.clock, db.projects, states})
+}
+
+// crdtDatabaseNode is the IPLD node for the root of a CRDTDatabase
+type crdtDatabaseNode struct {
+	Version  int                 `json:"version"`
+	Clock    clockTime           `json:"clock"`
+	Projects orSet               `json:"projects"`
+	States   map[string]ipldLink `json:"states,omitempty"`
+}
+
+// crdtProjectNode is the IPLD node for a project in a CRDTDatabase
+type crdtProjectNode struct {
+	crdtProject
+	States map[string]ipldLink `json:"states,omitempty"`
+}
+
+// Push will store the database in the IPFS, with a linked IPLD node for each project and run, and return the root CID
+//
+// Runs that haven't changed since they were last pushed or pulled are not stored again.
+func (db *CRDTDatabase) Push(ctx context.Context, node *backend.Node) (string, error) {
+	root := crdtDatabaseNode{
+		Version:  CRDTVersion,
+		Clock:    db.clock,
+		Projects: db.projects,
+		States:   make(map[string]ipldLink),
+	}
+	for label, state := range db.states {
+		projectNode := crdtProjectNode{
+			crdtProject: *state,
+			States:      make(map[string]ipldLink),
+		}
+		for runLabel, runState := range state.states {
+			if len(runState.nodeCID) == 0 {
+				cid, err := db.putNode(ctx, node, runState)
+				if err != nil {
+					return "", err
+				}
+				runState.nodeCID = cid
+			}
+			projectNode.States[runLabel] = ipldLink{runState.nodeCID}
+		}
+		cid, err := db.putNode(ctx, node, projectNode)
+		if err != nil {
+			return "", err
+		}
+		root.States[label] = ipldLink{cid}
+	}
+	return db.putNode(ctx, node, root)
+}
+
+// putNode will store a value in the IPFS as an IPLD node
+func (db *CRDTDatabase) putNode(ctx context.Context, node *backend.Node, value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return node.DagPut(ctx, data, "json", "cbor", db.Pin)
+}
+
+// PullCRDT will load a replicated database from the IPFS, for editing by a replica
+func PullCRDT(ctx context.Context, node *backend.Node, cid, replica string) (*CRDTDatabase, error) {
+	root := crdtDatabaseNode{}
+	if err := node.DagGet(ctx, cid, "", &root); err != nil {
+		return nil, err
+	}
+	if root.Version < 1 || root.Version > CRDTVersion {
+		return nil, fmt.Errorf("unsupported CRDT database version: %d", root.Version)
+	}
+	db := NewCRDTDatabase(replica)
+	db.clock = root.Clock
+	db.projects = root.Projects
+	for label, link := range root.States {
+		projectNode := crdtProjectNode{}
+		if err := node.DagGet(ctx, link.CID, "", &projectNode); err != nil {
+			return nil, err
+		}
+		state := &projectNode.crdtProject
+		db.states[label] = state
+		for runLabel, runLink := range projectNode.States {
+			run := state.run(runLabel)
+			if err := node.DagGet(ctx, runLink.CID, "", run); err != nil {
+				return nil, err
+			}
+			run.nodeCID = runLink.CID
+		}
+	}
+	return db, nil
+}
+
+// pullState will load the replicated state of a database that has been pulled from the IPFS at a CID
+//
+// A database that was pushed without its state (by an older version of Scribe) is recorded in a new state
+// at its versionStamp, so that replicas pulling the same database hold the same state.
+func pullState(ctx context.Context, node *backend.Node, db *ProjectDatabase, cid, replica string) (*CRDTDatabase, error) {
+	var state *CRDTDatabase
+	if len(db.GetStateCID()) != 0 {
+		var err error
+		if state, err = PullCRDT(ctx, node, db.GetStateCID(), replica); err != nil {
+			return nil, err
+		}
+	} else {
+		state = NewCRDTDatabase(replica)
+		if err := state.record(ctx, node, InitDB(), db, versionStamp(db.GetVersion(), cid)); err != nil {
+			return nil, err
+		}
+	}
+	state.Pin = db.GetPin()
+	state.adopt(db)
+	return state, nil
+}
+
+// registerTime will format a timestamp for a register, returning an empty string if it isn't set
+func registerTime(ts *timestamp.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseRegisterTime will parse a timestamp from a register, returning nil if it isn't set
+func parseRegisterTime(value string) (*timestamp.Timestamp, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
+	}
+	return ptypes.TimestampProto(t)
+}
+
+// stampTime will convert a timestamp to a clock time, returning 0 if it isn't set
+func stampTime(ts *timestamp.Timestamp) clockTime {
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return 0
+	}
+	return clockTime(t.UnixNano())
+}
+
+// stringsEqual reports whether two lists of strings are the same
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package records
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestCRDT
+func TestCRDT(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+
+		// edit will record the changes made to the database built from a replica
+		edit := func(state *CRDTDatabase, fn func(db *ProjectDatabase)) {
+			db := InitDB()
+			if err := state.build(ctx, node, db); err != nil {
+				t.Fatal(err)
+			}
+			before := proto.Clone(db).(*ProjectDatabase)
+			fn(db)
+			if err := state.record(ctx, node, before, db, state.next()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		editRun := func(state *CRDTDatabase, fn func(run *Run)) {
+			edit(state, func(db *ProjectDatabase) {
+				run := &Run{}
+				if err := getRecord(ctx, node, db.Projects[projectLabel].Runs[runLabel], run); err != nil {
+					t.Fatal(err)
+				}
+				fn(run)
+				cid, err := putRecord(ctx, node, run, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				db.Projects[projectLabel].Runs[runLabel] = cid
+			})
+		}
+
+		// create a database with a run on one replica and share it
+		origin := NewCRDTDatabase("replica-a")
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.ParentProjectLabel = projectLabel
+		run.Tags["basecall"] = NewTagState()
+		runCID, err := putRecord(ctx, node, run, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edit(origin, func(db *ProjectDatabase) {
+			project := InitProject(projectLabel)
+			project.Id, project.Runs = "project ID", map[string]string{runLabel: runCID}
+			db.Projects[projectLabel] = project
+		})
+		built := InitDB()
+		if err := origin.build(ctx, node, built); err != nil {
+			t.Fatal(err)
+		}
+		if built.Projects[projectLabel].GetRuns()[runLabel] != runCID {
+			t.Fatal("run not built with the CID it was stored at")
+		}
+		originCID, err := origin.Push(ctx, node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replicas := make([]*CRDTDatabase, 3)
+		for i, replica := range []string{"replica-a", "replica-b", "replica-c"} {
+			if replicas[i], err = PullCRDT(ctx, node, originCID, replica); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// make concurrent changes on each replica
+		editRun(replicas[0], func(run *Run) {
+			run.AddComment("comment from a")
+			run.OutputDirectory = "output from a"
+		})
+		edit(replicas[0], func(db *ProjectDatabase) {
+			db.Projects[projectLabel].Id = "another ID"
+			db.Projects[projectLabel].Libraries = map[string]string{"library 1": "library from a"}
+		})
+		editRun(replicas[1], func(run *Run) {
+			run.AddComment("comment from b")
+			run.Tags["basecall"].Succeed("result")
+			run.OutputDirectory = "output from b"
+		})
+		edit(replicas[2], func(db *ProjectDatabase) {
+			delete(db.Projects[projectLabel].Runs, runLabel)
+			db.Projects["another project"] = InitProject("another project")
+		})
+		editRun(replicas[1], func(run *Run) {
+			run.FastqOutputDirectory = "fastqs from b"
+		})
+
+		// merge the replicas in different orders and check they converge
+		pull := func(db *CRDTDatabase) *CRDTDatabase {
+			cid, err := db.Push(ctx, node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pulled, err := PullCRDT(ctx, node, cid, "merger")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return pulled
+		}
+		orders := [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0, 1}}
+		heads := make([]string, len(orders))
+		for i, order := range orders {
+			merged := pull(replicas[order[0]])
+			for _, j := range order[1:] {
+				merged.Merge(pull(replicas[j]))
+			}
+			if heads[i], err = merged.Push(ctx, node); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if heads[0] != heads[1] || heads[0] != heads[2] {
+			t.Fatalf("replicas did not converge: %v", heads)
+		}
+
+		// check the merged state
+		merged, err := PullCRDT(ctx, node, heads[0], "merger")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if projects := merged.Projects(); len(projects) != 2 {
+			t.Fatalf("unexpected projects: %v", projects)
+		}
+		mergedRun, err := merged.Run(projectLabel, runLabel)
+		if err != nil {
+			t.Fatal("a remove should not win over concurrent updates")
+		}
+		if mergedRun.GetOutputDirectory() != "output from b" && mergedRun.GetOutputDirectory() != "output from a" {
+			t.Fatalf("unexpected output directory: %v", mergedRun.GetOutputDirectory())
+		}
+		if mergedRun.GetFastqOutputDirectory() != "fastqs from b" || mergedRun.GetFast5OutputDirectory() != fast5Dir {
+			t.Fatalf("registers not merged: %v", mergedRun)
+		}
+		if len(mergedRun.GetHistory()) != 3 || !mergedRun.GetTags()["basecall"].IsDone() {
+			t.Fatalf("history or tags not merged: %v", mergedRun)
+		}
+		if !proto.Equal(mergedRun.GetCreated(), run.GetCreated()) {
+			t.Fatal("created time not preserved")
+		}
+		mergedDB := InitDB()
+		if err := merged.build(ctx, node, mergedDB); err != nil {
+			t.Fatal(err)
+		}
+		project := mergedDB.Projects[projectLabel]
+		if project.GetId() != "project ID" || project.GetLibraries()["library 1"] != "library from a" {
+			t.Fatalf("project details not merged: %v", project)
+		}
+
+		// a remove that has seen every add should win
+		edit(merged, func(db *ProjectDatabase) {
+			delete(db.Projects[projectLabel].Runs, runLabel)
+		})
+		replicas[0].Merge(merged)
+		if _, err := replicas[0].Run(projectLabel, runLabel); err != ErrNotFound {
+			t.Fatalf("removed run is still present: %v", err)
+		}
+	})
+}
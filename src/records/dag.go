@@ -0,0 +1,199 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// the fields of the DAG nodes that link to child nodes, so that records can be resolved by path (projects/x/runs/y)
+const (
+	projectsField  = "projects"
+	runsField      = "runs"
+	librariesField = "libraries"
+	samplesField   = "samples"
+)
+
+// ipldLink is a link to another IPLD node
+type ipldLink struct {
+	CID string `json:"/"`
+}
+
+// putDatabase will store a database as a root DAG node, linking to a DAG node for each project
+//
+// Runs, libraries and samples are already stored as their own DAG nodes, so only the project nodes and the root are
+// stored; unchanged projects give the same node and CID as the previous version. The CID of
+// each project is updated to its node.
+func putDatabase(ctx context.Context, node *backend.Node, db *ProjectDatabase) (string, error) {
+	links := make(map[string]ipldLink, len(db.GetProjects()))
+	for label, project := range db.GetProjects() {
+		cid, err := putProjectNode(ctx, node, project, db.GetPin())
+		if err != nil {
+			return "", err
+		}
+		project.CID = cid
+		links[label] = ipldLink{cid}
+	}
+	root := *db
+	root.Projects = nil
+	return putNode(ctx, node, &root, map[string]map[string]ipldLink{projectsField: links}, db.GetPin())
+}
+
+// getDatabase will load a database from a root DAG node, fetching the linked project nodes
+//
+// Databases pushed before projects were linked hold their projects inline, and are also loaded.
+func getDatabase(ctx context.Context, node *backend.Node, cid string, db *ProjectDatabase) error {
+	var projects map[string]json.RawMessage
+	if err := getNode(ctx, node, cid, db, map[string]interface{}{projectsField: &projects}); err != nil {
+		return err
+	}
+	if len(projects) != 0 && db.Projects == nil {
+		db.Projects = make(map[string]*Project, len(projects))
+	}
+	for label, data := range projects {
+		project := &Project{}
+		link := ipldLink{}
+		if err := json.Unmarshal(data, &link); err == nil && len(link.CID) != 0 {
+			if err := getProjectNode(ctx, node, link.CID, project); err != nil {
+				return err
+			}
+		} else if err := unmarshalRecord(data, project); err != nil {
+			return err
+		}
+		db.Projects[label] = project
+	}
+	return nil
+}
+
+// putProjectNode will store a project as a DAG node, linking to the DAG node for each run, library and sample
+func putProjectNode(ctx context.Context, node *backend.Node, project *Project, pin bool) (string, error) {
+	links := make(map[string]map[string]ipldLink)
+	records := map[string]map[string]string{
+		runsField:      project.GetRuns(),
+		librariesField: project.GetLibraries(),
+		samplesField:   project.GetSamples(),
+	}
+	for field, cids := range records {
+		links[field] = make(map[string]ipldLink, len(cids))
+		for label, cid := range cids {
+			if len(cid) == 0 {
+				return "", fmt.Errorf("record has no CID (project: %v, %v: %v)", project.GetLabel(), field, label)
+			}
+			links[field][label] = ipldLink{cid}
+		}
+	}
+
+	// the CID can't be stored in the node it identifies
+	record := *project
+	record.CID, record.Runs, record.Libraries, record.Samples = "", nil, nil, nil
+	return putNode(ctx, node, &record, links, pin)
+}
+
+// getProjectNode will load a project from a DAG node, recording the CIDs of the linked runs, libraries and samples
+func getProjectNode(ctx context.Context, node *backend.Node, cid string, project *Project) error {
+	var runs, libraries, samples map[string]ipldLink
+	links := map[string]interface{}{
+		runsField:      &runs,
+		librariesField: &libraries,
+		samplesField:   &samples,
+	}
+	if err := getNode(ctx, node, cid, project, links); err != nil {
+		return err
+	}
+	project.CID = cid
+	project.Runs, project.Libraries, project.Samples = linkCIDs(runs), linkCIDs(libraries), linkCIDs(samples)
+	return nil
+}
+
+// linkCIDs will return the CIDs of a set of links, or nil if there are none
+func linkCIDs(links map[string]ipldLink) map[string]string {
+	if len(links) == 0 {
+		return nil
+	}
+	cids := make(map[string]string, len(links))
+	for label, link := range links {
+		cids[label] = link.CID
+	}
+	return cids
+}
+
+// putNode will store a record as a DAG node, with its child nodes linked under the given fields
+func putNode(ctx context.Context, node *backend.Node, record proto.Message, links map[string]map[string]ipldLink, pin bool) (string, error) {
+	data, err := marshalRecord(record)
+	if err != nil {
+		return "", err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	for field, fieldLinks := range links {
+		if len(fieldLinks) == 0 {
+			continue
+		}
+		if fields[field], err = json.Marshal(fieldLinks); err != nil {
+			return "", err
+		}
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return "", err
+	}
+	return node.DagPut(ctx, data, "json", "cbor", pin)
+}
+
+// getNode will load a record from a DAG node, unmarshalling the links under each of the given fields into its output
+func getNode(ctx context.Context, node *backend.Node, cid string, record proto.Message, links map[string]interface{}) error {
+	fields := make(map[string]json.RawMessage)
+	if err := node.DagGet(ctx, cid, "", &fields); err != nil {
+		return err
+	}
+	for field, output := range links {
+		data, ok := fields[field]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(data, output); err != nil {
+			return err
+		}
+		delete(fields, field)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return unmarshalRecord(data, record)
+}
+
+// marshalRecord will marshal a record as json
+func marshalRecord(record proto.Message) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	jsonMarshaller := jsonpb.Marshaler{
+		EnumsAsInts:  false, // Whether to render enum values as integers, as opposed to string values.
+		EmitDefaults: false, // Whether to render fields with zero values
+		Indent:       "\t",  // A string to indent each level by
+		OrigName:     false, // Whether to use the original (.proto) name for fields
+	}
+	if err := jsonMarshaller.Marshal(buf, record); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalRecord will unmarshal a record from json, ignoring fields from newer versions of Scribe and migrating those from older ones
+func unmarshalRecord(data []byte, record proto.Message) error {
+	data, err := migrateTags(data)
+	if err != nil {
+		return err
+	}
+	jsonUnmarshaller := jsonpb.Unmarshaler{
+		AllowUnknownFields: true,
+	}
+	return jsonUnmarshaller.Unmarshal(bytes.NewReader(data), record)
+}
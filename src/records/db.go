@@ -2,11 +2,13 @@
 package records
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
-	"github.com/gogo/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/will-rowe/scribe/src/backend"
 )
 
@@ -35,44 +37,56 @@ func (db *ProjectDatabase) GetNumProjects() int {
 }
 
 // Pull will pull a database from the IPFS using the provided CID
-func (db *ProjectDatabase) Pull(node *backend.Node, cid string) error {
+//
+// If the database is not empty, the pulled database is merged into it (see Merge). As there is
+// no common base to merge from, any differences that can't be combined are returned as conflicts.
+func (db *ProjectDatabase) Pull(ctx context.Context, node *backend.Node, cid string) error {
 	if len(cid) < 1 {
 		return fmt.Errorf("no CID provided")
 	}
 
-	// prevent over write of db
-	if len(db.Projects) != 0 {
-		return fmt.Errorf("project database is not empty, Pull method does not currently support merges")
-	}
-
 	// get the DAG and load into the struct
-	return node.DagGet(cid, "", db)
+	if len(db.Projects) == 0 {
+		return getDatabase(ctx, node, cid, db)
+	}
 
+	// merge into the existing db
+	remote := InitDB()
+	if err := getDatabase(ctx, node, cid, remote); err != nil {
+		return err
+	}
+	return db.Merge(ctx, node, nil, remote)
 }
 
-// Push will push the database to the IPFS and return the CID (and any error)
-func (db *ProjectDatabase) Push(node *backend.Node) (string, error) {
-
-	// marshal the db as json
-	buf := &bytes.Buffer{}
-	jsonMarshaller := jsonpb.Marshaler{
-		EnumsAsInts:  false, // Whether to render enum values as integers, as opposed to string values.
-		EmitDefaults: false, // Whether to render fields with zero values
-		Indent:       "\t",  // A string to indent each level by
-		OrigName:     false, // Whether to use the original (.proto) name for fields
-	}
-	if err := jsonMarshaller.Marshal(buf, db); err != nil {
+// Push will push the database to the IPFS as a new version and return the CID (and any error)
+//
+// The new version links to the parent version (if there is one), and records the node's
+// peer ID, the time and a summary of the change. The changes from the parent version are
+// recorded in the replicated state of the database (see Replica) and the database is rebuilt
+// from it, so comments removed from a run's history or tags moved back to an earlier state are
+// restored.
+func (db *ProjectDatabase) Push(ctx context.Context, node *backend.Node, parentCID, summary string) (string, error) {
+	replica, err := NewReplica(ctx, node, parentCID)
+	if err != nil {
 		return "", err
 	}
+	replica.DB, replica.changes = db, []string{summary}
+	return replica.Push(ctx)
+}
 
-	// add to IPFS
-	cid, err := node.DagPut(buf.Bytes(), "json", "cbor", db.Pin)
-	if err != nil {
+// pushVersion will set the author and time of a version, then push the database
+func (db *ProjectDatabase) pushVersion(ctx context.Context, node *backend.Node, version *DatabaseVersion) (string, error) {
+	// the author is unknown if the node is offline and has no IPFS repo
+	self, err := node.Identity(ctx)
+	switch {
+	case err == nil:
+		version.Author = self.ID
+	case !errors.Is(err, backend.ErrOffline):
 		return "", err
 	}
-
-	// return the CID
-	return cid, nil
+	version.Timestamp = ptypes.TimestampNow()
+	db.Version = version
+	return putDatabase(ctx, node, db)
 }
 
 // AddProject will add a project to the db
@@ -95,3 +109,25 @@ func (db *ProjectDatabase) GetProject(projectLabel string) (*Project, error) {
 	}
 	return nil, ErrNotFound
 }
+
+// putRecord will store a record in the IPFS as a DAG node and return the CID (and any error)
+func putRecord(ctx context.Context, node *backend.Node, record proto.Message, pin bool) (string, error) {
+
+	// marshal the record as json
+	data, err := marshalRecord(record)
+	if err != nil {
+		return "", err
+	}
+
+	// add to IPFS
+	return node.DagPut(ctx, data, "json", "cbor", pin)
+}
+
+// getRecord will load a record from a DAG node in the IPFS
+func getRecord(ctx context.Context, node *backend.Node, cid string, record proto.Message) error {
+	var data json.RawMessage
+	if err := node.DagGet(ctx, cid, "", &data); err != nil {
+		return err
+	}
+	return unmarshalRecord(data, record)
+}
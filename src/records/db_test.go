@@ -1,6 +1,7 @@
 package records
 
 import (
+	"context"
 	"testing"
 
 	"github.com/will-rowe/scribe/src/backend"
@@ -8,15 +9,11 @@ import (
 
 // TestDAG
 func TestDAG(t *testing.T) {
+	ctx := context.Background()
 
-	// launch IPFS and then run the test
-	backend.WithIpfs(t, 1, func(t *testing.T, APIaddress string) {
-
-		// set up the node
-		node, err := backend.InitNode(APIaddress)
-		if err != nil {
-			t.Fatal(err)
-		}
+	// use an in-memory node and then run the test
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
 
 		// init the db
 		db := InitDB()
@@ -28,7 +25,7 @@ func TestDAG(t *testing.T) {
 		}
 
 		// push the db to the IPFS
-		cid, err := db.Push(node)
+		cid, err := db.Push(ctx, node, "", "added test project")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -38,7 +35,7 @@ func TestDAG(t *testing.T) {
 
 		// pull the db from the IPFS
 		db2 := InitDB()
-		if err := db2.Pull(node, cid); err != nil {
+		if err := db2.Pull(ctx, node, cid); err != nil {
 			t.Fatal(err)
 		}
 
@@ -50,5 +47,51 @@ func TestDAG(t *testing.T) {
 		if retreivedProject.GetLabel() != testProject.GetLabel() {
 			t.Fatalf("mismatch between retrieved label and original: %s vs %s", retreivedProject.GetLabel(), testProject.GetLabel())
 		}
+		if retreivedProject.GetCID() != testProject.GetCID() || len(retreivedProject.GetCID()) == 0 {
+			t.Fatalf("project CID not recorded: %v", retreivedProject.GetCID())
+		}
+
+		// add a project with a run and check the run can be fetched by path
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		runCID, err := putRecord(ctx, node, run, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runProject := InitProject(projectLabel)
+		runProject.Runs = map[string]string{runLabel: runCID}
+		if err := db2.AddProject(runProject); err != nil {
+			t.Fatal(err)
+		}
+		cid2, err := db2.Push(ctx, node, cid, "added run project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var label string
+		if err := node.DagGet(ctx, cid2, "projects/"+projectLabel+"/runs/"+runLabel+"/label", &label); err != nil || label != runLabel {
+			t.Fatalf("could not get run by path: %v (%v)", label, err)
+		}
+
+		// unchanged projects should be shared between versions
+		if db2.Projects["test project 1"].GetCID() != testProject.GetCID() {
+			t.Fatal("unchanged project was not shared between versions")
+		}
+
+		// databases stored before projects were linked should still load
+		legacy := InitDB()
+		if err := legacy.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		legacy.Projects[projectLabel].Runs = map[string]string{runLabel: runCID}
+		legacyCID, err := putRecord(ctx, node, legacy, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db3 := InitDB()
+		if err := db3.Pull(ctx, node, legacyCID); err != nil {
+			t.Fatal(err)
+		}
+		if project, err := db3.GetProject(projectLabel); err != nil || project.GetRuns()[runLabel] != runCID {
+			t.Fatalf("legacy database not loaded: %v (%v)", project, err)
+		}
 	})
 }
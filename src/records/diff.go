@@ -0,0 +1,282 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// DatabaseDiff is the structural difference between two versions of the project database
+type DatabaseDiff struct {
+	From            string        `json:"from"`                      // the CID of the old version
+	To              string        `json:"to"`                        // the CID of the new version
+	ProjectsAdded   []string      `json:"projectsAdded,omitempty"`   // labels of the projects only in the new version
+	ProjectsRemoved []string      `json:"projectsRemoved,omitempty"` // labels of the projects only in the old version
+	Projects        []ProjectDiff `json:"projects,omitempty"`        // the projects that have changed
+}
+
+// ProjectDiff is the difference between two versions of a project
+type ProjectDiff struct {
+	Project     string        `json:"project"`
+	Fields      []FieldChange `json:"fields,omitempty"`
+	RunsAdded   []string      `json:"runsAdded,omitempty"`
+	RunsRemoved []string      `json:"runsRemoved,omitempty"`
+	Runs        []RunDiff     `json:"runs,omitempty"`
+
+	LibrariesAdded   []string      `json:"librariesAdded,omitempty"`
+	LibrariesRemoved []string      `json:"librariesRemoved,omitempty"`
+	Libraries        []FieldChange `json:"libraries,omitempty"` // the library is the field, with the old and new CIDs
+
+	SamplesAdded   []string      `json:"samplesAdded,omitempty"`
+	SamplesRemoved []string      `json:"samplesRemoved,omitempty"`
+	Samples        []FieldChange `json:"samples,omitempty"` // the sample is the field, with the old and new CIDs
+}
+
+// RunDiff is the difference between two versions of a run
+type RunDiff struct {
+	Run         string        `json:"run"`
+	Fields      []FieldChange `json:"fields,omitempty"`
+	NewComments []CommentDiff `json:"newComments,omitempty"`
+	Tags        []FieldChange `json:"tags,omitempty"` // the tag is the field, with the tag state or "" for untagged
+}
+
+// FieldChange is a field with a different value in the new version
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// CommentDiff is a comment only in the new version of a run history
+type CommentDiff struct {
+	Timestamp string `json:"timestamp"`
+	Text      string `json:"text"`
+}
+
+// IsEmpty reports whether the versions are the same
+func (diff *DatabaseDiff) IsEmpty() bool {
+	return len(diff.ProjectsAdded) == 0 && len(diff.ProjectsRemoved) == 0 && len(diff.Projects) == 0
+}
+
+// Diff will compare two versions of the project database
+func Diff(ctx context.Context, node *backend.Node, fromCID, toCID string) (*DatabaseDiff, error) {
+	from, to := InitDB(), InitDB()
+	if err := getDatabase(ctx, node, fromCID, from); err != nil {
+		return nil, err
+	}
+	if err := getDatabase(ctx, node, toCID, to); err != nil {
+		return nil, err
+	}
+	diff := &DatabaseDiff{From: fromCID, To: toCID}
+	diff.ProjectsAdded, diff.ProjectsRemoved = diffKeys(projectCIDs(from), projectCIDs(to))
+	for _, label := range sortedKeys(projectCIDs(to)) {
+		old, ok := from.GetProjects()[label]
+		if !ok {
+			continue
+		}
+		projectDiff, err := diffProject(ctx, node, old, to.GetProjects()[label])
+		if err != nil {
+			return nil, err
+		}
+		if projectDiff != nil {
+			diff.Projects = append(diff.Projects, *projectDiff)
+		}
+	}
+	return diff, nil
+}
+
+// diffProject will compare two versions of a project, returning nil if they are the same
+func diffProject(ctx context.Context, node *backend.Node, old, new *Project) (*ProjectDiff, error) {
+	// projects stored as the same DAG node are the same
+	if len(old.GetCID()) != 0 && old.GetCID() == new.GetCID() {
+		return nil, nil
+	}
+	diff := &ProjectDiff{Project: new.GetLabel()}
+	diff.Fields = diffFields([][3]string{
+		{"CID", old.GetCID(), new.GetCID()},
+		{"id", old.GetId(), new.GetId()},
+		{"description", old.GetDescription(), new.GetDescription()},
+		{"owners", strings.Join(old.GetOwners(), ","), strings.Join(new.GetOwners(), ",")},
+		{"created", formatTime(old.GetCreated()), formatTime(new.GetCreated())},
+		{"license", old.GetLicense(), new.GetLicense()},
+	})
+	diff.LibrariesAdded, diff.LibrariesRemoved = diffKeys(old.GetLibraries(), new.GetLibraries())
+	diff.Libraries = diffCIDs(old.GetLibraries(), new.GetLibraries())
+	diff.SamplesAdded, diff.SamplesRemoved = diffKeys(old.GetSamples(), new.GetSamples())
+	diff.Samples = diffCIDs(old.GetSamples(), new.GetSamples())
+	diff.RunsAdded, diff.RunsRemoved = diffKeys(old.GetRuns(), new.GetRuns())
+	for _, label := range sortedKeys(new.GetRuns()) {
+		oldCID, ok := old.GetRuns()[label]
+		if !ok || oldCID == new.GetRuns()[label] {
+			continue
+		}
+		oldRun, newRun := &Run{}, &Run{}
+		if err := getRecord(ctx, node, oldCID, oldRun); err != nil {
+			return nil, err
+		}
+		if err := getRecord(ctx, node, new.GetRuns()[label], newRun); err != nil {
+			return nil, err
+		}
+		if runDiff := diffRun(label, oldRun, newRun); runDiff != nil {
+			diff.Runs = append(diff.Runs, *runDiff)
+		}
+	}
+	if len(diff.Fields) == 0 && len(diff.RunsAdded) == 0 && len(diff.RunsRemoved) == 0 && len(diff.Runs) == 0 &&
+		len(diff.LibrariesAdded) == 0 && len(diff.LibrariesRemoved) == 0 && len(diff.Libraries) == 0 &&
+		len(diff.SamplesAdded) == 0 && len(diff.SamplesRemoved) == 0 && len(diff.Samples) == 0 {
+		return nil, nil
+	}
+	return diff, nil
+}
+
+// diffRun will compare two versions of a run, returning nil if they are the same
+func diffRun(label string, old, new *Run) *RunDiff {
+	diff := &RunDiff{Run: label}
+	diff.Fields = diffFields([][3]string{
+		{"created", formatTime(old.GetCreated()), formatTime(new.GetCreated())},
+		{"parentProjectLabel", old.GetParentProjectLabel(), new.GetParentProjectLabel()},
+		{"library", old.GetLibrary(), new.GetLibrary()},
+		{"samples", strings.Join(old.GetSamples(), ","), strings.Join(new.GetSamples(), ",")},
+		{"parentProjectCID", old.GetParentProjectCID(), new.GetParentProjectCID()},
+		{"status", old.GetStatus().String(), new.GetStatus().String()},
+		{"requestOrder", strings.Join(old.GetRequestOrder(), ","), strings.Join(new.GetRequestOrder(), ",")},
+		{"outputDirectory", old.GetOutputDirectory(), new.GetOutputDirectory()},
+		{"fast5OutputDirectory", old.GetFast5OutputDirectory(), new.GetFast5OutputDirectory()},
+		{"fastqOutputDirectory", old.GetFastqOutputDirectory(), new.GetFastqOutputDirectory()},
+	})
+
+	// comments are compared by content, as the history is only added to
+	oldComments := make(map[string]bool)
+	for _, comment := range old.GetHistory() {
+		oldComments[comment.String()] = true
+	}
+	for _, comment := range new.GetHistory() {
+		if !oldComments[comment.String()] {
+			diff.NewComments = append(diff.NewComments, CommentDiff{
+				Timestamp: formatTime(comment.GetTimestamp()),
+				Text:      comment.GetText(),
+			})
+		}
+	}
+
+	// tags are shown by their state, or "" (untagged)
+	tags := keySet(old.GetTags())
+	for tag := range new.GetTags() {
+		tags[tag] = true
+	}
+	tagChanges := [][3]string{}
+	for _, tag := range sortedSet(tags) {
+		tagChanges = append(tagChanges, [3]string{tag, tagStatus(old.GetTags(), tag), tagStatus(new.GetTags(), tag)})
+	}
+	diff.Tags = diffFields(tagChanges)
+	if len(diff.Fields) == 0 && len(diff.NewComments) == 0 && len(diff.Tags) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// diffFields returns the changes for a list of field, old value, new value triples
+func diffFields(fields [][3]string) []FieldChange {
+	var changes []FieldChange
+	for _, field := range fields {
+		if field[1] != field[2] {
+			changes = append(changes, FieldChange{Field: field[0], Old: field[1], New: field[2]})
+		}
+	}
+	return changes
+}
+
+// diffCIDs returns the changes to the CIDs of the records in both maps, using the labels as the fields
+func diffCIDs(old, new map[string]string) []FieldChange {
+	changes := [][3]string{}
+	for _, label := range sortedKeys(new) {
+		if oldCID, ok := old[label]; ok {
+			changes = append(changes, [3]string{label, oldCID, new[label]})
+		}
+	}
+	return diffFields(changes)
+}
+
+// diffKeys returns the sorted keys that have been added to and removed from a map
+func diffKeys(old, new interface{}) (added, removed []string) {
+	oldKeys, newKeys := keySet(old), keySet(new)
+	for _, key := range sortedSet(newKeys) {
+		if !oldKeys[key] {
+			added = append(added, key)
+		}
+	}
+	for _, key := range sortedSet(oldKeys) {
+		if !newKeys[key] {
+			removed = append(removed, key)
+		}
+	}
+	return added, removed
+}
+
+// keySet returns the keys of a map of record CIDs or tags
+func keySet(labels interface{}) map[string]bool {
+	keys := make(map[string]bool)
+	switch labels := labels.(type) {
+	case map[string]string:
+		for key := range labels {
+			keys[key] = true
+		}
+	case map[string]*TagState:
+		for key := range labels {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// sortedKeys returns the sorted keys of a map of record CIDs or tags
+func sortedKeys(labels interface{}) []string {
+	return sortedSet(keySet(labels))
+}
+
+// sortedSet returns the sorted labels in a set
+func sortedSet(labels map[string]bool) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// projectCIDs returns the CIDs of the projects in a database, by label
+func projectCIDs(db *ProjectDatabase) map[string]string {
+	cids := make(map[string]string, len(db.GetProjects()))
+	for label, project := range db.GetProjects() {
+		cids[label] = project.GetCID()
+	}
+	return cids
+}
+
+// tagStatus describes the state of a tag
+func tagStatus(tags map[string]*TagState, tag string) string {
+	state, ok := tags[tag]
+	if !ok {
+		return ""
+	}
+	return state.Describe()
+}
+
+// formatTime will format a timestamp, or return "" if it is not set
+func formatTime(value *timestamp.Timestamp) string {
+	if value == nil {
+		return ""
+	}
+	t, err := ptypes.Timestamp(value)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,88 @@
+package records
+
+import (
+	"context"
+	"testing"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestDiff
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+
+		// push a database with a run
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.Tags["basecall"] = NewTagState()
+		runCID, err := putRecord(ctx, node, run, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := InitDB()
+		project := InitProject(projectLabel)
+		project.Runs = map[string]string{runLabel: runCID}
+		if err := db.AddProject(project); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.AddProject(InitProject("removed project")); err != nil {
+			t.Fatal(err)
+		}
+		from, err := db.Push(ctx, node, "", "added projects")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// change the run and the projects, then push again
+		run.AddComment("basecalling finished")
+		run.Tags["basecall"] = &TagState{State: TagState_succeeded}
+		run.Tags["demux"] = NewTagState()
+		run.OutputDirectory = "new output"
+		if project.Runs[runLabel], err = putRecord(ctx, node, run, true); err != nil {
+			t.Fatal(err)
+		}
+		project.Runs["new run"] = runCID
+		delete(db.Projects, "removed project")
+		if err := db.AddProject(InitProject("added project")); err != nil {
+			t.Fatal(err)
+		}
+		to, err := db.Push(ctx, node, from, "updated run")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// resolve the references and diff the versions
+		if ref, err := ResolveRef(ctx, node, to, "HEAD~1"); err != nil || ref != from {
+			t.Fatalf("could not resolve HEAD~1: %v (%v)", ref, err)
+		}
+		if _, err := ResolveRef(ctx, node, to, "HEAD~2"); err == nil {
+			t.Fatal("resolved a reference beyond the start of the history")
+		}
+		diff, err := Diff(ctx, node, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diff.ProjectsAdded) != 1 || diff.ProjectsAdded[0] != "added project" || len(diff.ProjectsRemoved) != 1 || diff.ProjectsRemoved[0] != "removed project" {
+			t.Fatalf("unexpected project changes: %+v", diff)
+		}
+		if len(diff.Projects) != 1 || len(diff.Projects[0].RunsAdded) != 1 || len(diff.Projects[0].Runs) != 1 {
+			t.Fatalf("unexpected run changes: %+v", diff.Projects)
+		}
+		runDiff := diff.Projects[0].Runs[0]
+		if len(runDiff.Fields) != 1 || runDiff.Fields[0].Field != "outputDirectory" || runDiff.Fields[0].New != "new output" {
+			t.Fatalf("unexpected field changes: %+v", runDiff.Fields)
+		}
+		if len(runDiff.NewComments) != 1 || runDiff.NewComments[0].Text != "basecalling finished" {
+			t.Fatalf("unexpected comments: %+v", runDiff.NewComments)
+		}
+		if len(runDiff.Tags) != 2 || runDiff.Tags[0].New != "succeeded" || runDiff.Tags[1].Old != "" {
+			t.Fatalf("unexpected tag changes: %+v", runDiff.Tags)
+		}
+
+		// a version has no difference to itself
+		if diff, err := Diff(ctx, node, to, to); err != nil || !diff.IsEmpty() {
+			t.Fatalf("version differs from itself: %+v (%v)", diff, err)
+		}
+	})
+}
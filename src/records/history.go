@@ -0,0 +1,138 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// headRef is the reference to the head of the database history
+const headRef = "HEAD"
+
+var (
+	// ErrNotInHistory is issued when a version is not in the history of the database
+	ErrNotInHistory = errors.New("version is not in the database history")
+
+	// ErrRevertHead is issued when reverting to the current head of the database
+	ErrRevertHead = errors.New("can't revert to the current head")
+)
+
+// VersionEntry is a version of the project database in the database history
+type VersionEntry struct {
+	CID     string           // the IPFS content identifier for this version of the database
+	Version *DatabaseVersion // describes the change (nil for databases pushed before versions were recorded)
+}
+
+// History will walk the database history back from the head CID, following the parent of each version
+//
+// At most limit entries are returned (or the whole history if limit is 0). The history stops at
+// the first version with no parent, or at a database pushed before versions were recorded.
+func History(ctx context.Context, node *backend.Node, head string, limit int) ([]VersionEntry, error) {
+	entries := []VersionEntry{}
+	seen := make(map[string]bool)
+	for cid := head; len(cid) != 0 && !seen[cid]; {
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+		seen[cid] = true
+		version, err := getVersion(ctx, node, cid)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, VersionEntry{CID: cid, Version: version})
+		cid = version.GetParentCID()
+	}
+	return entries, nil
+}
+
+// getVersion will load the version of a database from the IPFS, without loading the projects
+func getVersion(ctx context.Context, node *backend.Node, cid string) (*DatabaseVersion, error) {
+	var data json.RawMessage
+	if err := node.DagGet(ctx, cid, "version", &data); err != nil {
+		if errors.Is(err, backend.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	version := &DatabaseVersion{}
+	jsonUnmarshaller := jsonpb.Unmarshaler{
+		AllowUnknownFields: true,
+	}
+	if err := jsonUnmarshaller.Unmarshal(bytes.NewReader(data), version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// ResolveRef will resolve a reference to a version of the database into a CID
+//
+// The reference can be a CID, HEAD for the head CID, or HEAD~N for the Nth parent of the head.
+func ResolveRef(ctx context.Context, node *backend.Node, head, ref string) (string, error) {
+	if !strings.HasPrefix(ref, headRef) {
+		return ref, nil
+	}
+	if len(head) == 0 {
+		return "", fmt.Errorf("can't resolve %v without a database CID", ref)
+	}
+	generations := 0
+	if ref != headRef {
+		var err error
+		if !strings.HasPrefix(ref, headRef+"~") {
+			return "", fmt.Errorf("invalid reference: %v", ref)
+		}
+		if generations, err = strconv.Atoi(strings.TrimPrefix(ref, headRef+"~")); err != nil || generations < 0 {
+			return "", fmt.Errorf("invalid reference: %v", ref)
+		}
+	}
+	history, err := History(ctx, node, head, generations+1)
+	if err != nil {
+		return "", err
+	}
+	if len(history) <= generations {
+		return "", fmt.Errorf("%v is beyond the start of the history (%d versions)", ref, len(history))
+	}
+	return history[generations].CID, nil
+}
+
+// Revert will restore the database to an earlier version from the history of the head
+//
+// History is not rewritten; the earlier database is pushed as a new version on top of the head,
+// and the new version is returned along with its CID. Runs keep the comments and tag progress
+// recorded since the earlier version, as the replicated state can't remove them.
+func Revert(ctx context.Context, node *backend.Node, head, target string) (*ProjectDatabase, string, error) {
+	if target == head {
+		return nil, "", ErrRevertHead
+	}
+	history, err := History(ctx, node, head, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	found := false
+	for _, entry := range history {
+		if entry.CID == target {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, "", fmt.Errorf("%w: %v", ErrNotInHistory, target)
+	}
+	db := InitDB()
+	if err := db.Pull(ctx, node, target); err != nil {
+		return nil, "", err
+	}
+	cid, err := db.Push(ctx, node, head, fmt.Sprintf("reverted to %v", target))
+	if err != nil {
+		return nil, "", err
+	}
+	return db, cid, nil
+}
@@ -0,0 +1,85 @@
+package records
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestHistory
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+		self, err := node.Identity(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// start from a database pushed before versions were recorded
+		db := InitDB()
+		legacy, err := putRecord(ctx, node, db, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// push a chain of changes
+		head := legacy
+		summaries := []string{"added project 1", "added project 2", "added project 3"}
+		for i, summary := range summaries {
+			if err := db.AddProject(InitProject(summary)); err != nil {
+				t.Fatal(err)
+			}
+			if head, err = db.Push(ctx, node, head, summaries[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// walk the chain back from the head
+		history, err := History(ctx, node, head, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 4 || history[0].CID != head || history[3].CID != legacy || history[3].Version != nil {
+			t.Fatalf("unexpected history: %v", history)
+		}
+		for i, entry := range history[:3] {
+			if entry.Version.GetSummary() != summaries[2-i] || entry.Version.GetAuthor() != self.ID || entry.Version.GetTimestamp() == nil {
+				t.Fatalf("unexpected version: %v", entry.Version)
+			}
+		}
+
+		// check the limit
+		if history, err := History(ctx, node, head, 2); err != nil || len(history) != 2 {
+			t.Fatalf("history not limited (%v)", err)
+		}
+
+		// revert to the first change and check it is a new version on top of the head
+		target, err := ResolveRef(ctx, node, head, "HEAD~2")
+		if err != nil || target != history[2].CID {
+			t.Fatalf("could not resolve HEAD~2: %v (%v)", target, err)
+		}
+		reverted, revertCID, err := Revert(ctx, node, head, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reverted.GetNumProjects() != 1 {
+			t.Fatalf("database not reverted: %v", reverted)
+		}
+		history, err = History(ctx, node, revertCID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 5 || history[1].CID != head || history[0].Version.GetSummary() != "reverted to "+target {
+			t.Fatalf("revert rewrote the history: %v", history)
+		}
+		if _, _, err := Revert(ctx, node, revertCID, revertCID); !errors.Is(err, ErrRevertHead) {
+			t.Fatalf("reverted to the head (%v)", err)
+		}
+		if _, _, err := Revert(ctx, node, legacy, head); !errors.Is(err, ErrNotInHistory) {
+			t.Fatalf("reverted to a version outside the history (%v)", err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// BarcodeKits are the barcoding kits that a library can be prepared with, and the number of barcodes in each
+var BarcodeKits = map[string]int32{
+	"EXP-NBD103":    12,
+	"EXP-NBD104":    12,
+	"EXP-NBD114":    12,
+	"EXP-NBD196":    96,
+	"EXP-PBC001":    12,
+	"EXP-PBC096":    96,
+	"SQK-16S024":    24,
+	"SQK-LWB001":    12,
+	"SQK-PBK004":    12,
+	"SQK-PCB109":    12,
+	"SQK-RAB204":    12,
+	"SQK-RBK004":    12,
+	"SQK-RBK110.96": 96,
+	"SQK-RPB004":    12,
+}
+
+var (
+	// ErrOrphanLibrary is issued when a library without a parent project is synced
+	ErrOrphanLibrary = errors.New("orphan library can't be synced - needs a parent project")
+
+	// ErrUnknownBarcodeKit is issued when a library is prepared with a barcoding kit that isn't in BarcodeKits
+	ErrUnknownBarcodeKit = errors.New("unknown barcode kit")
+)
+
+// InitLibrary will init a library struct with the minimum required values
+func InitLibrary(label, prepKit, barcodeKit, flowcellType string) *Library {
+
+	// create the library
+	library := &Library{
+		Created:      ptypes.TimestampNow(),
+		Label:        label,
+		History:      []*Comment{},
+		Status:       Status_untagged,
+		PrepKit:      prepKit,
+		BarcodeKit:   barcodeKit,
+		FlowcellType: flowcellType,
+		Samples:      []string{},
+	}
+
+	// create the history
+	library.AddComment("library created.")
+
+	// return pointer to the library
+	return library
+}
+
+// AddComment adds a comment to the library history
+func (library *Library) AddComment(text string) error {
+	if len(text) == 0 {
+		return fmt.Errorf("no comment provided")
+	}
+	comment := &Comment{
+		Timestamp: ptypes.TimestampNow(),
+		Text:      text,
+	}
+	library.History = append(library.History, comment)
+	return nil
+}
+
+// Validate will check the library has the required fields, a known barcoding kit and no repeated samples
+func (library *Library) Validate() error {
+	switch {
+	case len(library.GetLabel()) == 0:
+		return fmt.Errorf("library has no label")
+	case len(library.GetPrepKit()) == 0:
+		return fmt.Errorf("library has no prep kit (%v)", library.GetLabel())
+	case len(library.GetFlowcellType()) == 0:
+		return fmt.Errorf("library has no flowcell type (%v)", library.GetLabel())
+	case library.GetInputDNA() < 0:
+		return fmt.Errorf("library input DNA can't be negative (%v)", library.GetLabel())
+	}
+	if len(library.GetBarcodeKit()) != 0 {
+		if _, ok := BarcodeKits[library.GetBarcodeKit()]; !ok {
+			return fmt.Errorf("%w: %v", ErrUnknownBarcodeKit, library.GetBarcodeKit())
+		}
+	}
+	samples := make(map[string]bool)
+	for _, sample := range library.GetSamples() {
+		if samples[sample] {
+			return fmt.Errorf("sample is in the library more than once (%v)", sample)
+		}
+		samples[sample] = true
+	}
+	return nil
+}
+
+// Sync will validate a library, store it in the IPFS, register it on its parent project and push the database as a new version
+//
+// The parent project, and the library's samples, must be in the database. The barcodes of the samples
+// it lists and the samples of the runs linked to it must be unique and, if the library has a barcode
+// kit, within the range of the kit. The CID of the new database version is returned,
+// or the parent CID if the library is unchanged, and the change is announced to the parent project
+// as a LibraryUpdated message.
+func (library *Library) Sync(ctx context.Context, node *backend.Node, db *ProjectDatabase, parentCID string) (string, error) {
+
+	// check the library and its parent project
+	if len(library.GetParentProjectLabel()) == 0 {
+		return "", ErrOrphanLibrary
+	}
+	if err := library.Validate(); err != nil {
+		return "", err
+	}
+	project, err := db.GetProject(library.GetParentProjectLabel())
+	if err != nil {
+		return "", fmt.Errorf("parent project not found for library %v: %w", library.GetLabel(), err)
+	}
+	samples, err := librarySamples(ctx, node, project, library)
+	if err != nil {
+		return "", err
+	}
+	barcodes := make(map[int32]string)
+	for _, label := range samples {
+		sampleCID, ok := project.GetSamples()[label]
+		if !ok {
+			return "", fmt.Errorf("sample not found in %v for library %v: %v", project.GetLabel(), library.GetLabel(), label)
+		}
+		sample := &Sample{}
+		if err := getRecord(ctx, node, sampleCID, sample); err != nil {
+			return "", err
+		}
+		if err := checkBarcodeKit(library, sample); err != nil {
+			return "", err
+		}
+		if other, ok := barcodes[sample.GetBarcode()]; ok {
+			return "", fmt.Errorf("%w: %d (samples %v and %v)", ErrBarcodeInUse, sample.GetBarcode(), other, label)
+		}
+		barcodes[sample.GetBarcode()] = label
+	}
+
+	// store the library and record it on the project
+	previousCID := project.GetLibraries()[library.GetLabel()]
+	cid, err := putRecord(ctx, node, library, db.GetPin())
+	if err != nil {
+		return "", err
+	}
+	if cid == previousCID {
+		return parentCID, nil
+	}
+	if project.Libraries == nil {
+		project.Libraries = make(map[string]string)
+	}
+	project.Libraries[library.GetLabel()] = cid
+	msg := NewMessage(project.GetLabel())
+	msg.Payload = &Message_LibraryUpdated{LibraryUpdated: &LibraryUpdated{Label: library.GetLabel(), CID: cid, PreviousCID: previousCID}}
+
+	// push the database, then announce the change
+	dbCID, err := db.Push(ctx, node, parentCID, msg.Describe())
+	if err != nil {
+		return "", err
+	}
+	if err := Announce(ctx, node, msg); err != nil && !errors.Is(err, backend.ErrOffline) {
+		return dbCID, fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+	}
+	return dbCID, nil
+}
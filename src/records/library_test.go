@@ -0,0 +1,110 @@
+package records
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+var (
+	libraryLabel = "test library"
+	prepKit      = "SQK-LSK109"
+	barcodeKit   = "EXP-NBD104"
+	flowcellType = "FLO-MIN106"
+)
+
+// TestLibrary
+func TestLibrary(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+
+		// check the validation
+		library := InitLibrary(libraryLabel, prepKit, barcodeKit, flowcellType)
+		library.InputDNA = 1000.5
+		library.Operator = "test operator"
+		library.Samples = []string{"sample 1", "sample 2"}
+		if err := library.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := InitLibrary(libraryLabel, prepKit, "not a kit", flowcellType).Validate(); !errors.Is(err, ErrUnknownBarcodeKit) {
+			t.Fatalf("unknown barcode kit was accepted (%v)", err)
+		}
+		if err := InitLibrary(libraryLabel, "", barcodeKit, flowcellType).Validate(); err == nil {
+			t.Fatal("library without a prep kit was accepted")
+		}
+		repeated := InitLibrary(libraryLabel, prepKit, barcodeKit, flowcellType)
+		repeated.Samples = []string{"sample 1", "sample 1"}
+		if err := repeated.Validate(); err == nil {
+			t.Fatal("repeated sample was accepted")
+		}
+
+		// make sure you can't sync an orphan library
+		db := InitDB()
+		if _, err := library.Sync(ctx, node, db, ""); err != ErrOrphanLibrary {
+			t.Fatalf("orphan library was synced (%v)", err)
+		}
+
+		// sync the library to a project
+		if err := db.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		library.ParentProjectLabel = projectLabel
+		if _, err := library.Sync(ctx, node, db, ""); err == nil {
+			t.Fatal("library was synced with samples that aren't in the project")
+		}
+		cid := ""
+		for i, label := range library.GetSamples() {
+			sample := InitSample(label, int32(i+1))
+			sample.ParentProjectLabel = projectLabel
+			var err error
+			if cid, err = sample.Sync(ctx, node, db, cid); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cid, err := library.Sync(ctx, node, db, cid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored := &Library{}
+		if err := getRecord(ctx, node, db.Projects[projectLabel].GetLibraries()[libraryLabel], stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored.GetInputDNA() != library.GetInputDNA() || stored.GetOperator() != library.GetOperator() || len(stored.GetSamples()) != 2 {
+			t.Fatalf("stored library does not match: %v", stored)
+		}
+		var kit string
+		if err := node.DagGet(ctx, cid, "projects/"+projectLabel+"/libraries/"+libraryLabel+"/barcodeKit", &kit); err != nil || kit != barcodeKit {
+			t.Fatalf("could not get library by path: %v (%v)", kit, err)
+		}
+
+		// runs can only be linked to libraries in their project
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.ParentProjectLabel = projectLabel
+		run.Library = "missing library"
+		if _, err := run.Sync(ctx, node, db, cid); err == nil {
+			t.Fatal("run was synced with a missing library")
+		}
+		run.Library = libraryLabel
+		if _, err := run.Sync(ctx, node, db, cid); err != nil {
+			t.Fatal(err)
+		}
+
+		// replicas record announced libraries
+		replica, err := NewReplica(ctx, node, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := NewMessage(projectLabel)
+		msg.Payload = &Message_LibraryUpdated{LibraryUpdated: &LibraryUpdated{Label: libraryLabel, CID: db.Projects[projectLabel].GetLibraries()[libraryLabel]}}
+		if changed, err := replica.Apply(ctx, msg); err != nil || !changed || !strings.HasPrefix(msg.Describe(), "library created") {
+			t.Fatalf("library not applied (%v): %v", err, msg.Describe())
+		}
+		if replica.DB.Projects[projectLabel].GetLibraries()[libraryLabel] != msg.GetLibraryUpdated().GetCID() {
+			t.Fatal("library not recorded by the replica")
+		}
+	})
+}
@@ -0,0 +1,324 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// ErrMergeConflict is returned when a merge finds changes that can't be combined automatically
+var ErrMergeConflict = errors.New("merge conflict")
+
+// Conflict is a field that has been changed differently in the local and remote databases
+type Conflict struct {
+	Project string // the label of the project
+	Run     string // the label of the run (if the conflict is in a run)
+	Field   string // the conflicting field
+	Local   string // the local value, which is kept by the merge
+	Remote  string // the remote value
+}
+
+// String returns a description of the conflict
+func (conflict Conflict) String() string {
+	location := conflict.Project
+	if len(conflict.Run) != 0 {
+		location = fmt.Sprintf("%v/%v", location, conflict.Run)
+	}
+	return fmt.Sprintf("%v %v: local %q vs remote %q", location, conflict.Field, conflict.Local, conflict.Remote)
+}
+
+// MergeError is returned by a merge that found conflicts
+//
+// The rest of the merge will have been applied, with the local values kept for the
+// conflicting fields.
+type MergeError struct {
+	Conflicts []Conflict
+}
+
+// Error returns a summary of the conflicts
+func (err *MergeError) Error() string {
+	conflicts := make([]string, len(err.Conflicts))
+	for i, conflict := range err.Conflicts {
+		conflicts[i] = conflict.String()
+	}
+	return fmt.Sprintf("%v (%d): %v", ErrMergeConflict, len(conflicts), strings.Join(conflicts, "; "))
+}
+
+// Is allows the error to be matched to ErrMergeConflict
+func (err *MergeError) Is(target error) bool {
+	return target == ErrMergeConflict
+}
+
+// merger holds the state for a merge
+type merger struct {
+	ctx       context.Context
+	node      *backend.Node
+	pin       bool
+	conflicts []Conflict
+}
+
+// Merge will three-way merge a remote database into this one, using the database they were both based on
+//
+// Projects and runs are merged by label, and those removed from the remote since the base are
+// removed unless they have changed locally. Where a run differs, the run records are fetched and
+// merged: the History is combined in timestamp order, Tags keep the state that has progressed
+// furthest and the Status that can be reached from the other by the status transitions is kept.
+// Other fields take whichever side changed from the base. A change to both sides, or statuses
+// that can't reach each other (e.g. complete and failed), is reported as a conflict in a
+// *MergeError. The base can be nil, in which case a field set differently on both sides is a conflict.
+func (db *ProjectDatabase) Merge(ctx context.Context, node *backend.Node, base, remote *ProjectDatabase) error {
+	if base == nil {
+		base = InitDB()
+	}
+	if db.Projects == nil {
+		db.Projects = make(map[string]*Project)
+	}
+	m := &merger{ctx: ctx, node: node, pin: db.GetPin()}
+	for label, remoteProject := range remote.GetProjects() {
+		localProject, ok := db.Projects[label]
+		if !ok {
+			db.Projects[label] = proto.Clone(remoteProject).(*Project)
+			continue
+		}
+		if err := m.mergeProject(localProject, base.GetProjects()[label], remoteProject); err != nil {
+			return err
+		}
+	}
+
+	// a project removed remotely is removed locally, unless it has changed since the base
+	for label, baseProject := range base.GetProjects() {
+		if _, ok := remote.GetProjects()[label]; !ok && sameProject(db.Projects[label], baseProject) {
+			delete(db.Projects, label)
+		}
+	}
+	if len(m.conflicts) != 0 {
+		sort.Slice(m.conflicts, func(i, j int) bool {
+			return m.conflicts[i].String() < m.conflicts[j].String()
+		})
+		return &MergeError{Conflicts: m.conflicts}
+	}
+	return nil
+}
+
+// mergeProject will merge a remote project into the local one
+func (m *merger) mergeProject(local, base, remote *Project) error {
+	// the CID identifies the stored project, so a project with the remote CID already has the remote changes
+	if len(remote.GetCID()) != 0 && local.GetCID() == remote.GetCID() {
+		return nil
+	}
+
+	// merge the registration details, keeping the earliest creation time and all of the owners
+	if remote.GetCreated() != nil && (local.GetCreated() == nil || timestampLess(remote.GetCreated(), local.GetCreated())) {
+		local.Created = remote.GetCreated()
+	}
+	for _, owner := range remote.GetOwners() {
+		if !containsString(local.GetOwners(), owner) {
+			local.Owners = append(local.Owners, owner)
+		}
+	}
+	local.Id = m.mergeField(local.GetLabel(), "", "id", local.GetId(), base.GetId(), remote.GetId())
+	local.Description = m.mergeField(local.GetLabel(), "", "description", local.GetDescription(), base.GetDescription(), remote.GetDescription())
+	local.License = m.mergeField(local.GetLabel(), "", "license", local.GetLicense(), base.GetLicense(), remote.GetLicense())
+
+	// libraries and samples aren't merged field by field, so one changed on both sides is a conflict
+	if len(remote.GetLibraries()) != 0 && local.Libraries == nil {
+		local.Libraries = make(map[string]string)
+	}
+	for label, remoteCID := range remote.GetLibraries() {
+		setRecord(local.Libraries, label, m.mergeField(local.GetLabel(), "", "libraries/"+label, local.Libraries[label], base.GetLibraries()[label], remoteCID))
+	}
+	if len(remote.GetSamples()) != 0 && local.Samples == nil {
+		local.Samples = make(map[string]string)
+	}
+	for label, remoteCID := range remote.GetSamples() {
+		setRecord(local.Samples, label, m.mergeField(local.GetLabel(), "", "samples/"+label, local.Samples[label], base.GetSamples()[label], remoteCID))
+	}
+
+	// runs are merged field by field
+	if local.Runs == nil {
+		local.Runs = make(map[string]string)
+	}
+	for label, remoteCID := range remote.GetRuns() {
+		localCID, ok := local.Runs[label]
+		if !ok || localCID == remoteCID {
+			local.Runs[label] = remoteCID
+			continue
+		}
+		baseCID := base.GetRuns()[label]
+		switch {
+		case localCID == baseCID:
+			local.Runs[label] = remoteCID
+		case remoteCID == baseCID:
+		default:
+			mergedCID, err := m.mergeRun(local.GetLabel(), baseCID, localCID, remoteCID)
+			if err != nil {
+				return err
+			}
+			local.Runs[label] = mergedCID
+		}
+	}
+
+	// records removed remotely are removed locally, unless they have changed since the base
+	removeUnchanged(local.Libraries, base.GetLibraries(), remote.GetLibraries())
+	removeUnchanged(local.Samples, base.GetSamples(), remote.GetSamples())
+	removeUnchanged(local.Runs, base.GetRuns(), remote.GetRuns())
+	return nil
+}
+
+// setRecord will set the CID of a record, removing the record if the CID is empty
+func setRecord(records map[string]string, label, cid string) {
+	if len(cid) == 0 {
+		delete(records, label)
+		return
+	}
+	records[label] = cid
+}
+
+// removeUnchanged will delete the labels that are in the base but not the remote, if the local CID matches the base
+func removeUnchanged(local, base, remote map[string]string) {
+	for label, baseCID := range base {
+		if _, ok := remote[label]; !ok && local[label] == baseCID {
+			delete(local, label)
+		}
+	}
+}
+
+// sameProject reports whether two projects are the same, regardless of their CIDs
+func sameProject(a, b *Project) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	a, b = proto.Clone(a).(*Project), proto.Clone(b).(*Project)
+	a.CID, b.CID = "", ""
+	return proto.Equal(a, b)
+}
+
+// mergeRun will fetch and merge two versions of a run, store the result and return its CID
+func (m *merger) mergeRun(project, baseCID, localCID, remoteCID string) (string, error) {
+	base, local, remote := &Run{}, &Run{}, &Run{}
+	if len(baseCID) != 0 {
+		if err := getRecord(m.ctx, m.node, baseCID, base); err != nil {
+			return "", err
+		}
+	}
+	if err := getRecord(m.ctx, m.node, localCID, local); err != nil {
+		return "", err
+	}
+	if err := getRecord(m.ctx, m.node, remoteCID, remote); err != nil {
+		return "", err
+	}
+	label := local.GetLabel()
+
+	// merge the fields that can be combined
+	if remote.GetCreated() != nil && (local.GetCreated() == nil || timestampLess(remote.GetCreated(), local.GetCreated())) {
+		local.Created = remote.GetCreated()
+	}
+	local.History = mergeHistory(local.GetHistory(), remote.GetHistory())
+	local.Status = m.mergeStatus(project, label, local.GetStatus(), remote.GetStatus())
+	if len(remote.GetTags()) != 0 && local.Tags == nil {
+		local.Tags = make(map[string]*TagState)
+	}
+	for tag, state := range remote.GetTags() {
+		if current, ok := local.Tags[tag]; !ok || compareTags(state, current) > 0 {
+			local.Tags[tag] = state
+		}
+	}
+	for _, sample := range remote.GetSamples() {
+		if !containsString(local.GetSamples(), sample) {
+			local.Samples = append(local.Samples, sample)
+		}
+	}
+
+	// merge the rest, looking for conflicts
+	local.Library = m.mergeField(project, label, "library", local.GetLibrary(), base.GetLibrary(), remote.GetLibrary())
+	local.ParentProjectLabel = m.mergeField(project, label, "parentProjectLabel", local.GetParentProjectLabel(), base.GetParentProjectLabel(), remote.GetParentProjectLabel())
+	local.ParentProjectCID = m.mergeField(project, label, "parentProjectCID", local.GetParentProjectCID(), base.GetParentProjectCID(), remote.GetParentProjectCID())
+	local.OutputDirectory = m.mergeField(project, label, "outputDirectory", local.GetOutputDirectory(), base.GetOutputDirectory(), remote.GetOutputDirectory())
+	local.Fast5OutputDirectory = m.mergeField(project, label, "fast5OutputDirectory", local.GetFast5OutputDirectory(), base.GetFast5OutputDirectory(), remote.GetFast5OutputDirectory())
+	local.FastqOutputDirectory = m.mergeField(project, label, "fastqOutputDirectory", local.GetFastqOutputDirectory(), base.GetFastqOutputDirectory(), remote.GetFastqOutputDirectory())
+	requestOrder := m.mergeField(project, label, "requestOrder", strings.Join(local.GetRequestOrder(), ","), strings.Join(base.GetRequestOrder(), ","), strings.Join(remote.GetRequestOrder(), ","))
+	if requestOrder != strings.Join(local.GetRequestOrder(), ",") {
+		local.RequestOrder = remote.GetRequestOrder()
+	}
+	return putRecord(m.ctx, m.node, local, m.pin)
+}
+
+// mergeField will three-way merge a field, recording a conflict if both sides have changed it
+//
+// Clearing a field is a change like any other, so a field cleared on one side is cleared by the merge.
+func (m *merger) mergeField(project, run, field, local, base, remote string) string {
+	switch {
+	case local == remote, remote == base:
+		return local
+	case local == base:
+		return remote
+	}
+	m.conflicts = append(m.conflicts, Conflict{
+		Project: project,
+		Run:     run,
+		Field:   field,
+		Local:   local,
+		Remote:  remote,
+	})
+	return local
+}
+
+// mergeStatus will merge the status of a run, keeping whichever side can be reached from the other through the status
+// transitions and recording a conflict if neither can (e.g. one side completed and the other failed)
+func (m *merger) mergeStatus(project, run string, local, remote Status) Status {
+	switch {
+	case local == remote, remote == Status_UN_INITIALIZED, canReach(remote, local):
+		return local
+	case local == Status_UN_INITIALIZED, canReach(local, remote):
+		return remote
+	}
+	m.conflicts = append(m.conflicts, Conflict{
+		Project: project,
+		Run:     run,
+		Field:   "status",
+		Local:   local.String(),
+		Remote:  remote.String(),
+	})
+	return local
+}
+
+// mergeHistory will combine two histories in timestamp order, dropping duplicate comments
+func mergeHistory(local, remote []*Comment) []*Comment {
+	merged := make([]*Comment, 0, len(local)+len(remote))
+	seen := make(map[string]bool)
+	for _, comment := range append(append([]*Comment{}, local...), remote...) {
+		key := proto.CompactTextString(comment)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, comment)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return timestampLess(merged[i].GetTimestamp(), merged[j].GetTimestamp())
+	})
+	return merged
+}
+
+// containsString reports whether a list of strings contains a string
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// timestampLess reports whether timestamp a is before timestamp b
+func timestampLess(a, b *timestamp.Timestamp) bool {
+	return a.GetSeconds() < b.GetSeconds() || (a.GetSeconds() == b.GetSeconds() && a.GetNanos() < b.GetNanos())
+}
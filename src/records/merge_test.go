@@ -0,0 +1,130 @@
+package records
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestMerge
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+
+		// create a base database with a run in it
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.Tags["basecall"] = NewTagState()
+		run.Tags["assemble"] = &TagState{State: TagState_succeeded}
+		runCID, err := putRecord(ctx, node, run, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := InitDB()
+		project := InitProject(projectLabel)
+		project.Runs = map[string]string{runLabel: runCID}
+		if err := base.AddProject(project); err != nil {
+			t.Fatal(err)
+		}
+
+		// change the run differently on each side
+		edit := func(fn func(run *Run)) *ProjectDatabase {
+			edited := proto.Clone(run).(*Run)
+			fn(edited)
+			cid, err := putRecord(ctx, node, edited, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := proto.Clone(base).(*ProjectDatabase)
+			db.Projects[projectLabel].Runs[runLabel] = cid
+			return db
+		}
+		local := edit(func(run *Run) {
+			run.AddComment("local comment")
+			run.Tags["basecall"] = &TagState{State: TagState_succeeded}
+			run.OutputDirectory = "local output"
+			run.FastqOutputDirectory = "local fastqs"
+		})
+		remote := edit(func(run *Run) {
+			run.History = append(run.History, &Comment{Timestamp: ptypes.TimestampNow(), Text: "remote comment"})
+			run.Tags["demux"] = NewTagState()
+			run.OutputDirectory = "remote output"
+			run.Fast5OutputDirectory = "remote fast5s"
+			run.Status = Status_tagged
+		})
+		remote.Projects["another project"] = InitProject("another project")
+
+		// merge and check the conflict is reported
+		err = local.Merge(ctx, node, base, remote)
+		var mergeErr *MergeError
+		if !errors.Is(err, ErrMergeConflict) || !errors.As(err, &mergeErr) {
+			t.Fatalf("expected a merge conflict, got %v", err)
+		}
+		if len(mergeErr.Conflicts) != 1 || mergeErr.Conflicts[0].Field != "outputDirectory" || mergeErr.Conflicts[0].Remote != "remote output" {
+			t.Fatalf("unexpected conflicts: %v", mergeErr.Conflicts)
+		}
+
+		// check the rest was merged
+		if _, err := local.GetProject("another project"); err != nil {
+			t.Fatal("new remote project was not merged")
+		}
+		merged := &Run{}
+		if err := getRecord(ctx, node, local.Projects[projectLabel].Runs[runLabel], merged); err != nil {
+			t.Fatal(err)
+		}
+		if merged.GetOutputDirectory() != "local output" || merged.GetFastqOutputDirectory() != "local fastqs" || merged.GetFast5OutputDirectory() != "remote fast5s" {
+			t.Fatalf("fields not merged: %v", merged)
+		}
+		if !merged.Tags["basecall"].IsDone() || !merged.Tags["assemble"].IsDone() || merged.Tags["demux"].IsDone() || len(merged.Tags) != 3 {
+			t.Fatalf("tags not merged: %v", merged.Tags)
+		}
+		if merged.GetStatus() != Status_tagged {
+			t.Fatalf("status not merged: %v", merged.GetStatus())
+		}
+		if len(merged.History) != 3 || merged.History[0].Text != "run created." || merged.History[2].Text != "remote comment" {
+			t.Fatalf("history not merged: %v", merged.History)
+		}
+
+		// pulling into a non-empty database should merge without a base, so the changed fields conflict
+		remoteCID, err := remote.Push(ctx, node, "", "remote changes")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pulled := proto.Clone(base).(*ProjectDatabase)
+		if err := pulled.Pull(ctx, node, remoteCID); !errors.As(err, &mergeErr) || len(mergeErr.Conflicts) != 2 {
+			t.Fatalf("expected conflicts without a base, got %v", err)
+		}
+		if pulled.GetNumProjects() != 2 {
+			t.Fatal("pull did not merge the remote database")
+		}
+
+		// a field cleared on one side is cleared by the merge, unless the other side has changed it
+		m := &merger{}
+		if field := m.mergeField(projectLabel, runLabel, "fast5OutputDirectory", "", fast5Dir, fast5Dir); field != "" {
+			t.Fatalf("cleared field was not kept: %v", field)
+		}
+		if field := m.mergeField(projectLabel, runLabel, "fast5OutputDirectory", fast5Dir, fast5Dir, ""); field != "" {
+			t.Fatalf("remotely cleared field was not merged: %v", field)
+		}
+		if field := m.mergeField(projectLabel, runLabel, "fast5OutputDirectory", "", fast5Dir, "remote fast5s"); field != "" || len(m.conflicts) != 1 {
+			t.Fatalf("cleared and changed field did not conflict: %v (%v)", field, m.conflicts)
+		}
+		m.conflicts = nil
+
+		// statuses are merged by the status transitions, so complete and failed runs conflict
+		if status := m.mergeStatus(projectLabel, runLabel, Status_failed, Status_archived); status != Status_archived {
+			t.Fatalf("archived status not kept: %v", status)
+		}
+		if status := m.mergeStatus(projectLabel, runLabel, Status_complete, Status_tagged); status != Status_complete {
+			t.Fatalf("complete status not kept: %v", status)
+		}
+		if status := m.mergeStatus(projectLabel, runLabel, Status_complete, Status_failed); status != Status_complete || len(m.conflicts) != 1 || m.conflicts[0].Field != "status" {
+			t.Fatalf("complete and failed statuses did not conflict: %v (%v)", status, m.conflicts)
+		}
+	})
+}
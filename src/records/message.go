@@ -0,0 +1,125 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// MessageVersion is the version of the Message envelope sent by this version of Scribe
+const MessageVersion uint32 = 1
+
+// error messages
+var (
+	// ErrMessageVersion is returned when decoding a message envelope from an incompatible version of Scribe
+	ErrMessageVersion = errors.New("unsupported message version")
+
+	// ErrNoPayload is returned when a message envelope has no payload
+	ErrNoPayload = errors.New("message has no payload")
+)
+
+// NewMessage will create a message envelope about a project, ready for a payload and the sender's peer ID to be added
+func NewMessage(project string) *Message {
+	return &Message{
+		Version:   MessageVersion,
+		Id:        newID(),
+		Timestamp: ptypes.TimestampNow(),
+		Project:   project,
+	}
+}
+
+// newID will return a random identifier
+func newID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// EncodeMessage will marshal a message envelope for publishing
+func EncodeMessage(msg *Message) (string, error) {
+	if msg.GetPayload() == nil {
+		return "", ErrNoPayload
+	}
+	buf := &bytes.Buffer{}
+	jsonMarshaller := jsonpb.Marshaler{
+		EnumsAsInts:  false,
+		EmitDefaults: false,
+		OrigName:     false,
+	}
+	if err := jsonMarshaller.Marshal(buf, msg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Announce will set the sender of a message envelope to the node's peer ID and publish it to the message's project
+func Announce(ctx context.Context, node *backend.Node, msg *Message) error {
+	self, err := node.Identity(ctx)
+	if err != nil {
+		return err
+	}
+	msg.Sender = self.ID
+	data, err := EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	return node.PublishProject(ctx, msg.GetProject(), data)
+}
+
+// DecodeMessage will unmarshal a received message envelope
+//
+// Fields added by newer versions of Scribe are ignored, but messages with a newer
+// envelope version are rejected.
+func DecodeMessage(data []byte) (*Message, error) {
+	msg := &Message{}
+	jsonUnmarshaller := jsonpb.Unmarshaler{
+		AllowUnknownFields: true,
+	}
+	if err := jsonUnmarshaller.Unmarshal(bytes.NewReader(data), msg); err != nil {
+		return nil, err
+	}
+	if msg.GetVersion() == 0 || msg.GetVersion() > MessageVersion {
+		return nil, fmt.Errorf("%w: %d", ErrMessageVersion, msg.GetVersion())
+	}
+	if msg.GetPayload() == nil {
+		return nil, ErrNoPayload
+	}
+	return msg, nil
+}
+
+// Describe will return a human readable summary of the message payload
+func (msg *Message) Describe() string {
+	switch payload := msg.GetPayload().(type) {
+	case *Message_RunCreated:
+		return fmt.Sprintf("run created: %v (%v)", payload.RunCreated.GetLabel(), payload.RunCreated.GetCID())
+	case *Message_RunUpdated:
+		return fmt.Sprintf("run updated: %v (%v -> %v)", payload.RunUpdated.GetLabel(), payload.RunUpdated.GetPreviousCID(), payload.RunUpdated.GetCID())
+	case *Message_LibraryUpdated:
+		if len(payload.LibraryUpdated.GetPreviousCID()) == 0 {
+			return fmt.Sprintf("library created: %v (%v)", payload.LibraryUpdated.GetLabel(), payload.LibraryUpdated.GetCID())
+		}
+		return fmt.Sprintf("library updated: %v (%v -> %v)", payload.LibraryUpdated.GetLabel(), payload.LibraryUpdated.GetPreviousCID(), payload.LibraryUpdated.GetCID())
+	case *Message_TagCompleted:
+		return fmt.Sprintf("tag completed: %v for run %v", payload.TagCompleted.GetTag(), payload.TagCompleted.GetRunLabel())
+	case *Message_DatabaseHeadChanged:
+		return fmt.Sprintf("database head changed: %v -> %v", payload.DatabaseHeadChanged.GetPreviousCID(), payload.DatabaseHeadChanged.GetCID())
+	case *Message_ProjectRegistered:
+		return fmt.Sprintf("project registered: %v (%v)", payload.ProjectRegistered.GetLabel(), payload.ProjectRegistered.GetId())
+	case *Message_TagRequested:
+		return fmt.Sprintf("tag requested: %v for run %v (attempt %d)", payload.TagRequested.GetTag(), payload.TagRequested.GetRunLabel(), payload.TagRequested.GetAttempt())
+	case *Message_TagFailed:
+		return fmt.Sprintf("tag failed: %v for run %v (%v)", payload.TagFailed.GetTag(), payload.TagFailed.GetRunLabel(), payload.TagFailed.GetError())
+	case *Message_StatusChanged:
+		return fmt.Sprintf("%v status changed: %v (%v -> %v)", payload.StatusChanged.GetRecordType(), payload.StatusChanged.GetLabel(), payload.StatusChanged.GetPreviousStatus(), payload.StatusChanged.GetStatus())
+	}
+	return "unknown payload"
+}
@@ -0,0 +1,62 @@
+package records
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// testRunCID is used as the CID of a run in test messages
+var testRunCID = "bafyreigdmqpykrgxyaxtlafqpqhzrb7qy2rh75nldvfd4tucqmqqme5yje"
+
+// TestMessage
+func TestMessage(t *testing.T) {
+	msg := NewMessage(projectLabel)
+	msg.Sender = "QmSender"
+	msg.Payload = &Message_RunCreated{RunCreated: &RunCreated{Label: runLabel, CID: testRunCID}}
+
+	// check the envelope survives encoding
+	data, err := EncodeMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeMessage([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.GetId() != msg.GetId() || decoded.GetSender() != "QmSender" || decoded.GetProject() != projectLabel {
+		t.Fatalf("decoded envelope does not match the original: %v", decoded)
+	}
+	if !proto.Equal(decoded.GetTimestamp(), msg.GetTimestamp()) {
+		t.Fatal("decoded timestamp does not match the original")
+	}
+	if decoded.GetRunCreated().GetLabel() != runLabel || decoded.GetRunCreated().GetCID() != testRunCID {
+		t.Fatalf("decoded payload does not match the original: %v", decoded.GetPayload())
+	}
+	if !strings.HasPrefix(decoded.Describe(), "run created") {
+		t.Fatalf("unexpected description: %v", decoded.Describe())
+	}
+
+	// unknown fields should be ignored, but not newer versions
+	if _, err := DecodeMessage([]byte(strings.Replace(data, "{", `{"futureField": true,`, 1))); err != nil {
+		t.Fatal(err)
+	}
+	msg.Version = MessageVersion + 1
+	data, err = EncodeMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeMessage([]byte(data)); !errors.Is(err, ErrMessageVersion) {
+		t.Fatalf("expected ErrMessageVersion, got %v", err)
+	}
+
+	// free text and empty envelopes should be rejected
+	if _, err := DecodeMessage([]byte("just loaded the project over here...")); err == nil {
+		t.Fatal("free text decoded as a message")
+	}
+	if _, err := EncodeMessage(NewMessage(projectLabel)); err != ErrNoPayload {
+		t.Fatalf("expected ErrNoPayload, got %v", err)
+	}
+}
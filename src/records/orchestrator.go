@@ -0,0 +1,276 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// DefaultMaxAttempts is the number of times a tagged service is requested before the run fails
+const DefaultMaxAttempts uint32 = 3
+
+// Orchestrator sends requests to the tagged services of the runs in a project, one service at a time in
+// the order set by each run's RequestOrder
+//
+// A tagged run has at most one outstanding request. When the service replies with a TagCompleted message
+// the tag succeeds and the next service is requested; after a TagFailed message the service is requested
+// again, until MaxAttempts is reached and the run fails. Once every service in the request order is done,
+// the run is moved to announced.
+//
+// The orchestrator's database is a Replica, so new database heads announced by other nodes are merged
+// into it. Each change to a run is pushed as a new version on top of the Head and announced as a
+// RunUpdated message.
+type Orchestrator struct {
+	*Replica
+	Project     string // the label of the project being orchestrated
+	MaxAttempts uint32 // the number of times a service is requested before the run fails
+}
+
+// NewOrchestrator will create an orchestrator for a project in the database at the head CID
+func NewOrchestrator(ctx context.Context, node *backend.Node, head, project string) (*Orchestrator, error) {
+	replica, err := NewReplica(ctx, node, head)
+	if err != nil {
+		return nil, err
+	}
+	orchestrator := &Orchestrator{
+		Replica:     replica,
+		Project:     project,
+		MaxAttempts: DefaultMaxAttempts,
+	}
+	if _, err := orchestrator.DB.GetProject(project); err != nil {
+		return nil, fmt.Errorf("can't orchestrate %v: %w", project, err)
+	}
+	return orchestrator, nil
+}
+
+// Dispatch will request the next service for every tagged run in the project
+//
+// Outstanding requests are sent again, in case they were lost while the orchestrator was not running,
+// so services should ignore a request for an attempt they are already working on. An ErrNotAnnounced
+// error is returned once the rest of the runs have been dispatched.
+func (orchestrator *Orchestrator) Dispatch(ctx context.Context) error {
+	project, err := orchestrator.DB.GetProject(orchestrator.Project)
+	if err != nil {
+		return err
+	}
+	return orchestrator.dispatch(ctx, sortedKeys(project.GetRuns()), true)
+}
+
+// Apply will update the orchestrator with a message envelope, and request the next service for any run that needs one
+//
+// Replies from tagged services update their runs, and the tags are finished at the time of the
+// reply so that replicas applying the same reply store the same run. New database heads, new and
+// updated runs are applied to the orchestrator's Replica and the database is pushed if it has
+// changed, so that runs tagged by other nodes are dispatched. It returns true if the database has
+// changed. Messages about other projects, other payloads and replies to requests that are not
+// outstanding (e.g. duplicates) are ignored.
+func (orchestrator *Orchestrator) Apply(ctx context.Context, msg *Message) (bool, error) {
+	if msg.GetProject() != orchestrator.Project {
+		return false, nil
+	}
+	var labels []string
+	switch payload := msg.GetPayload().(type) {
+	case *Message_TagCompleted:
+
+		// the tag is completed by the Replica, so that the run matches the one stored by other replicas
+		if _, tag, err := orchestrator.requested(ctx, payload.TagCompleted.GetRunLabel(), payload.TagCompleted.GetTag()); tag == nil {
+			return false, err
+		}
+		labels = []string{payload.TagCompleted.GetRunLabel()}
+	case *Message_TagFailed:
+		run, tag, err := orchestrator.requested(ctx, payload.TagFailed.GetRunLabel(), payload.TagFailed.GetTag())
+		if tag == nil {
+			return false, err
+		}
+		tag.Fail(payload.TagFailed.GetError())
+		tag.Finished = msg.GetTimestamp()
+		return true, orchestrator.advance(ctx, run, false)
+	case *Message_RunCreated:
+		labels = []string{payload.RunCreated.GetLabel()}
+	case *Message_RunUpdated:
+		labels = []string{payload.RunUpdated.GetLabel()}
+	case *Message_StatusChanged:
+		if payload.StatusChanged.GetRecordType() != "run" || payload.StatusChanged.GetStatus() != Status_tagged {
+			return false, nil
+		}
+		labels = []string{payload.StatusChanged.GetLabel()}
+	case *Message_DatabaseHeadChanged:
+	default:
+		return false, nil
+	}
+
+	// apply the change to the database, then dispatch the runs it affects and push any other changes
+	head := orchestrator.Head
+	if _, err := orchestrator.Replica.Apply(ctx, msg); err != nil {
+		return false, err
+	}
+	if msg.GetDatabaseHeadChanged() != nil {
+		labels = sortedKeys(orchestrator.DB.Projects[orchestrator.Project].GetRuns())
+	}
+	dispatchErr := orchestrator.dispatch(ctx, labels, false)
+	if err := orchestrator.push(ctx); err != nil {
+		return orchestrator.Head != head, err
+	}
+	if dispatchErr != nil {
+		return orchestrator.Head != head, dispatchErr
+	}
+	return orchestrator.Head != head, nil
+}
+
+// dispatch will advance the tagged runs in a list of labels, skipping any that aren't in the project
+//
+// An ErrNotAnnounced error is returned once the rest of the runs have been dispatched.
+func (orchestrator *Orchestrator) dispatch(ctx context.Context, labels []string, resend bool) error {
+	var announceErr error
+	for _, label := range labels {
+		if _, ok := orchestrator.DB.Projects[orchestrator.Project].GetRuns()[label]; !ok {
+			continue
+		}
+		run, err := orchestrator.run(ctx, label)
+		if err != nil {
+			return err
+		}
+		switch err := orchestrator.advance(ctx, run, resend); {
+		case errors.Is(err, ErrNotAnnounced):
+			announceErr = err
+		case err != nil:
+			return err
+		}
+	}
+	return announceErr
+}
+
+// run will get a run from the project
+func (orchestrator *Orchestrator) run(ctx context.Context, label string) (*Run, error) {
+	project, err := orchestrator.DB.GetProject(orchestrator.Project)
+	if err != nil {
+		return nil, err
+	}
+	cid, ok := project.GetRuns()[label]
+	if !ok {
+		return nil, fmt.Errorf("run not found in %v: %v", orchestrator.Project, label)
+	}
+	run := &Run{}
+	if err := getRecord(ctx, orchestrator.node, cid, run); err != nil {
+		return nil, err
+	}
+	if len(run.GetParentProjectLabel()) == 0 {
+		run.ParentProjectLabel = orchestrator.Project
+	}
+	return run, nil
+}
+
+// requested will get a tagged run and the tag that a service has replied about, returning a nil tag if there is no outstanding request for it
+func (orchestrator *Orchestrator) requested(ctx context.Context, runLabel, label string) (*Run, *TagState, error) {
+	run, err := orchestrator.run(ctx, runLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+	tag, ok := run.GetTags()[label]
+	if run.GetStatus() != Status_tagged || !ok || (tag.GetState() != TagState_requested && tag.GetState() != TagState_running) {
+		return nil, nil, nil
+	}
+	return run, tag, nil
+}
+
+// advance will move a tagged run on to its next service, store it and send any request
+//
+// If resend is set, an outstanding request is sent again. Any change of status is announced once the run has been stored.
+func (orchestrator *Orchestrator) advance(ctx context.Context, run *Run, resend bool) error {
+	if run.GetStatus() != Status_tagged {
+		return nil
+	}
+
+	// work out what the run is waiting for
+	var request *TagRequested
+	var statusChanged *Message
+	var err error
+	label, tag := nextTag(run)
+	switch {
+	case tag == nil:
+		statusChanged, err = setStatus("run", run, Status_announced, func(status Status) { run.Status = status }, "")
+	case tag.GetState() == TagState_requested || tag.GetState() == TagState_running:
+		if resend {
+			request = &TagRequested{RunLabel: run.GetLabel(), Tag: label, Attempt: tag.GetAttempts()}
+		}
+	case tag.GetState() == TagState_failed && tag.GetAttempts() >= orchestrator.MaxAttempts:
+		statusChanged, err = setStatus("run", run, Status_failed, func(status Status) { run.Status = status }, fmt.Sprintf("%v failed after %d attempts: %v", label, tag.GetAttempts(), tag.GetLastError()))
+	default:
+		tag.Request()
+		request = &TagRequested{RunLabel: run.GetLabel(), Tag: label, Attempt: tag.GetAttempts()}
+	}
+	if err != nil {
+		return err
+	}
+
+	// store the run before announcing any changes, so that the services can fetch it
+	updated, err := orchestrator.store(ctx, run)
+	if err != nil {
+		return err
+	}
+	var announceErr error
+	for _, msg := range []*Message{updated, statusChanged} {
+		if msg == nil {
+			continue
+		}
+		if err := Announce(ctx, orchestrator.node, msg); err != nil && !errors.Is(err, backend.ErrOffline) {
+			announceErr = fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+		}
+	}
+	if request == nil {
+		return announceErr
+	}
+	request.RunCID = orchestrator.DB.Projects[orchestrator.Project].GetRuns()[run.GetLabel()]
+	msg := NewMessage(orchestrator.Project)
+	msg.Payload = &Message_TagRequested{TagRequested: request}
+	if err := Announce(ctx, orchestrator.node, msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+	}
+	return announceErr
+}
+
+// store will put a run in the IPFS and push the database if the run has changed, returning the RunUpdated message to announce (or nil if it is unchanged)
+func (orchestrator *Orchestrator) store(ctx context.Context, run *Run) (*Message, error) {
+	previousCID := orchestrator.DB.Projects[orchestrator.Project].GetRuns()[run.GetLabel()]
+	cid, err := putRecord(ctx, orchestrator.node, run, orchestrator.DB.GetPin())
+	if err != nil || cid == previousCID {
+		return nil, err
+	}
+	msg := NewMessage(orchestrator.Project)
+	msg.Payload = &Message_RunUpdated{RunUpdated: &RunUpdated{Label: run.GetLabel(), CID: cid, PreviousCID: previousCID}}
+	orchestrator.setRun(orchestrator.Project, run.GetLabel(), cid)
+	orchestrator.changes = append(orchestrator.changes, msg.Describe())
+	return msg, orchestrator.push(ctx)
+}
+
+// push will push the orchestrator's database if it has changed since the Head
+func (orchestrator *Orchestrator) push(ctx context.Context) error {
+	if len(orchestrator.changes) == 0 && len(orchestrator.merged) == 0 {
+		return nil
+	}
+	_, err := orchestrator.Push(ctx)
+	return err
+}
+
+// nextTag will return the first tag in the request order of a run that is not done, or a nil tag if they are all done
+//
+// Tags in the request order that haven't been added to the run are added as pending.
+func nextTag(run *Run) (string, *TagState) {
+	for _, label := range run.GetRequestOrder() {
+		tag, ok := run.GetTags()[label]
+		if !ok {
+			if run.Tags == nil {
+				run.Tags = make(map[string]*TagState)
+			}
+			tag = NewTagState()
+			run.Tags[label] = tag
+		}
+		if !tag.IsDone() {
+			return label, tag
+		}
+	}
+	return "", nil
+}
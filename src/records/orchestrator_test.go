@@ -0,0 +1,178 @@
+package records
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestOrchestrator
+func TestOrchestrator(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 2, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+
+		// listen for requests on another node
+		sub, err := nodes[1].Subscribe(ctx, projectLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receive := func() *TagRequested {
+			for {
+				select {
+				case received := <-sub.Messages():
+					msg, err := DecodeMessage(received.Data)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if request := msg.GetTagRequested(); request != nil {
+						return request
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("no request received")
+				}
+			}
+		}
+		reply := func(orchestrator *Orchestrator, payload isMessage_Payload) bool {
+			msg := NewMessage(projectLabel)
+			msg.Payload = payload
+			changed, err := orchestrator.Apply(ctx, msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return changed
+		}
+
+		// set up a project with a tagged run
+		db := InitDB()
+		if err := db.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewOrchestrator(ctx, node, "", "missing project"); err == nil {
+			t.Fatal("orchestrator created for a missing project")
+		}
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.ParentProjectLabel = projectLabel
+		if err := run.Tag(ctx, node, "basecall", "upload"); err != nil {
+			t.Fatal(err)
+		}
+		head, err := run.Sync(ctx, node, db, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		orchestrator, err := NewOrchestrator(ctx, node, head, projectLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the first service in the request order should be requested
+		if err := orchestrator.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		request := receive()
+		if request.GetTag() != "basecall" || request.GetAttempt() != 1 || request.GetRunCID() != orchestrator.DB.Projects[projectLabel].GetRuns()[runLabel] {
+			t.Fatalf("unexpected request: %v", request)
+		}
+		if orchestrator.Head == head {
+			t.Fatal("requested run was not synced")
+		}
+
+		// replies for services that haven't been requested are ignored
+		if reply(orchestrator, &Message_TagCompleted{TagCompleted: &TagCompleted{RunLabel: runLabel, Tag: "upload"}}) {
+			t.Fatal("reply for a service that wasn't requested was applied")
+		}
+
+		// a completed service should be followed by the next one, and a failed service should be retried
+		completed := NewMessage(projectLabel)
+		completed.Payload = &Message_TagCompleted{TagCompleted: &TagCompleted{RunLabel: runLabel, Tag: "basecall", ResultCID: "result"}}
+		if changed, err := orchestrator.Apply(ctx, completed); err != nil || !changed {
+			t.Fatalf("completed service was not applied (%v)", err)
+		}
+		if request := receive(); request.GetTag() != "upload" || request.GetAttempt() != 1 {
+			t.Fatalf("unexpected request: %v", request)
+		}
+		reply(orchestrator, &Message_TagFailed{TagFailed: &TagFailed{RunLabel: runLabel, Tag: "upload", Error: "no network"}})
+		if request := receive(); request.GetTag() != "upload" || request.GetAttempt() != 2 {
+			t.Fatalf("unexpected request: %v", request)
+		}
+
+		// once every service is done the run should be announced
+		reply(orchestrator, &Message_TagCompleted{TagCompleted: &TagCompleted{RunLabel: runLabel, Tag: "upload"}})
+		orchestrated, err := orchestrator.run(ctx, runLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if orchestrated.GetStatus() != Status_announced || orchestrated.GetTags()["basecall"].GetResultCID() != "result" || orchestrated.GetTags()["upload"].GetAttempts() != 2 {
+			t.Fatalf("run not orchestrated: %v", orchestrated)
+		}
+		if !proto.Equal(orchestrated.GetTags()["basecall"].GetFinished(), completed.GetTimestamp()) {
+			t.Fatalf("tag not finished at the time of the reply: %v", orchestrated.GetTags()["basecall"])
+		}
+
+		// a run tagged by another node should be merged into the orchestrator's database and dispatched
+		remote := InitDB()
+		if err := remote.Pull(ctx, nodes[1], orchestrator.Head); err != nil {
+			t.Fatal(err)
+		}
+		failing := InitRun("failing run", outputDir, fast5Dir, fastqDir)
+		failing.ParentProjectLabel = projectLabel
+		if err := failing.Tag(ctx, nodes[1], "basecall"); err != nil {
+			t.Fatal(err)
+		}
+		remoteHead, err := failing.Sync(ctx, nodes[1], remote, orchestrator.Head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reply(orchestrator, &Message_DatabaseHeadChanged{DatabaseHeadChanged: &DatabaseHeadChanged{CID: remoteHead, PreviousCID: orchestrator.Head}}) {
+			t.Fatal("new database head was not merged")
+		}
+
+		// a run should fail once a service has used up its attempts
+		orchestrator.MaxAttempts = 2
+		for attempt := uint32(1); attempt <= orchestrator.MaxAttempts; attempt++ {
+			if request := receive(); request.GetRunLabel() != "failing run" || request.GetAttempt() != attempt {
+				t.Fatalf("unexpected request: %v", request)
+			}
+			reply(orchestrator, &Message_TagFailed{TagFailed: &TagFailed{RunLabel: "failing run", Tag: "basecall", Error: "out of memory"}})
+		}
+		if failing, err = orchestrator.run(ctx, "failing run"); err != nil {
+			t.Fatal(err)
+		}
+		if failing.GetStatus() != Status_failed || !strings.Contains(failing.GetHistory()[len(failing.GetHistory())-1].GetText(), "out of memory") {
+			t.Fatalf("run did not fail: %v", failing)
+		}
+
+		// a tagged run created after the orchestrator started should be dispatched
+		late := InitRun("late run", outputDir, fast5Dir, fastqDir)
+		late.ParentProjectLabel = projectLabel
+		if _, err := late.AddTags("basecall"); err != nil {
+			t.Fatal(err)
+		}
+		lateCID, err := putRecord(ctx, nodes[1], late, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply(orchestrator, &Message_RunCreated{RunCreated: &RunCreated{Label: "late run", CID: lateCID}})
+		if request := receive(); request.GetRunLabel() != "late run" || request.GetAttempt() != 1 {
+			t.Fatalf("unexpected request: %v", request)
+		}
+
+		// the orchestrator's changes should be pushed on top of the merged head
+		history, err := History(ctx, node, orchestrator.Head, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		merged := false
+		for _, entry := range history {
+			merged = merged || entry.CID == remoteHead
+		}
+		if !merged {
+			t.Fatalf("orchestrator forked the database history: %v", history)
+		}
+	})
+}
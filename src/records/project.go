@@ -1,6 +1,19 @@
 // Package records interfaces the protobuf definitions with Scribe
 package records
 
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// ErrNoLabel is issued when a project without a label is registered
+var ErrNoLabel = errors.New("project has no label")
+
 // InitProject will init a project struct with the minimum required values
 func InitProject(label string) *Project {
 
@@ -13,8 +26,53 @@ func InitProject(label string) *Project {
 	return project
 }
 
-// Register will register a project on the IPFS
-func (project *Project) Register() error {
+// Register will register a new project in the database, publish it to the IPFS and announce it
+//
+// The project is given a stable ID and creation time, and is owned by the node if no owners
+// are set (the owners are left empty if the node is offline). The project name is published so
+// that other nodes can discover it, and a ProjectRegistered message is sent to the project's
+// pubsub topic so that they can join it. The CID of the new database version is returned.
+func (project *Project) Register(ctx context.Context, node *backend.Node, db *ProjectDatabase, parentCID string) (string, error) {
+	if len(project.GetLabel()) == 0 {
+		return "", ErrNoLabel
+	}
+
+	// set the registration details
+	if len(project.GetId()) == 0 {
+		project.Id = newID()
+	}
+	if project.GetCreated() == nil {
+		project.Created = ptypes.TimestampNow()
+	}
+	if len(project.GetOwners()) == 0 {
+		self, err := node.Identity(ctx)
+		switch {
+		case err == nil:
+			project.Owners = []string{self.ID}
+		case !errors.Is(err, backend.ErrOffline):
+			return "", err
+		}
+	}
+
+	// add the project to the database and push it
+	if err := db.AddProject(project); err != nil {
+		return "", err
+	}
+	msg := NewMessage(project.GetLabel())
+	msg.Payload = &Message_ProjectRegistered{ProjectRegistered: &ProjectRegistered{Label: project.GetLabel(), Id: project.GetId()}}
+	dbCID, err := db.Push(ctx, node, parentCID, msg.Describe())
+	if err != nil {
+		delete(db.Projects, project.GetLabel())
+		return "", err
+	}
+	msg.GetProjectRegistered().CID = project.GetCID()
 
-	return nil
+	// make the project discoverable and announce it on its topic
+	if err := node.PublishName(ctx, project.GetLabel()); err != nil && !errors.Is(err, backend.ErrOffline) {
+		return dbCID, fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+	}
+	if err := Announce(ctx, node, msg); err != nil && !errors.Is(err, backend.ErrOffline) {
+		return dbCID, fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+	}
+	return dbCID, nil
 }
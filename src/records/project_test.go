@@ -1,17 +1,80 @@
 package records
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
 
 var (
 	projectLabel = "test project"
-	projectCID   = ""
 )
 
 // TestProject
 func TestProject(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 2, func(t *testing.T, nodes []*backend.Node) {
+		db := InitDB()
+
+		// a project needs a label to be registered
+		if _, err := InitProject("").Register(ctx, nodes[0], db, ""); err != ErrNoLabel {
+			t.Fatalf("project registered without a label (%v)", err)
+		}
 
-	// init a project
-	project := InitProject(projectLabel)
+		// listen for the project on another node, then register it
+		replica, err := NewReplica(ctx, nodes[1], "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, err := nodes[1].Subscribe(ctx, projectLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		project := InitProject(projectLabel)
+		project.Description = "a test project"
+		project.License = "MIT"
+		cid, err := project.Register(ctx, nodes[0], db, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		self, err := nodes[0].Identity(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(project.GetId()) == 0 || project.GetCreated() == nil || len(project.GetOwners()) != 1 || project.GetOwners()[0] != self.ID {
+			t.Fatalf("registration details not set: %v", project)
+		}
+		if _, err := InitProject(projectLabel).Register(ctx, nodes[0], db, cid); err == nil {
+			t.Fatal("project registered twice")
+		}
 
-	_ = project
+		// check the other node can join the project from the announcement
+		var msg *Message
+		select {
+		case received := <-sub.Messages():
+			if msg, err = DecodeMessage(received.Data); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no announcement received")
+		}
+		if changed, err := replica.Apply(ctx, msg); err != nil || !changed {
+			t.Fatalf("could not join project (%v)", err)
+		}
+		joined, err := replica.DB.GetProject(projectLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := replica.Push(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if joined.GetId() != project.GetId() || joined.GetDescription() != project.GetDescription() || joined.GetLicense() != project.GetLicense() || joined.GetCID() != project.GetCID() {
+			t.Fatalf("joined project does not match the registered one: %v", joined)
+		}
+		if changed, err := replica.Apply(ctx, msg); err != nil || changed {
+			t.Fatalf("project joined twice (%v)", err)
+		}
+	})
 }
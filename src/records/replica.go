@@ -0,0 +1,265 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// Replica is a local copy of the project database, which is kept up to date with the events
+// published by other Scribe nodes
+//
+// Events are recorded in the replicated state of the database (see CRDTDatabase), which is pushed
+// with each version and merged from the versions pushed by other replicas, so replicas that have
+// seen the same events hold the same projects and runs whatever order the events arrived in. The
+// DB is built from the state after each event; changes made to the DB directly are recorded in the
+// state before the next event is applied or the database is pushed.
+type Replica struct {
+	DB      *ProjectDatabase // the local copy of the database, built from the replicated state
+	Head    string           // the CID of the database when it was last pulled or pushed
+	node    *backend.Node
+	state   *CRDTDatabase    // the replicated state of the database
+	built   *ProjectDatabase // a copy of the DB as it was last built, for finding local changes
+	changes []string         // descriptions of the changes since the Head, for the next version
+	merged  string           // the CID of a version merged into the database since the Head
+}
+
+// NewReplica will create a replica of the database at the head CID (or an empty database if no CID is provided)
+func NewReplica(ctx context.Context, node *backend.Node, head string) (*Replica, error) {
+	// changes are recorded as the node's peer ID, or anonymously if the node is offline and has no IPFS repo
+	var id string
+	self, err := node.Identity(ctx)
+	switch {
+	case err == nil:
+		id = self.ID
+	case !errors.Is(err, backend.ErrOffline):
+		return nil, err
+	}
+	replica := &Replica{
+		DB:    InitDB(),
+		node:  node,
+		state: NewCRDTDatabase(id),
+		built: InitDB(),
+	}
+	if len(head) == 0 {
+		return replica, nil
+	}
+	if err := replica.DB.Pull(ctx, node, head); err != nil {
+		return nil, err
+	}
+	if replica.state, err = pullState(ctx, node, replica.DB, head, id); err != nil {
+		return nil, err
+	}
+	replica.built = proto.Clone(replica.DB).(*ProjectDatabase)
+	replica.Head = head
+	return replica, nil
+}
+
+// Apply will update the replica with an event from a message envelope
+//
+// It returns true if the database has been changed and needs pushing. A new database head is
+// merged into the replica's database and becomes the replica's Head, so the Head should be
+// checked after applying messages.
+func (replica *Replica) Apply(ctx context.Context, msg *Message) (bool, error) {
+	changed, err := replica.apply(ctx, msg)
+	if changed {
+		replica.changes = append(replica.changes, msg.Describe())
+	}
+	return changed, err
+}
+
+// apply will record the payload of a message envelope in the replicated state and rebuild the database
+func (replica *Replica) apply(ctx context.Context, msg *Message) (bool, error) {
+	if err := replica.commit(ctx); err != nil {
+		return false, err
+	}
+	stamp := messageStamp(msg)
+	var err error
+	switch payload := msg.GetPayload().(type) {
+	case *Message_RunCreated:
+		err = replica.state.putRun(ctx, replica.node, msg.GetProject(), payload.RunCreated.GetLabel(), payload.RunCreated.GetCID(), "", stamp)
+
+	case *Message_RunUpdated:
+		err = replica.state.putRun(ctx, replica.node, msg.GetProject(), payload.RunUpdated.GetLabel(), payload.RunUpdated.GetCID(), payload.RunUpdated.GetPreviousCID(), stamp)
+
+	case *Message_LibraryUpdated:
+		replica.state.putLibrary(msg.GetProject(), payload.LibraryUpdated.GetLabel(), payload.LibraryUpdated.GetCID(), stamp)
+
+	case *Message_TagCompleted:
+		err = replica.state.completeTag(msg.GetProject(), payload.TagCompleted.GetRunLabel(), payload.TagCompleted.GetTag(), payload.TagCompleted.GetResultCID(), stamp)
+
+	case *Message_DatabaseHeadChanged:
+		return replica.merge(ctx, payload.DatabaseHeadChanged.GetCID())
+
+	case *Message_ProjectRegistered:
+		err = replica.joinProject(ctx, payload.ProjectRegistered, stamp)
+
+	case *Message_StatusChanged, *Message_TagRequested, *Message_TagFailed:
+		// the changed record is received when it is synced, so there is nothing to apply
+		return false, nil
+
+	default:
+		return false, ErrNoPayload
+	}
+	if err != nil {
+		return false, err
+	}
+	return replica.build(ctx)
+}
+
+// merge will merge the replicated state of a new database head into the replica
+//
+// A head whose changes the replica already holds is ignored. Otherwise the head becomes the
+// replica's Head, and true is returned if the replica holds changes that the head doesn't.
+func (replica *Replica) merge(ctx context.Context, head string) (bool, error) {
+	if head == replica.Head {
+		return false, nil
+	}
+	remote := InitDB()
+	if err := getDatabase(ctx, replica.node, head, remote); err != nil {
+		return false, err
+	}
+	state, err := pullState(ctx, replica.node, remote, head, replica.state.replica)
+	if err != nil {
+		return false, err
+	}
+	before, err := replica.state.encode()
+	if err != nil {
+		return false, err
+	}
+	replica.state.Merge(state)
+	after, err := replica.state.encode()
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(before, after) && len(replica.Head) != 0 {
+		return false, nil
+	}
+	if _, err := replica.build(ctx); err != nil {
+		return false, err
+	}
+	theirs, err := state.encode()
+	if err != nil {
+		return false, err
+	}
+	changed := !bytes.Equal(after, theirs) || !sameContent(replica.DB, remote)
+	if changed && len(replica.Head) != 0 {
+		replica.merged = replica.Head
+	}
+	replica.Head = head
+	return changed, nil
+}
+
+// commit will record the changes made to the DB since it was last built in the replicated state
+func (replica *Replica) commit(ctx context.Context) error {
+	if sameContent(replica.built, replica.DB) {
+		return nil
+	}
+	return replica.state.record(ctx, replica.node, replica.built, replica.DB, replica.state.next())
+}
+
+// build will rebuild the DB from the replicated state, returning true if it has changed
+func (replica *Replica) build(ctx context.Context) (bool, error) {
+	before := proto.Clone(replica.DB).(*ProjectDatabase)
+	replica.state.Pin = replica.DB.GetPin()
+	if err := replica.state.build(ctx, replica.node, replica.DB); err != nil {
+		return false, err
+	}
+	replica.built = proto.Clone(replica.DB).(*ProjectDatabase)
+	return !sameContent(before, replica.DB), nil
+}
+
+// Push will push the replica's database to the IPFS as a new version and update the Head
+//
+// The version is summarised by the changes applied since the last pull or push, and links to
+// the replicated state of the database so that other replicas can merge it.
+func (replica *Replica) Push(ctx context.Context) (string, error) {
+	if err := replica.commit(ctx); err != nil {
+		return "", err
+	}
+	if _, err := replica.build(ctx); err != nil {
+		return "", err
+	}
+	stateCID, err := replica.state.Push(ctx, replica.node)
+	if err != nil {
+		return "", err
+	}
+	replica.DB.StateCID = stateCID
+	summary := strings.Join(replica.changes, "; ")
+	if len(summary) == 0 {
+		summary = "no changes"
+	}
+	cid, err := replica.DB.pushVersion(ctx, replica.node, &DatabaseVersion{
+		ParentCID: replica.Head,
+		MergedCID: replica.merged,
+		Summary:   summary,
+	})
+	if err != nil {
+		return "", err
+	}
+	replica.built = proto.Clone(replica.DB).(*ProjectDatabase)
+	replica.Head, replica.changes, replica.merged = cid, nil, ""
+	return cid, nil
+}
+
+// project will get a project from the replica's database, creating it if needed
+func (replica *Replica) project(label string) *Project {
+	project, err := replica.DB.GetProject(label)
+	if err != nil {
+		project = InitProject(label)
+		replica.DB.Projects[label] = project
+	}
+	if project.Runs == nil {
+		project.Runs = make(map[string]string)
+	}
+	return project
+}
+
+// joinProject will record a registered project in the replicated state
+//
+// Runs, libraries and samples recorded for the project before it was registered are kept.
+func (replica *Replica) joinProject(ctx context.Context, registered *ProjectRegistered, stamp crdtStamp) error {
+	project, err := replica.DB.GetProject(registered.GetLabel())
+	if err == nil && project.GetId() == registered.GetId() {
+		return nil
+	}
+	if err == nil && len(project.GetId()) != 0 {
+		return fmt.Errorf("project %v is already registered with a different ID (%v)", registered.GetLabel(), project.GetId())
+	}
+	joined := &Project{}
+	if err := getProjectNode(ctx, replica.node, registered.GetCID(), joined); err != nil {
+		return err
+	}
+	joined.Label = registered.GetLabel()
+	return replica.state.putProject(ctx, replica.node, joined, nil, stamp)
+}
+
+// setRun will record the CID of a run in a project, returning true if it has changed
+func (replica *Replica) setRun(projectLabel, runLabel, cid string) bool {
+	project := replica.project(projectLabel)
+	if project.Runs[runLabel] == cid {
+		return false
+	}
+	project.Runs[runLabel] = cid
+	return true
+}
+
+// sameContent reports whether two databases hold the same projects, regardless of their versions, states and project CIDs
+func sameContent(a, b *ProjectDatabase) bool {
+	a, b = proto.Clone(a).(*ProjectDatabase), proto.Clone(b).(*ProjectDatabase)
+	a.Version, b.Version = nil, nil
+	a.StateCID, b.StateCID = "", ""
+	for _, db := range []*ProjectDatabase{a, b} {
+		for _, project := range db.GetProjects() {
+			project.CID = ""
+		}
+	}
+	return proto.Equal(a, b)
+}
@@ -0,0 +1,338 @@
+package records
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestReplica
+func TestReplica(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 2, func(t *testing.T, nodes []*backend.Node) {
+
+		// store a tagged run and create replicas of a database on both nodes
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.Tags["basecall"] = NewTagState()
+		runCID, err := putRecord(ctx, nodes[0], run, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := InitDB()
+		if err := db.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		head, err := db.Push(ctx, nodes[0], "", "added test project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		replicas := make([]*Replica, len(nodes))
+		for i, node := range nodes {
+			if replicas[i], err = NewReplica(ctx, node, head); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// apply the same events to both replicas
+		created := NewMessage(projectLabel)
+		created.Payload = &Message_RunCreated{RunCreated: &RunCreated{Label: runLabel, CID: runCID}}
+		tagged := NewMessage(projectLabel)
+		tagged.Payload = &Message_TagCompleted{TagCompleted: &TagCompleted{RunLabel: runLabel, Tag: "basecall"}}
+		for _, replica := range replicas {
+			for _, msg := range []*Message{created, tagged} {
+				changed, err := replica.Apply(ctx, msg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !changed {
+					t.Fatalf("event did not change the database: %v", msg.Describe())
+				}
+			}
+
+			// applying an event twice should not change anything, and tags can't be added by completing them
+			if changed, err := replica.Apply(ctx, tagged); err != nil || changed {
+				t.Fatalf("repeated event changed the database (%v)", err)
+			}
+			injected := NewMessage(projectLabel)
+			injected.Payload = &Message_TagCompleted{TagCompleted: &TagCompleted{RunLabel: runLabel, Tag: "injected"}}
+			if changed, err := replica.Apply(ctx, injected); err == nil || changed {
+				t.Fatal("completion for a tag the run doesn't have was applied")
+			}
+		}
+
+		// check the replicas converge
+		heads := make([]string, len(replicas))
+		for i, replica := range replicas {
+			if heads[i], err = replica.Push(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !sameContent(replicas[0].DB, replicas[1].DB) || replicas[0].DB.GetStateCID() != replicas[1].DB.GetStateCID() {
+			t.Fatal("replicas did not converge")
+		}
+		for _, newHead := range heads {
+			history, err := History(ctx, nodes[0], newHead, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 2 || history[1].CID != head || !strings.HasPrefix(history[0].Version.GetSummary(), "run created") {
+				t.Fatalf("new head is not linked to the original: %v", history)
+			}
+		}
+
+		// check the tag was recorded on the stored run
+		project, err := replicas[1].DB.GetProject(projectLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagRun := &Run{}
+		if err := getRecord(ctx, nodes[1], project.GetRuns()[runLabel], tagRun); err != nil {
+			t.Fatal(err)
+		}
+		if !tagRun.GetTags()["basecall"].IsDone() || tagRun.GetLabel() != runLabel {
+			t.Fatalf("tag not recorded on run: %v", tagRun)
+		}
+
+		// a new head should replace the database
+		replica, err := NewReplica(ctx, nodes[1], head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headChanged := NewMessage(projectLabel)
+		headChanged.Payload = &Message_DatabaseHeadChanged{DatabaseHeadChanged: &DatabaseHeadChanged{CID: heads[0], PreviousCID: head}}
+		if _, err := replica.Apply(ctx, headChanged); err != nil {
+			t.Fatal(err)
+		}
+		if replica.Head != heads[0] {
+			t.Fatalf("replica head not updated: %v", replica.Head)
+		}
+		if project, err := replica.DB.GetProject(projectLabel); err != nil || project.GetRuns()[runLabel] == "" {
+			t.Fatal("new head was not pulled")
+		}
+	})
+}
+
+// TestReplicaConvergence
+func TestReplicaConvergence(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 2, func(t *testing.T, nodes []*backend.Node) {
+		db := InitDB()
+		if err := db.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		head, err := db.Push(ctx, nodes[0], "", "added test project")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// store a tagged run and an update to it, and announce them with a completion, a library and another run
+		store := func(record proto.Message) string {
+			cid, err := putRecord(ctx, nodes[0], record, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cid
+		}
+		message := func(sender string, payload isMessage_Payload) *Message {
+			msg := NewMessage(projectLabel)
+			msg.Sender, msg.Payload = sender, payload
+			return msg
+		}
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.ParentProjectLabel = projectLabel
+		run.Tags["basecall"] = NewTagState()
+		runCID := store(run)
+		run.Tags["basecall"].Request()
+		run.OutputDirectory = "updated output directory"
+		updatedCID := store(run)
+		otherCID := store(InitRun("other run", outputDir, fast5Dir, fastqDir))
+		libraryCID := store(InitLibrary("library 1", "prep kit", "barcode kit", "flowcell"))
+		msgs := []*Message{
+			message("node a", &Message_RunCreated{RunCreated: &RunCreated{Label: runLabel, CID: runCID}}),
+			message("node a", &Message_RunUpdated{RunUpdated: &RunUpdated{Label: runLabel, CID: updatedCID, PreviousCID: runCID}}),
+			message("node b", &Message_TagCompleted{TagCompleted: &TagCompleted{RunLabel: runLabel, Tag: "basecall", ResultCID: "result"}}),
+			message("node c", &Message_LibraryUpdated{LibraryUpdated: &LibraryUpdated{Label: "library 1", CID: libraryCID}}),
+			message("node c", &Message_RunCreated{RunCreated: &RunCreated{Label: "other run", CID: otherCID}}),
+		}
+
+		// apply the messages to replicas in different orders (completions need the run to be created first)
+		orders := [][]int{{0, 1, 2, 3, 4}, {4, 3, 0, 2, 1}, {0, 2, 4, 1, 3}, {3, 0, 2, 1, 4, 0}}
+		replicas := make([]*Replica, len(orders))
+		for i, order := range orders {
+			if replicas[i], err = NewReplica(ctx, nodes[i%len(nodes)], head); err != nil {
+				t.Fatal(err)
+			}
+			for _, j := range order {
+				if _, err := replicas[i].Apply(ctx, msgs[j]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := replicas[i].Push(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, replica := range replicas[1:] {
+			if replica.DB.GetStateCID() != replicas[0].DB.GetStateCID() || !sameContent(replica.DB, replicas[0].DB) {
+				t.Fatal("replicas did not converge")
+			}
+		}
+		project := replicas[0].DB.Projects[projectLabel]
+		merged := &Run{}
+		if err := getRecord(ctx, nodes[0], project.GetRuns()[runLabel], merged); err != nil {
+			t.Fatal(err)
+		}
+		if merged.GetOutputDirectory() != "updated output directory" || !merged.GetTags()["basecall"].IsDone() || merged.GetTags()["basecall"].GetResultCID() != "result" {
+			t.Fatalf("events not applied to the run: %v", merged)
+		}
+		if project.GetRuns()["other run"] != otherCID || project.GetLibraries()["library 1"] != libraryCID {
+			t.Fatalf("events not applied to the project: %v", project)
+		}
+
+		// make concurrent changes on two replicas, then exchange their heads and check they converge
+		replicas[0].setRun(projectLabel, "other run", store(InitRun("other run", "another output directory", fast5Dir, fastqDir)))
+		sampleCID := store(InitSample("sample 1", 1))
+		replicas[1].DB.Projects[projectLabel].Samples = map[string]string{"sample 1": sampleCID}
+		heads := make([]string, 2)
+		for i := range heads {
+			if heads[i], err = replicas[i].Push(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := range heads {
+			headChanged := message("", &Message_DatabaseHeadChanged{DatabaseHeadChanged: &DatabaseHeadChanged{CID: heads[1-i]}})
+			if changed, err := replicas[i].Apply(ctx, headChanged); err != nil || !changed {
+				t.Fatalf("concurrent changes not merged (%v)", err)
+			}
+			if _, err := replicas[i].Push(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if replicas[0].DB.GetStateCID() != replicas[1].DB.GetStateCID() || !sameContent(replicas[0].DB, replicas[1].DB) {
+			t.Fatal("replicas did not converge after exchanging heads")
+		}
+		pulled, err := NewReplica(ctx, nodes[1], replicas[0].Head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pulled.DB.GetStateCID() != replicas[0].DB.GetStateCID() {
+			t.Fatal("replicated state not pushed with the database")
+		}
+		if replicas[0].DB.Projects[projectLabel].GetSamples()["sample 1"] != sampleCID || replicas[1].DB.Projects[projectLabel].GetRuns()["other run"] == otherCID {
+			t.Fatalf("concurrent changes not kept: %v", replicas[1].DB.Projects[projectLabel])
+		}
+	})
+}
+
+// TestReplicaRevert
+func TestReplicaRevert(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 2, func(t *testing.T, nodes []*backend.Node) {
+
+		// push a project and then a run on the first node, and replicate it on the second
+		db := InitDB()
+		if err := db.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		target, err := db.Push(ctx, nodes[0], "", "added test project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		runCID, err := putRecord(ctx, nodes[0], InitRun(runLabel, outputDir, fast5Dir, fastqDir), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Projects[projectLabel].Runs = map[string]string{runLabel: runCID}
+		head, err := db.Push(ctx, nodes[0], target, "added test run")
+		if err != nil {
+			t.Fatal(err)
+		}
+		replica, err := NewReplica(ctx, nodes[1], head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending, err := NewReplica(ctx, nodes[1], head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherCID, err := putRecord(ctx, nodes[1], InitRun("other run", outputDir, fast5Dir, fastqDir), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		created := NewMessage(projectLabel)
+		created.Payload = &Message_RunCreated{RunCreated: &RunCreated{Label: "other run", CID: otherCID}}
+		if _, err := pending.Apply(ctx, created); err != nil {
+			t.Fatal(err)
+		}
+
+		// revert the first node and check the run is removed from the replica
+		_, reverted, err := Revert(ctx, nodes[0], head, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headChanged := NewMessage(projectLabel)
+		headChanged.Payload = &Message_DatabaseHeadChanged{DatabaseHeadChanged: &DatabaseHeadChanged{CID: reverted, PreviousCID: head}}
+		changed, err := replica.Apply(ctx, headChanged)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed || replica.Head != reverted {
+			t.Fatalf("revert was not adopted (changed: %v, head: %v)", changed, replica.Head)
+		}
+		if _, ok := replica.DB.Projects[projectLabel].GetRuns()[runLabel]; ok {
+			t.Fatal("reverted run is still in the replica")
+		}
+
+		// a replica with local changes should keep them, but still remove the reverted run
+		if _, err := pending.Apply(ctx, headChanged); err != nil {
+			t.Fatal(err)
+		}
+		runs := pending.DB.Projects[projectLabel].GetRuns()
+		if _, ok := runs[runLabel]; ok || runs["other run"] != otherCID {
+			t.Fatalf("revert not merged with local changes: %v", runs)
+		}
+	})
+}
+
+// TestReplicaJoin
+func TestReplicaJoin(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		replica, err := NewReplica(ctx, nodes[0], "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// record a run, library and sample for a project before it is registered
+		project := replica.project(projectLabel)
+		project.Libraries = map[string]string{"library 1": "libraryCID"}
+		project.Samples = map[string]string{"sample 1": "sampleCID"}
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.ParentProjectLabel = projectLabel
+		runCID, err := putRecord(ctx, nodes[0], run, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replica.setRun(projectLabel, runLabel, runCID)
+
+		// join the registered project and check the local records are kept
+		registered := InitProject(projectLabel)
+		registered.Id = "project ID"
+		cid, err := putProjectNode(ctx, nodes[0], registered, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := NewMessage(projectLabel)
+		msg.Payload = &Message_ProjectRegistered{ProjectRegistered: &ProjectRegistered{Label: projectLabel, Id: registered.Id, CID: cid}}
+		if changed, err := replica.Apply(ctx, msg); err != nil || !changed {
+			t.Fatalf("project not joined (%v)", err)
+		}
+		joined := replica.DB.Projects[projectLabel]
+		if joined.GetId() != registered.Id || joined.GetRuns()[runLabel] != runCID || joined.GetLibraries()["library 1"] != "libraryCID" || joined.GetSamples()["sample 1"] != "sampleCID" {
+			t.Fatalf("local records not kept when joining: %v", joined)
+		}
+	})
+}
@@ -2,9 +2,24 @@
 package records
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/golang/protobuf/ptypes"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+var (
+	// ErrOrphanRun is issued when a run without a parent project is synced
+	ErrOrphanRun = errors.New("orphan run can't be synced - needs a parent project")
+
+	// ErrNotAnnounced is issued when a change has been pushed but could not be announced
+	//
+	// Announcements are skipped without an error if the node is offline. A function returning
+	// ErrNotAnnounced has still made its change, and returns the new CID if there is one.
+	ErrNotAnnounced = errors.New("change was pushed but not announced")
 )
 
 // InitRun will init a run struct with the minimum required values
@@ -15,8 +30,8 @@ func InitRun(label, outputDir, fast5Dir, fastqDir string) *Run {
 		Created:              ptypes.TimestampNow(),
 		Label:                label,
 		History:              []*Comment{},
-		Status:               1,
-		Tags:                 make(map[string]bool),
+		Status:               Status_untagged,
+		Tags:                 make(map[string]*TagState),
 		RequestOrder:         []string{},
 		OutputDirectory:      outputDir,
 		Fast5OutputDirectory: fast5Dir,
@@ -43,15 +58,56 @@ func (run *Run) AddComment(text string) error {
 	return nil
 }
 
-// Sync will store a run in the IPFS and update the parent project's record
-func (run *Run) Sync() error {
+// Sync will store a run in the IPFS, register it on its parent project and push the database as a new version
+//
+// The parent project, and the run's library if it has one, must be in the database. The CID of the new database version is returned,
+// and the change is announced to the parent project as a RunCreated or RunUpdated message. An
+// unchanged run is not pushed or announced.
+func (run *Run) Sync(ctx context.Context, node *backend.Node, db *ProjectDatabase, parentCID string) (string, error) {
 
-	/*
-		// check there is a registered parent project for this run
-		if len(run.GetParentProjectCID()) == 0 {
-			return fmt.Errorf("orphan run can't be synced - needs a parent project")
-		}
-	*/
+	// check there is a registered parent project for this run
+	if len(run.GetParentProjectLabel()) == 0 {
+		return "", ErrOrphanRun
+	}
+	project, err := db.GetProject(run.GetParentProjectLabel())
+	if err != nil {
+		return "", fmt.Errorf("parent project not found for run %v: %w", run.GetLabel(), err)
+	}
+	if _, ok := project.GetLibraries()[run.GetLibrary()]; len(run.GetLibrary()) != 0 && !ok {
+		return "", fmt.Errorf("library not found in %v for run %v: %v", project.GetLabel(), run.GetLabel(), run.GetLibrary())
+	}
 
-	return nil
+	// store the run and record it on the project
+	// the parent CID is only set when the run is first registered, as the project changes with each run
+	previousCID := project.GetRuns()[run.GetLabel()]
+	if len(run.GetParentProjectCID()) == 0 {
+		run.ParentProjectCID = project.GetCID()
+	}
+	cid, err := putRecord(ctx, node, run, db.GetPin())
+	if err != nil {
+		return "", err
+	}
+	if cid == previousCID {
+		return parentCID, nil
+	}
+	if project.Runs == nil {
+		project.Runs = make(map[string]string)
+	}
+	project.Runs[run.GetLabel()] = cid
+
+	// push the project and database, then announce the change
+	msg := NewMessage(project.GetLabel())
+	if len(previousCID) == 0 {
+		msg.Payload = &Message_RunCreated{RunCreated: &RunCreated{Label: run.GetLabel(), CID: cid}}
+	} else {
+		msg.Payload = &Message_RunUpdated{RunUpdated: &RunUpdated{Label: run.GetLabel(), CID: cid, PreviousCID: previousCID}}
+	}
+	dbCID, err := db.Push(ctx, node, parentCID, msg.Describe())
+	if err != nil {
+		return "", err
+	}
+	if err := Announce(ctx, node, msg); err != nil && !errors.Is(err, backend.ErrOffline) {
+		return dbCID, fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+	}
+	return dbCID, nil
 }
@@ -1,7 +1,11 @@
 package records
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/will-rowe/scribe/src/backend"
 )
 
 var (
@@ -13,19 +17,84 @@ var (
 
 // TestSync
 func TestSync(t *testing.T) {
-	run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+	ctx := context.Background()
+	backend.WithMemory(t, 2, func(t *testing.T, nodes []*backend.Node) {
+		db := InitDB()
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+
+		// make sure you can't sync an orphan run
+		if _, err := run.Sync(ctx, nodes[0], db, ""); err != ErrOrphanRun {
+			t.Fatalf("orphan run was synced (%v)", err)
+		}
+
+		// or a run with a parent project that isn't in the database
+		run.ParentProjectLabel = projectLabel
+		if _, err := run.Sync(ctx, nodes[0], db, ""); err == nil {
+			t.Fatal("run was synced without its parent project")
+		}
+
+		// add the parent project and listen for announcements
+		if err := db.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		head, err := db.Push(ctx, nodes[0], "", "added test project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub, err := nodes[1].Subscribe(ctx, projectLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receive := func() *Message {
+			select {
+			case received := <-sub.Messages():
+				msg, err := DecodeMessage(received.Data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return msg
+			case <-time.After(5 * time.Second):
+				t.Fatal("no announcement received")
+			}
+			return nil
+		}
 
-	// make sure you can't sync an orphan run
-	//if err := run.Sync(); err == nil {
-	//	t.Fatal("orphan run was synced")
-	//}
+		// try syncing again
+		created, err := run.Sync(ctx, nodes[0], db, head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runCID := db.Projects[projectLabel].GetRuns()[runLabel]
+		if msg := receive(); msg.GetRunCreated().GetCID() != runCID || msg.GetProject() != projectLabel {
+			t.Fatalf("unexpected announcement: %v", msg.Describe())
+		}
+		if run.GetParentProjectCID() == "" {
+			t.Fatal("parent project CID not recorded on the run")
+		}
 
-	// add a parent project
-	run.ParentProjectCID = projectCID
+		// check the run is registered on the project in the new database version
+		var label string
+		if err := nodes[1].DagGet(ctx, created, "projects/"+projectLabel+"/runs/"+runLabel+"/label", &label); err != nil || label != runLabel {
+			t.Fatalf("run not registered on the project: %v (%v)", label, err)
+		}
+		if history, err := History(ctx, nodes[1], created, 0); err != nil || len(history) != 2 || history[1].CID != head {
+			t.Fatalf("new version not linked to the previous one: %v (%v)", history, err)
+		}
 
-	// try syncing again
-	if err := run.Sync(); err != nil {
-		t.Fatal(err)
-	}
+		// an unchanged run should not be pushed again
+		if unchanged, err := run.Sync(ctx, nodes[0], db, created); err != nil || unchanged != created {
+			t.Fatalf("unchanged run was pushed (%v)", err)
+		}
 
+		// an updated run should be announced as an update
+		if err := run.AddComment("basecalling started"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := run.Sync(ctx, nodes[0], db, created); err != nil {
+			t.Fatal(err)
+		}
+		if msg := receive(); msg.GetRunUpdated().GetPreviousCID() != runCID {
+			t.Fatalf("unexpected announcement: %v", msg.Describe())
+		}
+	})
 }
@@ -0,0 +1,257 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+var (
+	// ErrOrphanSample is issued when a sample without a parent project is synced
+	ErrOrphanSample = errors.New("orphan sample can't be synced - needs a parent project")
+
+	// ErrBarcodeInUse is issued when a sample has the same barcode as another sample in its run or library
+	ErrBarcodeInUse = errors.New("barcode is already used in the run or library")
+
+	// ErrBarcodeRange is issued when a sample barcode is not in the barcode kit of its library
+	ErrBarcodeRange = errors.New("barcode is not in the barcode kit")
+)
+
+// InitSample will init a sample struct with the minimum required values
+func InitSample(label string, barcode int32) *Sample {
+
+	// create the sample
+	sample := &Sample{
+		Created:      ptypes.TimestampNow(),
+		Label:        label,
+		History:      []*Comment{},
+		Status:       Status_untagged,
+		Tags:         make(map[string]*TagState),
+		RequestOrder: []string{},
+		Barcode:      barcode,
+	}
+
+	// create the history
+	sample.AddComment("sample created.")
+
+	// return pointer to the sample
+	return sample
+}
+
+// AddComment adds a comment to the sample history
+func (sample *Sample) AddComment(text string) error {
+	if len(text) == 0 {
+		return fmt.Errorf("no comment provided")
+	}
+	comment := &Comment{
+		Timestamp: ptypes.TimestampNow(),
+		Text:      text,
+	}
+	sample.History = append(sample.History, comment)
+	return nil
+}
+
+// Validate will check the sample has a label and a valid barcode
+func (sample *Sample) Validate() error {
+	switch {
+	case len(sample.GetLabel()) == 0:
+		return fmt.Errorf("sample has no label")
+	case sample.GetBarcode() < 0:
+		return fmt.Errorf("sample barcode can't be negative (%v)", sample.GetLabel())
+	}
+	return nil
+}
+
+// Sync will store a sample in the IPFS, register it on its parent project and push the database as a new version
+//
+// If the sample has a parent run (ParentExperiment), the run must be in the project and the sample
+// is attached to it. The sample's libraries are those in the project that list it, along with the
+// run's library. The barcode must be unique within the run and each library (including the samples
+// of the runs linked to the library) and, if a library has
+// a barcode kit, within the range of the kit; an unbarcoded library takes barcode 0. A sample
+// can't be moved to another run once it has been synced.
+//
+// The CID of the new database version is returned, or the parent CID if nothing has changed. A
+// change to the run is announced as a RunUpdated message.
+func (sample *Sample) Sync(ctx context.Context, node *backend.Node, db *ProjectDatabase, parentCID string) (string, error) {
+
+	// check the sample and its parent project
+	if len(sample.GetParentProjectLabel()) == 0 {
+		return "", ErrOrphanSample
+	}
+	if err := sample.Validate(); err != nil {
+		return "", err
+	}
+	project, err := db.GetProject(sample.GetParentProjectLabel())
+	if err != nil {
+		return "", fmt.Errorf("parent project not found for sample %v: %w", sample.GetLabel(), err)
+	}
+	previousCID := project.GetSamples()[sample.GetLabel()]
+	if len(previousCID) != 0 {
+		previous := &Sample{}
+		if err := getRecord(ctx, node, previousCID, previous); err != nil {
+			return "", err
+		}
+		if previous.GetParentExperiment() != sample.GetParentExperiment() {
+			return "", fmt.Errorf("sample %v can't be moved from run %v", sample.GetLabel(), previous.GetParentExperiment())
+		}
+	}
+
+	// check the sample against the run it is sequenced in and its libraries
+	var run *Run
+	runCID := project.GetRuns()[sample.GetParentExperiment()]
+	if len(sample.GetParentExperiment()) != 0 {
+		if len(runCID) == 0 {
+			return "", fmt.Errorf("run not found in %v for sample %v: %v", project.GetLabel(), sample.GetLabel(), sample.GetParentExperiment())
+		}
+		run = &Run{}
+		if err := getRecord(ctx, node, runCID, run); err != nil {
+			return "", err
+		}
+	}
+	if err := checkBarcode(ctx, node, project, run, sample); err != nil {
+		return "", err
+	}
+
+	// store the sample and record it on the project
+	cid, err := putRecord(ctx, node, sample, db.GetPin())
+	if err != nil {
+		return "", err
+	}
+	attach := run != nil && !containsString(run.GetSamples(), sample.GetLabel())
+	if cid == previousCID && !attach {
+		return parentCID, nil
+	}
+	if project.Samples == nil {
+		project.Samples = make(map[string]string)
+	}
+	project.Samples[sample.GetLabel()] = cid
+	summary := fmt.Sprintf("sample created: %v (%v)", sample.GetLabel(), cid)
+	if len(previousCID) != 0 {
+		summary = fmt.Sprintf("sample updated: %v (%v -> %v)", sample.GetLabel(), previousCID, cid)
+	}
+
+	// attach the sample to the run
+	var msg *Message
+	if attach {
+		run.Samples = append(run.Samples, sample.GetLabel())
+		updatedCID, err := putRecord(ctx, node, run, db.GetPin())
+		if err != nil {
+			return "", err
+		}
+		project.Runs[run.GetLabel()] = updatedCID
+		msg = NewMessage(project.GetLabel())
+		msg.Payload = &Message_RunUpdated{RunUpdated: &RunUpdated{Label: run.GetLabel(), CID: updatedCID, PreviousCID: runCID}}
+	}
+
+	// push the database, then announce any change to the run
+	dbCID, err := db.Push(ctx, node, parentCID, summary)
+	if err != nil {
+		return "", err
+	}
+	if msg == nil {
+		return dbCID, nil
+	}
+	if err := Announce(ctx, node, msg); err != nil && !errors.Is(err, backend.ErrOffline) {
+		return dbCID, fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+	}
+	return dbCID, nil
+}
+
+// checkBarcode will check a sample barcode is unique within its run (if it has one) and libraries, and in the range of each library's barcode kit
+func checkBarcode(ctx context.Context, node *backend.Node, project *Project, run *Run, sample *Sample) error {
+	libraries, err := sampleLibraries(ctx, node, project, run, sample)
+	if err != nil {
+		return err
+	}
+	for _, library := range libraries {
+		if err := checkBarcodeKit(library, sample); err != nil {
+			return err
+		}
+		samples, err := librarySamples(ctx, node, project, library)
+		if err != nil {
+			return err
+		}
+		if err := checkBarcodeUnique(ctx, node, project, samples, sample); err != nil {
+			return err
+		}
+	}
+	if run == nil {
+		return nil
+	}
+	return checkBarcodeUnique(ctx, node, project, run.GetSamples(), sample)
+}
+
+// sampleLibraries will get the libraries in a project that list a sample, along with the library of the sample's run
+func sampleLibraries(ctx context.Context, node *backend.Node, project *Project, run *Run, sample *Sample) ([]*Library, error) {
+	if len(run.GetLibrary()) != 0 {
+		if _, ok := project.GetLibraries()[run.GetLibrary()]; !ok {
+			return nil, fmt.Errorf("library not found in %v for run %v: %v", project.GetLabel(), run.GetLabel(), run.GetLibrary())
+		}
+	}
+	libraries := []*Library{}
+	for _, label := range sortedKeys(project.GetLibraries()) {
+		library := &Library{}
+		if err := getRecord(ctx, node, project.GetLibraries()[label], library); err != nil {
+			return nil, err
+		}
+		if label == run.GetLibrary() || containsString(library.GetSamples(), sample.GetLabel()) {
+			libraries = append(libraries, library)
+		}
+	}
+	return libraries, nil
+}
+
+// librarySamples will get the labels of the samples in a library, which are those it lists along with the samples of the runs linked to it
+func librarySamples(ctx context.Context, node *backend.Node, project *Project, library *Library) ([]string, error) {
+	samples := append([]string{}, library.GetSamples()...)
+	for _, label := range sortedKeys(project.GetRuns()) {
+		run := &Run{}
+		if err := getRecord(ctx, node, project.GetRuns()[label], run); err != nil {
+			return nil, err
+		}
+		if run.GetLibrary() != library.GetLabel() {
+			continue
+		}
+		for _, sample := range run.GetSamples() {
+			if !containsString(samples, sample) {
+				samples = append(samples, sample)
+			}
+		}
+	}
+	return samples, nil
+}
+
+// checkBarcodeKit will check a sample barcode is in the range of a library's barcode kit, or 0 if the library is not barcoded
+func checkBarcodeKit(library *Library, sample *Sample) error {
+	barcodes, barcoded := BarcodeKits[library.GetBarcodeKit()]
+	switch {
+	case barcoded && (sample.GetBarcode() < 1 || sample.GetBarcode() > barcodes):
+		return fmt.Errorf("%w: %d (%v has barcodes 1-%d)", ErrBarcodeRange, sample.GetBarcode(), library.GetBarcodeKit(), barcodes)
+	case !barcoded && sample.GetBarcode() != 0:
+		return fmt.Errorf("%w: %d (library %v is not barcoded)", ErrBarcodeRange, sample.GetBarcode(), library.GetLabel())
+	}
+	return nil
+}
+
+// checkBarcodeUnique will check a sample barcode isn't used by any of the other samples in a list
+func checkBarcodeUnique(ctx context.Context, node *backend.Node, project *Project, labels []string, sample *Sample) error {
+	for _, label := range labels {
+		if label == sample.GetLabel() {
+			continue
+		}
+		other := &Sample{}
+		if err := getRecord(ctx, node, project.GetSamples()[label], other); err != nil {
+			return err
+		}
+		if other.GetBarcode() == sample.GetBarcode() {
+			return fmt.Errorf("%w: %d (sample %v)", ErrBarcodeInUse, sample.GetBarcode(), label)
+		}
+	}
+	return nil
+}
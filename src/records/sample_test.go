@@ -0,0 +1,123 @@
+package records
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestSample
+func TestSample(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+
+		// set up a project with a barcoded library and a run
+		db := InitDB()
+		if err := db.AddProject(InitProject(projectLabel)); err != nil {
+			t.Fatal(err)
+		}
+		library := InitLibrary(libraryLabel, prepKit, barcodeKit, flowcellType)
+		library.ParentProjectLabel = projectLabel
+		head, err := library.Sync(ctx, node, db, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		run.ParentProjectLabel = projectLabel
+		run.Library = libraryLabel
+		if head, err = run.Sync(ctx, node, db, head); err != nil {
+			t.Fatal(err)
+		}
+
+		// make sure you can't sync an orphan sample
+		sample := InitSample("sample 1", 1)
+		if _, err := sample.Sync(ctx, node, db, head); err != ErrOrphanSample {
+			t.Fatalf("orphan sample was synced (%v)", err)
+		}
+		sample.ParentProjectLabel = projectLabel
+		sample.ParentExperiment = "missing run"
+		if _, err := sample.Sync(ctx, node, db, head); err == nil {
+			t.Fatal("sample was synced to a missing run")
+		}
+
+		// check the barcode is in the range of the barcode kit
+		sample.ParentExperiment = runLabel
+		sample.Barcode = BarcodeKits[barcodeKit] + 1
+		if _, err := sample.Sync(ctx, node, db, head); !errors.Is(err, ErrBarcodeRange) {
+			t.Fatalf("barcode out of range was synced (%v)", err)
+		}
+
+		// attach the sample to the run
+		sample.Barcode = 1
+		if head, err = sample.Sync(ctx, node, db, head); err != nil {
+			t.Fatal(err)
+		}
+		if unchanged, err := sample.Sync(ctx, node, db, head); err != nil || unchanged != head {
+			t.Fatalf("unchanged sample was pushed (%v)", err)
+		}
+
+		// check barcodes are unique within the run
+		sample2 := InitSample("sample 2", 1)
+		sample2.ParentProjectLabel = projectLabel
+		sample2.ParentExperiment = runLabel
+		if _, err := sample2.Sync(ctx, node, db, head); !errors.Is(err, ErrBarcodeInUse) {
+			t.Fatalf("repeated barcode was synced (%v)", err)
+		}
+		sample2.Barcode = 2
+		if head, err = sample2.Sync(ctx, node, db, head); err != nil {
+			t.Fatal(err)
+		}
+		attached := &Run{}
+		if err := getRecord(ctx, node, db.Projects[projectLabel].GetRuns()[runLabel], attached); err != nil {
+			t.Fatal(err)
+		}
+		if len(attached.GetSamples()) != 2 {
+			t.Fatalf("samples not attached to the run: %v", attached.GetSamples())
+		}
+		var barcode int32
+		if err := node.DagGet(ctx, head, "projects/"+projectLabel+"/samples/sample 2/barcode", &barcode); err != nil || barcode != 2 {
+			t.Fatalf("could not get sample by path: %v (%v)", barcode, err)
+		}
+
+		// samples without a run are checked against the libraries that list them
+		unsequenced := InitSample("sample 3", 1)
+		unsequenced.ParentProjectLabel = projectLabel
+		if head, err = unsequenced.Sync(ctx, node, db, head); err != nil {
+			t.Fatal(err)
+		}
+		library.Samples = []string{"sample 1", "sample 3"}
+		if _, err := library.Sync(ctx, node, db, head); !errors.Is(err, ErrBarcodeInUse) {
+			t.Fatalf("library was synced with a repeated barcode (%v)", err)
+		}
+
+		// the samples of the runs linked to a library are in the library too
+		library.Samples = []string{"sample 3"}
+		if _, err := library.Sync(ctx, node, db, head); !errors.Is(err, ErrBarcodeInUse) {
+			t.Fatalf("library was synced with the barcode of a run sample (%v)", err)
+		}
+		unsequenced.Barcode = 3
+		if head, err = unsequenced.Sync(ctx, node, db, head); err != nil {
+			t.Fatal(err)
+		}
+		if head, err = library.Sync(ctx, node, db, head); err != nil {
+			t.Fatal(err)
+		}
+		unsequenced.Barcode = 2
+		if _, err := unsequenced.Sync(ctx, node, db, head); !errors.Is(err, ErrBarcodeInUse) {
+			t.Fatalf("sample was synced with the barcode of a run sample in its library (%v)", err)
+		}
+		unsequenced.Barcode = BarcodeKits[barcodeKit] + 1
+		if _, err := unsequenced.Sync(ctx, node, db, head); !errors.Is(err, ErrBarcodeRange) {
+			t.Fatalf("barcode out of range was synced without a run (%v)", err)
+		}
+
+		// samples can't move between runs
+		sample.ParentExperiment = ""
+		if _, err := sample.Sync(ctx, node, db, head); err == nil {
+			t.Fatal("sample was moved from its run")
+		}
+	})
+}
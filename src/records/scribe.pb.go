@@ -23,6 +23,8 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 //
 //Status is used to determine if experiments/samples have tagged processes, or if they have been announced via the message server
+//
+// Statuses are ordered by how far a record has progressed, so that merges can keep the most advanced.
 type Status int32
 
 const (
@@ -30,6 +32,9 @@ const (
 	Status_untagged       Status = 1
 	Status_tagged         Status = 2
 	Status_announced      Status = 3
+	Status_complete       Status = 4
+	Status_failed         Status = 5
+	Status_archived       Status = 6
 )
 
 var Status_name = map[int32]string{
@@ -37,6 +42,9 @@ var Status_name = map[int32]string{
 	1: "untagged",
 	2: "tagged",
 	3: "announced",
+	4: "complete",
+	5: "failed",
+	6: "archived",
 }
 
 var Status_value = map[string]int32{
@@ -44,6 +52,9 @@ var Status_value = map[string]int32{
 	"untagged":       1,
 	"tagged":         2,
 	"announced":      3,
+	"complete":       4,
+	"failed":         5,
+	"archived":       6,
 }
 
 func (x Status) String() string {
@@ -54,6 +65,43 @@ func (Status) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_df7aaa7859039b55, []int{0}
 }
 
+type TagState_State int32
+
+const (
+	TagState_pending   TagState_State = 0
+	TagState_requested TagState_State = 1
+	TagState_running   TagState_State = 2
+	TagState_failed    TagState_State = 3
+	TagState_skipped   TagState_State = 4
+	TagState_succeeded TagState_State = 5
+)
+
+var TagState_State_name = map[int32]string{
+	0: "pending",
+	1: "requested",
+	2: "running",
+	3: "failed",
+	4: "skipped",
+	5: "succeeded",
+}
+
+var TagState_State_value = map[string]int32{
+	"pending":   0,
+	"requested": 1,
+	"running":   2,
+	"failed":    3,
+	"skipped":   4,
+	"succeeded": 5,
+}
+
+func (x TagState_State) String() string {
+	return proto.EnumName(TagState_State_name, int32(x))
+}
+
+func (TagState_State) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{7, 0}
+}
+
 //
 //Comments are used to record a message history
 type Comment struct {
@@ -106,12 +154,19 @@ func (m *Comment) GetText() string {
 //
 //Project is used to group Runs, Libraries and Samples
 type Project struct {
-	Label                string            `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
-	CID                  string            `protobuf:"bytes,3,opt,name=CID,proto3" json:"CID,omitempty"`
-	Runs                 map[string]string `protobuf:"bytes,4,rep,name=Runs,proto3" json:"Runs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+	Label                string               `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	CID                  string               `protobuf:"bytes,3,opt,name=CID,proto3" json:"CID,omitempty"`
+	Runs                 map[string]string    `protobuf:"bytes,4,rep,name=Runs,proto3" json:"Runs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Id                   string               `protobuf:"bytes,5,opt,name=id,proto3" json:"id,omitempty"`
+	Description          string               `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	Owners               []string             `protobuf:"bytes,7,rep,name=owners,proto3" json:"owners,omitempty"`
+	Created              *timestamp.Timestamp `protobuf:"bytes,8,opt,name=created,proto3" json:"created,omitempty"`
+	License              string               `protobuf:"bytes,9,opt,name=license,proto3" json:"license,omitempty"`
+	Libraries            map[string]string    `protobuf:"bytes,10,rep,name=libraries,proto3" json:"libraries,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Samples              map[string]string    `protobuf:"bytes,11,rep,name=samples,proto3" json:"samples,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *Project) Reset()         { *m = Project{} }
@@ -160,11 +215,62 @@ func (m *Project) GetRuns() map[string]string {
 	return nil
 }
 
+func (m *Project) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Project) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Project) GetOwners() []string {
+	if m != nil {
+		return m.Owners
+	}
+	return nil
+}
+
+func (m *Project) GetCreated() *timestamp.Timestamp {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+func (m *Project) GetLicense() string {
+	if m != nil {
+		return m.License
+	}
+	return ""
+}
+
+func (m *Project) GetLibraries() map[string]string {
+	if m != nil {
+		return m.Libraries
+	}
+	return nil
+}
+
+func (m *Project) GetSamples() map[string]string {
+	if m != nil {
+		return m.Samples
+	}
+	return nil
+}
+
 //
 //ProjectDatabase is used to organise Projects
 type ProjectDatabase struct {
 	Projects             map[string]*Project `protobuf:"bytes,2,rep,name=projects,proto3" json:"projects,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	Pin                  bool                `protobuf:"varint,3,opt,name=pin,proto3" json:"pin,omitempty"`
+	Version              *DatabaseVersion    `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	StateCID             string              `protobuf:"bytes,5,opt,name=stateCID,proto3" json:"stateCID,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
 	XXX_unrecognized     []byte              `json:"-"`
 	XXX_sizecache        int32               `json:"-"`
@@ -209,30 +315,119 @@ func (m *ProjectDatabase) GetPin() bool {
 	return false
 }
 
+func (m *ProjectDatabase) GetVersion() *DatabaseVersion {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *ProjectDatabase) GetStateCID() string {
+	if m != nil {
+		return m.StateCID
+	}
+	return ""
+}
+
+//
+//DatabaseVersion links a ProjectDatabase to the version it was changed from, forming the database history
+type DatabaseVersion struct {
+	ParentCID            string               `protobuf:"bytes,1,opt,name=parentCID,proto3" json:"parentCID,omitempty"`
+	MergedCID            string               `protobuf:"bytes,2,opt,name=mergedCID,proto3" json:"mergedCID,omitempty"`
+	Author               string               `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	Timestamp            *timestamp.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Summary              string               `protobuf:"bytes,5,opt,name=summary,proto3" json:"summary,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *DatabaseVersion) Reset()         { *m = DatabaseVersion{} }
+func (m *DatabaseVersion) String() string { return proto.CompactTextString(m) }
+func (*DatabaseVersion) ProtoMessage()    {}
+func (*DatabaseVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{3}
+}
+
+func (m *DatabaseVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DatabaseVersion.Unmarshal(m, b)
+}
+func (m *DatabaseVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DatabaseVersion.Marshal(b, m, deterministic)
+}
+func (m *DatabaseVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DatabaseVersion.Merge(m, src)
+}
+func (m *DatabaseVersion) XXX_Size() int {
+	return xxx_messageInfo_DatabaseVersion.Size(m)
+}
+func (m *DatabaseVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_DatabaseVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DatabaseVersion proto.InternalMessageInfo
+
+func (m *DatabaseVersion) GetParentCID() string {
+	if m != nil {
+		return m.ParentCID
+	}
+	return ""
+}
+
+func (m *DatabaseVersion) GetMergedCID() string {
+	if m != nil {
+		return m.MergedCID
+	}
+	return ""
+}
+
+func (m *DatabaseVersion) GetAuthor() string {
+	if m != nil {
+		return m.Author
+	}
+	return ""
+}
+
+func (m *DatabaseVersion) GetTimestamp() *timestamp.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func (m *DatabaseVersion) GetSummary() string {
+	if m != nil {
+		return m.Summary
+	}
+	return ""
+}
+
 //
 //Run is used to describe a Nanopore sequencing run
 type Run struct {
-	Created *timestamp.Timestamp `protobuf:"bytes,1,opt,name=created,proto3" json:"created,omitempty"`
-	Label   string               `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
-	//string parentProjectLabel = 3;               // the label of the project that this run belongs to
-	ParentProjectCID     string          `protobuf:"bytes,4,opt,name=parentProjectCID,proto3" json:"parentProjectCID,omitempty"`
-	History              []*Comment      `protobuf:"bytes,5,rep,name=history,proto3" json:"history,omitempty"`
-	Status               Status          `protobuf:"varint,6,opt,name=status,proto3,enum=records.Status" json:"status,omitempty"`
-	Tags                 map[string]bool `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
-	RequestOrder         []string        `protobuf:"bytes,8,rep,name=requestOrder,proto3" json:"requestOrder,omitempty"`
-	OutputDirectory      string          `protobuf:"bytes,9,opt,name=outputDirectory,proto3" json:"outputDirectory,omitempty"`
-	Fast5OutputDirectory string          `protobuf:"bytes,10,opt,name=fast5OutputDirectory,proto3" json:"fast5OutputDirectory,omitempty"`
-	FastqOutputDirectory string          `protobuf:"bytes,11,opt,name=fastqOutputDirectory,proto3" json:"fastqOutputDirectory,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+	Created              *timestamp.Timestamp `protobuf:"bytes,1,opt,name=created,proto3" json:"created,omitempty"`
+	Label                string               `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	ParentProjectLabel   string               `protobuf:"bytes,3,opt,name=parentProjectLabel,proto3" json:"parentProjectLabel,omitempty"`
+	ParentProjectCID     string               `protobuf:"bytes,4,opt,name=parentProjectCID,proto3" json:"parentProjectCID,omitempty"`
+	History              []*Comment           `protobuf:"bytes,5,rep,name=history,proto3" json:"history,omitempty"`
+	Status               Status               `protobuf:"varint,6,opt,name=status,proto3,enum=records.Status" json:"status,omitempty"`
+	RequestOrder         []string             `protobuf:"bytes,8,rep,name=requestOrder,proto3" json:"requestOrder,omitempty"`
+	OutputDirectory      string               `protobuf:"bytes,9,opt,name=outputDirectory,proto3" json:"outputDirectory,omitempty"`
+	Fast5OutputDirectory string               `protobuf:"bytes,10,opt,name=fast5OutputDirectory,proto3" json:"fast5OutputDirectory,omitempty"`
+	FastqOutputDirectory string               `protobuf:"bytes,11,opt,name=fastqOutputDirectory,proto3" json:"fastqOutputDirectory,omitempty"`
+	Library              string               `protobuf:"bytes,12,opt,name=library,proto3" json:"library,omitempty"`
+	Samples              []string             `protobuf:"bytes,13,rep,name=samples,proto3" json:"samples,omitempty"`
+	Tags                 map[string]*TagState `protobuf:"bytes,14,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *Run) Reset()         { *m = Run{} }
 func (m *Run) String() string { return proto.CompactTextString(m) }
 func (*Run) ProtoMessage()    {}
 func (*Run) Descriptor() ([]byte, []int) {
-	return fileDescriptor_df7aaa7859039b55, []int{3}
+	return fileDescriptor_df7aaa7859039b55, []int{4}
 }
 
 func (m *Run) XXX_Unmarshal(b []byte) error {
@@ -267,6 +462,13 @@ func (m *Run) GetLabel() string {
 	return ""
 }
 
+func (m *Run) GetParentProjectLabel() string {
+	if m != nil {
+		return m.ParentProjectLabel
+	}
+	return ""
+}
+
 func (m *Run) GetParentProjectCID() string {
 	if m != nil {
 		return m.ParentProjectCID
@@ -288,13 +490,6 @@ func (m *Run) GetStatus() Status {
 	return Status_UN_INITIALIZED
 }
 
-func (m *Run) GetTags() map[string]bool {
-	if m != nil {
-		return m.Tags
-	}
-	return nil
-}
-
 func (m *Run) GetRequestOrder() []string {
 	if m != nil {
 		return m.RequestOrder
@@ -323,6 +518,148 @@ func (m *Run) GetFastqOutputDirectory() string {
 	return ""
 }
 
+func (m *Run) GetLibrary() string {
+	if m != nil {
+		return m.Library
+	}
+	return ""
+}
+
+func (m *Run) GetSamples() []string {
+	if m != nil {
+		return m.Samples
+	}
+	return nil
+}
+
+func (m *Run) GetTags() map[string]*TagState {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+//
+//Library is used to describe a sequencing library, prepared from one or more Samples and sequenced in a Run
+type Library struct {
+	Created              *timestamp.Timestamp `protobuf:"bytes,1,opt,name=created,proto3" json:"created,omitempty"`
+	Label                string               `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	ParentProjectLabel   string               `protobuf:"bytes,3,opt,name=parentProjectLabel,proto3" json:"parentProjectLabel,omitempty"`
+	History              []*Comment           `protobuf:"bytes,4,rep,name=history,proto3" json:"history,omitempty"`
+	Status               Status               `protobuf:"varint,5,opt,name=status,proto3,enum=records.Status" json:"status,omitempty"`
+	PrepKit              string               `protobuf:"bytes,6,opt,name=prepKit,proto3" json:"prepKit,omitempty"`
+	BarcodeKit           string               `protobuf:"bytes,7,opt,name=barcodeKit,proto3" json:"barcodeKit,omitempty"`
+	FlowcellType         string               `protobuf:"bytes,8,opt,name=flowcellType,proto3" json:"flowcellType,omitempty"`
+	InputDNA             float64              `protobuf:"fixed64,9,opt,name=inputDNA,proto3" json:"inputDNA,omitempty"`
+	Operator             string               `protobuf:"bytes,10,opt,name=operator,proto3" json:"operator,omitempty"`
+	Samples              []string             `protobuf:"bytes,11,rep,name=samples,proto3" json:"samples,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Library) Reset()         { *m = Library{} }
+func (m *Library) String() string { return proto.CompactTextString(m) }
+func (*Library) ProtoMessage()    {}
+func (*Library) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{5}
+}
+
+func (m *Library) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Library.Unmarshal(m, b)
+}
+func (m *Library) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Library.Marshal(b, m, deterministic)
+}
+func (m *Library) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Library.Merge(m, src)
+}
+func (m *Library) XXX_Size() int {
+	return xxx_messageInfo_Library.Size(m)
+}
+func (m *Library) XXX_DiscardUnknown() {
+	xxx_messageInfo_Library.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Library proto.InternalMessageInfo
+
+func (m *Library) GetCreated() *timestamp.Timestamp {
+	if m != nil {
+		return m.Created
+	}
+	return nil
+}
+
+func (m *Library) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *Library) GetParentProjectLabel() string {
+	if m != nil {
+		return m.ParentProjectLabel
+	}
+	return ""
+}
+
+func (m *Library) GetHistory() []*Comment {
+	if m != nil {
+		return m.History
+	}
+	return nil
+}
+
+func (m *Library) GetStatus() Status {
+	if m != nil {
+		return m.Status
+	}
+	return Status_UN_INITIALIZED
+}
+
+func (m *Library) GetPrepKit() string {
+	if m != nil {
+		return m.PrepKit
+	}
+	return ""
+}
+
+func (m *Library) GetBarcodeKit() string {
+	if m != nil {
+		return m.BarcodeKit
+	}
+	return ""
+}
+
+func (m *Library) GetFlowcellType() string {
+	if m != nil {
+		return m.FlowcellType
+	}
+	return ""
+}
+
+func (m *Library) GetInputDNA() float64 {
+	if m != nil {
+		return m.InputDNA
+	}
+	return 0
+}
+
+func (m *Library) GetOperator() string {
+	if m != nil {
+		return m.Operator
+	}
+	return ""
+}
+
+func (m *Library) GetSamples() []string {
+	if m != nil {
+		return m.Samples
+	}
+	return nil
+}
+
 //
 //Sample is used to describe a biological sample which is being sequenced as part of a Run
 type Sample struct {
@@ -330,10 +667,11 @@ type Sample struct {
 	Label                string               `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
 	History              []*Comment           `protobuf:"bytes,3,rep,name=history,proto3" json:"history,omitempty"`
 	Status               Status               `protobuf:"varint,4,opt,name=status,proto3,enum=records.Status" json:"status,omitempty"`
-	Tags                 map[string]bool      `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
 	RequestOrder         []string             `protobuf:"bytes,6,rep,name=requestOrder,proto3" json:"requestOrder,omitempty"`
 	ParentExperiment     string               `protobuf:"bytes,7,opt,name=parentExperiment,proto3" json:"parentExperiment,omitempty"`
 	Barcode              int32                `protobuf:"varint,8,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	ParentProjectLabel   string               `protobuf:"bytes,9,opt,name=parentProjectLabel,proto3" json:"parentProjectLabel,omitempty"`
+	Tags                 map[string]*TagState `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
 	XXX_unrecognized     []byte               `json:"-"`
 	XXX_sizecache        int32                `json:"-"`
@@ -343,7 +681,7 @@ func (m *Sample) Reset()         { *m = Sample{} }
 func (m *Sample) String() string { return proto.CompactTextString(m) }
 func (*Sample) ProtoMessage()    {}
 func (*Sample) Descriptor() ([]byte, []int) {
-	return fileDescriptor_df7aaa7859039b55, []int{4}
+	return fileDescriptor_df7aaa7859039b55, []int{6}
 }
 
 func (m *Sample) XXX_Unmarshal(b []byte) error {
@@ -392,13 +730,6 @@ func (m *Sample) GetStatus() Status {
 	return Status_UN_INITIALIZED
 }
 
-func (m *Sample) GetTags() map[string]bool {
-	if m != nil {
-		return m.Tags
-	}
-	return nil
-}
-
 func (m *Sample) GetRequestOrder() []string {
 	if m != nil {
 		return m.RequestOrder
@@ -420,17 +751,868 @@ func (m *Sample) GetBarcode() int32 {
 	return 0
 }
 
+func (m *Sample) GetParentProjectLabel() string {
+	if m != nil {
+		return m.ParentProjectLabel
+	}
+	return ""
+}
+
+func (m *Sample) GetTags() map[string]*TagState {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+//
+//TagState describes the progress of a tagged service with a Run or Sample
+type TagState struct {
+	State                TagState_State       `protobuf:"varint,1,opt,name=state,proto3,enum=records.TagState_State" json:"state,omitempty"`
+	Attempts             uint32               `protobuf:"varint,2,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	LastError            string               `protobuf:"bytes,3,opt,name=lastError,proto3" json:"lastError,omitempty"`
+	Started              *timestamp.Timestamp `protobuf:"bytes,4,opt,name=started,proto3" json:"started,omitempty"`
+	Finished             *timestamp.Timestamp `protobuf:"bytes,5,opt,name=finished,proto3" json:"finished,omitempty"`
+	ResultCID            string               `protobuf:"bytes,6,opt,name=resultCID,proto3" json:"resultCID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *TagState) Reset()         { *m = TagState{} }
+func (m *TagState) String() string { return proto.CompactTextString(m) }
+func (*TagState) ProtoMessage()    {}
+func (*TagState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{7}
+}
+
+func (m *TagState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TagState.Unmarshal(m, b)
+}
+func (m *TagState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TagState.Marshal(b, m, deterministic)
+}
+func (m *TagState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TagState.Merge(m, src)
+}
+func (m *TagState) XXX_Size() int {
+	return xxx_messageInfo_TagState.Size(m)
+}
+func (m *TagState) XXX_DiscardUnknown() {
+	xxx_messageInfo_TagState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TagState proto.InternalMessageInfo
+
+func (m *TagState) GetState() TagState_State {
+	if m != nil {
+		return m.State
+	}
+	return TagState_pending
+}
+
+func (m *TagState) GetAttempts() uint32 {
+	if m != nil {
+		return m.Attempts
+	}
+	return 0
+}
+
+func (m *TagState) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+func (m *TagState) GetStarted() *timestamp.Timestamp {
+	if m != nil {
+		return m.Started
+	}
+	return nil
+}
+
+func (m *TagState) GetFinished() *timestamp.Timestamp {
+	if m != nil {
+		return m.Finished
+	}
+	return nil
+}
+
+func (m *TagState) GetResultCID() string {
+	if m != nil {
+		return m.ResultCID
+	}
+	return ""
+}
+
+//
+//Message is the envelope for pubsub messages sent between Scribe nodes
+type Message struct {
+	Version   uint32               `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Id        string               `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Sender    string               `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`
+	Timestamp *timestamp.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Project   string               `protobuf:"bytes,5,opt,name=project,proto3" json:"project,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//	*Message_RunCreated
+	//	*Message_RunUpdated
+	//	*Message_TagCompleted
+	//	*Message_DatabaseHeadChanged
+	//	*Message_ProjectRegistered
+	//	*Message_StatusChanged
+	//	*Message_TagRequested
+	//	*Message_TagFailed
+	//	*Message_LibraryUpdated
+	Payload              isMessage_Payload `protobuf_oneof:"payload"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return proto.CompactTextString(m) }
+func (*Message) ProtoMessage()    {}
+func (*Message) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{8}
+}
+
+func (m *Message) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Message.Unmarshal(m, b)
+}
+func (m *Message) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Message.Marshal(b, m, deterministic)
+}
+func (m *Message) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message.Merge(m, src)
+}
+func (m *Message) XXX_Size() int {
+	return xxx_messageInfo_Message.Size(m)
+}
+func (m *Message) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message proto.InternalMessageInfo
+
+func (m *Message) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Message) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Message) GetSender() string {
+	if m != nil {
+		return m.Sender
+	}
+	return ""
+}
+
+func (m *Message) GetTimestamp() *timestamp.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func (m *Message) GetProject() string {
+	if m != nil {
+		return m.Project
+	}
+	return ""
+}
+
+type isMessage_Payload interface {
+	isMessage_Payload()
+}
+
+type Message_RunCreated struct {
+	RunCreated *RunCreated `protobuf:"bytes,6,opt,name=runCreated,proto3,oneof"`
+}
+
+type Message_RunUpdated struct {
+	RunUpdated *RunUpdated `protobuf:"bytes,7,opt,name=runUpdated,proto3,oneof"`
+}
+
+type Message_TagCompleted struct {
+	TagCompleted *TagCompleted `protobuf:"bytes,8,opt,name=tagCompleted,proto3,oneof"`
+}
+
+type Message_DatabaseHeadChanged struct {
+	DatabaseHeadChanged *DatabaseHeadChanged `protobuf:"bytes,9,opt,name=databaseHeadChanged,proto3,oneof"`
+}
+
+type Message_ProjectRegistered struct {
+	ProjectRegistered *ProjectRegistered `protobuf:"bytes,10,opt,name=projectRegistered,proto3,oneof"`
+}
+
+type Message_StatusChanged struct {
+	StatusChanged *StatusChanged `protobuf:"bytes,11,opt,name=statusChanged,proto3,oneof"`
+}
+
+type Message_TagRequested struct {
+	TagRequested *TagRequested `protobuf:"bytes,12,opt,name=tagRequested,proto3,oneof"`
+}
+
+type Message_TagFailed struct {
+	TagFailed *TagFailed `protobuf:"bytes,13,opt,name=tagFailed,proto3,oneof"`
+}
+
+type Message_LibraryUpdated struct {
+	LibraryUpdated *LibraryUpdated `protobuf:"bytes,14,opt,name=libraryUpdated,proto3,oneof"`
+}
+
+func (*Message_RunCreated) isMessage_Payload() {}
+
+func (*Message_RunUpdated) isMessage_Payload() {}
+
+func (*Message_TagCompleted) isMessage_Payload() {}
+
+func (*Message_DatabaseHeadChanged) isMessage_Payload() {}
+
+func (*Message_ProjectRegistered) isMessage_Payload() {}
+
+func (*Message_StatusChanged) isMessage_Payload() {}
+
+func (*Message_TagRequested) isMessage_Payload() {}
+
+func (*Message_TagFailed) isMessage_Payload() {}
+
+func (*Message_LibraryUpdated) isMessage_Payload() {}
+
+func (m *Message) GetPayload() isMessage_Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *Message) GetRunCreated() *RunCreated {
+	if x, ok := m.GetPayload().(*Message_RunCreated); ok {
+		return x.RunCreated
+	}
+	return nil
+}
+
+func (m *Message) GetRunUpdated() *RunUpdated {
+	if x, ok := m.GetPayload().(*Message_RunUpdated); ok {
+		return x.RunUpdated
+	}
+	return nil
+}
+
+func (m *Message) GetTagCompleted() *TagCompleted {
+	if x, ok := m.GetPayload().(*Message_TagCompleted); ok {
+		return x.TagCompleted
+	}
+	return nil
+}
+
+func (m *Message) GetDatabaseHeadChanged() *DatabaseHeadChanged {
+	if x, ok := m.GetPayload().(*Message_DatabaseHeadChanged); ok {
+		return x.DatabaseHeadChanged
+	}
+	return nil
+}
+
+func (m *Message) GetProjectRegistered() *ProjectRegistered {
+	if x, ok := m.GetPayload().(*Message_ProjectRegistered); ok {
+		return x.ProjectRegistered
+	}
+	return nil
+}
+
+func (m *Message) GetStatusChanged() *StatusChanged {
+	if x, ok := m.GetPayload().(*Message_StatusChanged); ok {
+		return x.StatusChanged
+	}
+	return nil
+}
+
+func (m *Message) GetTagRequested() *TagRequested {
+	if x, ok := m.GetPayload().(*Message_TagRequested); ok {
+		return x.TagRequested
+	}
+	return nil
+}
+
+func (m *Message) GetTagFailed() *TagFailed {
+	if x, ok := m.GetPayload().(*Message_TagFailed); ok {
+		return x.TagFailed
+	}
+	return nil
+}
+
+func (m *Message) GetLibraryUpdated() *LibraryUpdated {
+	if x, ok := m.GetPayload().(*Message_LibraryUpdated); ok {
+		return x.LibraryUpdated
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*Message) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*Message_RunCreated)(nil),
+		(*Message_RunUpdated)(nil),
+		(*Message_TagCompleted)(nil),
+		(*Message_DatabaseHeadChanged)(nil),
+		(*Message_ProjectRegistered)(nil),
+		(*Message_StatusChanged)(nil),
+		(*Message_TagRequested)(nil),
+		(*Message_TagFailed)(nil),
+		(*Message_LibraryUpdated)(nil),
+	}
+}
+
+//
+//RunCreated is sent when a Run is added to a Project
+type RunCreated struct {
+	Label                string   `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	CID                  string   `protobuf:"bytes,2,opt,name=CID,proto3" json:"CID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RunCreated) Reset()         { *m = RunCreated{} }
+func (m *RunCreated) String() string { return proto.CompactTextString(m) }
+func (*RunCreated) ProtoMessage()    {}
+func (*RunCreated) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{9}
+}
+
+func (m *RunCreated) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RunCreated.Unmarshal(m, b)
+}
+func (m *RunCreated) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RunCreated.Marshal(b, m, deterministic)
+}
+func (m *RunCreated) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RunCreated.Merge(m, src)
+}
+func (m *RunCreated) XXX_Size() int {
+	return xxx_messageInfo_RunCreated.Size(m)
+}
+func (m *RunCreated) XXX_DiscardUnknown() {
+	xxx_messageInfo_RunCreated.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RunCreated proto.InternalMessageInfo
+
+func (m *RunCreated) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *RunCreated) GetCID() string {
+	if m != nil {
+		return m.CID
+	}
+	return ""
+}
+
+//
+//RunUpdated is sent when a Run is changed
+type RunUpdated struct {
+	Label                string   `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	CID                  string   `protobuf:"bytes,2,opt,name=CID,proto3" json:"CID,omitempty"`
+	PreviousCID          string   `protobuf:"bytes,3,opt,name=previousCID,proto3" json:"previousCID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RunUpdated) Reset()         { *m = RunUpdated{} }
+func (m *RunUpdated) String() string { return proto.CompactTextString(m) }
+func (*RunUpdated) ProtoMessage()    {}
+func (*RunUpdated) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{10}
+}
+
+func (m *RunUpdated) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RunUpdated.Unmarshal(m, b)
+}
+func (m *RunUpdated) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RunUpdated.Marshal(b, m, deterministic)
+}
+func (m *RunUpdated) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RunUpdated.Merge(m, src)
+}
+func (m *RunUpdated) XXX_Size() int {
+	return xxx_messageInfo_RunUpdated.Size(m)
+}
+func (m *RunUpdated) XXX_DiscardUnknown() {
+	xxx_messageInfo_RunUpdated.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RunUpdated proto.InternalMessageInfo
+
+func (m *RunUpdated) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *RunUpdated) GetCID() string {
+	if m != nil {
+		return m.CID
+	}
+	return ""
+}
+
+func (m *RunUpdated) GetPreviousCID() string {
+	if m != nil {
+		return m.PreviousCID
+	}
+	return ""
+}
+
+//
+//LibraryUpdated is sent when a Library is added to a Project or changed
+type LibraryUpdated struct {
+	Label                string   `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	CID                  string   `protobuf:"bytes,2,opt,name=CID,proto3" json:"CID,omitempty"`
+	PreviousCID          string   `protobuf:"bytes,3,opt,name=previousCID,proto3" json:"previousCID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LibraryUpdated) Reset()         { *m = LibraryUpdated{} }
+func (m *LibraryUpdated) String() string { return proto.CompactTextString(m) }
+func (*LibraryUpdated) ProtoMessage()    {}
+func (*LibraryUpdated) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{11}
+}
+
+func (m *LibraryUpdated) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LibraryUpdated.Unmarshal(m, b)
+}
+func (m *LibraryUpdated) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LibraryUpdated.Marshal(b, m, deterministic)
+}
+func (m *LibraryUpdated) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LibraryUpdated.Merge(m, src)
+}
+func (m *LibraryUpdated) XXX_Size() int {
+	return xxx_messageInfo_LibraryUpdated.Size(m)
+}
+func (m *LibraryUpdated) XXX_DiscardUnknown() {
+	xxx_messageInfo_LibraryUpdated.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LibraryUpdated proto.InternalMessageInfo
+
+func (m *LibraryUpdated) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *LibraryUpdated) GetCID() string {
+	if m != nil {
+		return m.CID
+	}
+	return ""
+}
+
+func (m *LibraryUpdated) GetPreviousCID() string {
+	if m != nil {
+		return m.PreviousCID
+	}
+	return ""
+}
+
+//
+//TagCompleted is sent when a tagged service has finished with a Run
+type TagCompleted struct {
+	RunLabel             string   `protobuf:"bytes,1,opt,name=runLabel,proto3" json:"runLabel,omitempty"`
+	Tag                  string   `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	ResultCID            string   `protobuf:"bytes,3,opt,name=resultCID,proto3" json:"resultCID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TagCompleted) Reset()         { *m = TagCompleted{} }
+func (m *TagCompleted) String() string { return proto.CompactTextString(m) }
+func (*TagCompleted) ProtoMessage()    {}
+func (*TagCompleted) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{12}
+}
+
+func (m *TagCompleted) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TagCompleted.Unmarshal(m, b)
+}
+func (m *TagCompleted) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TagCompleted.Marshal(b, m, deterministic)
+}
+func (m *TagCompleted) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TagCompleted.Merge(m, src)
+}
+func (m *TagCompleted) XXX_Size() int {
+	return xxx_messageInfo_TagCompleted.Size(m)
+}
+func (m *TagCompleted) XXX_DiscardUnknown() {
+	xxx_messageInfo_TagCompleted.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TagCompleted proto.InternalMessageInfo
+
+func (m *TagCompleted) GetRunLabel() string {
+	if m != nil {
+		return m.RunLabel
+	}
+	return ""
+}
+
+func (m *TagCompleted) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *TagCompleted) GetResultCID() string {
+	if m != nil {
+		return m.ResultCID
+	}
+	return ""
+}
+
+//
+//TagRequested is sent to ask a tagged service to process a Run
+type TagRequested struct {
+	RunLabel             string   `protobuf:"bytes,1,opt,name=runLabel,proto3" json:"runLabel,omitempty"`
+	Tag                  string   `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	RunCID               string   `protobuf:"bytes,3,opt,name=runCID,proto3" json:"runCID,omitempty"`
+	Attempt              uint32   `protobuf:"varint,4,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TagRequested) Reset()         { *m = TagRequested{} }
+func (m *TagRequested) String() string { return proto.CompactTextString(m) }
+func (*TagRequested) ProtoMessage()    {}
+func (*TagRequested) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{13}
+}
+
+func (m *TagRequested) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TagRequested.Unmarshal(m, b)
+}
+func (m *TagRequested) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TagRequested.Marshal(b, m, deterministic)
+}
+func (m *TagRequested) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TagRequested.Merge(m, src)
+}
+func (m *TagRequested) XXX_Size() int {
+	return xxx_messageInfo_TagRequested.Size(m)
+}
+func (m *TagRequested) XXX_DiscardUnknown() {
+	xxx_messageInfo_TagRequested.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TagRequested proto.InternalMessageInfo
+
+func (m *TagRequested) GetRunLabel() string {
+	if m != nil {
+		return m.RunLabel
+	}
+	return ""
+}
+
+func (m *TagRequested) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *TagRequested) GetRunCID() string {
+	if m != nil {
+		return m.RunCID
+	}
+	return ""
+}
+
+func (m *TagRequested) GetAttempt() uint32 {
+	if m != nil {
+		return m.Attempt
+	}
+	return 0
+}
+
+//
+//TagFailed is sent when a tagged service could not process a Run
+type TagFailed struct {
+	RunLabel             string   `protobuf:"bytes,1,opt,name=runLabel,proto3" json:"runLabel,omitempty"`
+	Tag                  string   `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	Error                string   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TagFailed) Reset()         { *m = TagFailed{} }
+func (m *TagFailed) String() string { return proto.CompactTextString(m) }
+func (*TagFailed) ProtoMessage()    {}
+func (*TagFailed) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{14}
+}
+
+func (m *TagFailed) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TagFailed.Unmarshal(m, b)
+}
+func (m *TagFailed) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TagFailed.Marshal(b, m, deterministic)
+}
+func (m *TagFailed) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TagFailed.Merge(m, src)
+}
+func (m *TagFailed) XXX_Size() int {
+	return xxx_messageInfo_TagFailed.Size(m)
+}
+func (m *TagFailed) XXX_DiscardUnknown() {
+	xxx_messageInfo_TagFailed.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TagFailed proto.InternalMessageInfo
+
+func (m *TagFailed) GetRunLabel() string {
+	if m != nil {
+		return m.RunLabel
+	}
+	return ""
+}
+
+func (m *TagFailed) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *TagFailed) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+//
+//DatabaseHeadChanged is sent when a new version of the ProjectDatabase has been pushed to the IPFS
+type DatabaseHeadChanged struct {
+	CID                  string   `protobuf:"bytes,1,opt,name=CID,proto3" json:"CID,omitempty"`
+	PreviousCID          string   `protobuf:"bytes,2,opt,name=previousCID,proto3" json:"previousCID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DatabaseHeadChanged) Reset()         { *m = DatabaseHeadChanged{} }
+func (m *DatabaseHeadChanged) String() string { return proto.CompactTextString(m) }
+func (*DatabaseHeadChanged) ProtoMessage()    {}
+func (*DatabaseHeadChanged) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{15}
+}
+
+func (m *DatabaseHeadChanged) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DatabaseHeadChanged.Unmarshal(m, b)
+}
+func (m *DatabaseHeadChanged) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DatabaseHeadChanged.Marshal(b, m, deterministic)
+}
+func (m *DatabaseHeadChanged) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DatabaseHeadChanged.Merge(m, src)
+}
+func (m *DatabaseHeadChanged) XXX_Size() int {
+	return xxx_messageInfo_DatabaseHeadChanged.Size(m)
+}
+func (m *DatabaseHeadChanged) XXX_DiscardUnknown() {
+	xxx_messageInfo_DatabaseHeadChanged.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DatabaseHeadChanged proto.InternalMessageInfo
+
+func (m *DatabaseHeadChanged) GetCID() string {
+	if m != nil {
+		return m.CID
+	}
+	return ""
+}
+
+func (m *DatabaseHeadChanged) GetPreviousCID() string {
+	if m != nil {
+		return m.PreviousCID
+	}
+	return ""
+}
+
+//
+//ProjectRegistered is sent when a new Project is registered, so that other nodes can join it
+type ProjectRegistered struct {
+	Label                string   `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Id                   string   `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	CID                  string   `protobuf:"bytes,3,opt,name=CID,proto3" json:"CID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ProjectRegistered) Reset()         { *m = ProjectRegistered{} }
+func (m *ProjectRegistered) String() string { return proto.CompactTextString(m) }
+func (*ProjectRegistered) ProtoMessage()    {}
+func (*ProjectRegistered) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{16}
+}
+
+func (m *ProjectRegistered) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProjectRegistered.Unmarshal(m, b)
+}
+func (m *ProjectRegistered) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProjectRegistered.Marshal(b, m, deterministic)
+}
+func (m *ProjectRegistered) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProjectRegistered.Merge(m, src)
+}
+func (m *ProjectRegistered) XXX_Size() int {
+	return xxx_messageInfo_ProjectRegistered.Size(m)
+}
+func (m *ProjectRegistered) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProjectRegistered.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProjectRegistered proto.InternalMessageInfo
+
+func (m *ProjectRegistered) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *ProjectRegistered) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ProjectRegistered) GetCID() string {
+	if m != nil {
+		return m.CID
+	}
+	return ""
+}
+
+//
+//StatusChanged is sent when the Status of a Run or Sample changes
+type StatusChanged struct {
+	RecordType           string   `protobuf:"bytes,1,opt,name=recordType,proto3" json:"recordType,omitempty"`
+	Label                string   `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	PreviousStatus       Status   `protobuf:"varint,3,opt,name=previousStatus,proto3,enum=records.Status" json:"previousStatus,omitempty"`
+	Status               Status   `protobuf:"varint,4,opt,name=status,proto3,enum=records.Status" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatusChanged) Reset()         { *m = StatusChanged{} }
+func (m *StatusChanged) String() string { return proto.CompactTextString(m) }
+func (*StatusChanged) ProtoMessage()    {}
+func (*StatusChanged) Descriptor() ([]byte, []int) {
+	return fileDescriptor_df7aaa7859039b55, []int{17}
+}
+
+func (m *StatusChanged) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatusChanged.Unmarshal(m, b)
+}
+func (m *StatusChanged) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatusChanged.Marshal(b, m, deterministic)
+}
+func (m *StatusChanged) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatusChanged.Merge(m, src)
+}
+func (m *StatusChanged) XXX_Size() int {
+	return xxx_messageInfo_StatusChanged.Size(m)
+}
+func (m *StatusChanged) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatusChanged.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatusChanged proto.InternalMessageInfo
+
+func (m *StatusChanged) GetRecordType() string {
+	if m != nil {
+		return m.RecordType
+	}
+	return ""
+}
+
+func (m *StatusChanged) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *StatusChanged) GetPreviousStatus() Status {
+	if m != nil {
+		return m.PreviousStatus
+	}
+	return Status_UN_INITIALIZED
+}
+
+func (m *StatusChanged) GetStatus() Status {
+	if m != nil {
+		return m.Status
+	}
+	return Status_UN_INITIALIZED
+}
+
 func init() {
 	proto.RegisterEnum("records.Status", Status_name, Status_value)
+	proto.RegisterEnum("records.TagState_State", TagState_State_name, TagState_State_value)
 	proto.RegisterType((*Comment)(nil), "records.Comment")
 	proto.RegisterType((*Project)(nil), "records.Project")
+	proto.RegisterMapType((map[string]string)(nil), "records.Project.LibrariesEntry")
 	proto.RegisterMapType((map[string]string)(nil), "records.Project.RunsEntry")
+	proto.RegisterMapType((map[string]string)(nil), "records.Project.SamplesEntry")
 	proto.RegisterType((*ProjectDatabase)(nil), "records.ProjectDatabase")
 	proto.RegisterMapType((map[string]*Project)(nil), "records.ProjectDatabase.ProjectsEntry")
+	proto.RegisterType((*DatabaseVersion)(nil), "records.DatabaseVersion")
 	proto.RegisterType((*Run)(nil), "records.Run")
-	proto.RegisterMapType((map[string]bool)(nil), "records.Run.TagsEntry")
+	proto.RegisterMapType((map[string]*TagState)(nil), "records.Run.TagsEntry")
+	proto.RegisterType((*Library)(nil), "records.Library")
 	proto.RegisterType((*Sample)(nil), "records.Sample")
-	proto.RegisterMapType((map[string]bool)(nil), "records.Sample.TagsEntry")
+	proto.RegisterMapType((map[string]*TagState)(nil), "records.Sample.TagsEntry")
+	proto.RegisterType((*TagState)(nil), "records.TagState")
+	proto.RegisterType((*Message)(nil), "records.Message")
+	proto.RegisterType((*RunCreated)(nil), "records.RunCreated")
+	proto.RegisterType((*RunUpdated)(nil), "records.RunUpdated")
+	proto.RegisterType((*LibraryUpdated)(nil), "records.LibraryUpdated")
+	proto.RegisterType((*TagCompleted)(nil), "records.TagCompleted")
+	proto.RegisterType((*TagRequested)(nil), "records.TagRequested")
+	proto.RegisterType((*TagFailed)(nil), "records.TagFailed")
+	proto.RegisterType((*DatabaseHeadChanged)(nil), "records.DatabaseHeadChanged")
+	proto.RegisterType((*ProjectRegistered)(nil), "records.ProjectRegistered")
+	proto.RegisterType((*StatusChanged)(nil), "records.StatusChanged")
 }
 
 func init() {
@@ -438,42 +1620,101 @@ func init() {
 }
 
 var fileDescriptor_df7aaa7859039b55 = []byte{
-	// 591 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x52, 0xcd, 0x6e, 0xd3, 0x40,
-	0x10, 0xc6, 0xb1, 0xeb, 0x9f, 0xe9, 0x9f, 0xb5, 0xaa, 0xd0, 0x92, 0x0b, 0x56, 0x0e, 0xc5, 0x8a,
-	0x84, 0x2b, 0x05, 0x10, 0x15, 0x37, 0x68, 0x72, 0x88, 0x04, 0x2d, 0xda, 0x16, 0x21, 0x71, 0x41,
-	0x6b, 0x7b, 0x6b, 0x0c, 0x89, 0xed, 0xee, 0xae, 0x51, 0xfb, 0x2a, 0x3c, 0x08, 0x6f, 0xc5, 0x13,
-	0x70, 0x41, 0x5e, 0xff, 0x24, 0x35, 0x85, 0x56, 0x88, 0xdb, 0xec, 0x37, 0xdf, 0xcc, 0x7c, 0xb3,
-	0xdf, 0xc0, 0x96, 0x88, 0x78, 0x1a, 0xb2, 0xa0, 0xe0, 0xb9, 0xcc, 0x91, 0xc5, 0x59, 0x94, 0xf3,
-	0x58, 0x0c, 0x1f, 0x26, 0x79, 0x9e, 0x2c, 0xd8, 0x81, 0x82, 0xc3, 0xf2, 0xfc, 0x40, 0xa6, 0x4b,
-	0x26, 0x24, 0x5d, 0x16, 0x35, 0x73, 0xf4, 0x1e, 0xac, 0xa3, 0x7c, 0xb9, 0x64, 0x99, 0x44, 0x87,
-	0xe0, 0x74, 0x59, 0xac, 0x79, 0x9a, 0xbf, 0x39, 0x19, 0x06, 0x75, 0x7d, 0xd0, 0xd6, 0x07, 0x67,
-	0x2d, 0x83, 0xac, 0xc8, 0x08, 0x81, 0x21, 0xd9, 0xa5, 0xc4, 0x03, 0x4f, 0xf3, 0x1d, 0xa2, 0xe2,
-	0xd1, 0x37, 0x0d, 0xac, 0xb7, 0x3c, 0xff, 0xcc, 0x22, 0x89, 0xf6, 0x60, 0x63, 0x41, 0x43, 0xb6,
-	0x68, 0x08, 0xf5, 0x03, 0xb9, 0xa0, 0x1f, 0xcd, 0xa7, 0x58, 0x57, 0x58, 0x15, 0xa2, 0x00, 0x0c,
-	0x52, 0x66, 0x02, 0x1b, 0x9e, 0xae, 0x86, 0x37, 0x5b, 0x04, 0x4d, 0x9f, 0xa0, 0x4a, 0xce, 0x32,
-	0xc9, 0xaf, 0x88, 0xe2, 0x0d, 0x9f, 0x83, 0xd3, 0x41, 0x55, 0xbb, 0x2f, 0xec, 0x4a, 0x09, 0x77,
-	0x48, 0x15, 0x56, 0x63, 0xbf, 0xd2, 0x45, 0xc9, 0xda, 0xb1, 0xea, 0xf1, 0x62, 0x70, 0xa8, 0x8d,
-	0xbe, 0x6b, 0xb0, 0xdb, 0x34, 0x9d, 0x52, 0x49, 0x43, 0x2a, 0x18, 0x7a, 0x05, 0x76, 0x51, 0x43,
-	0x02, 0x0f, 0x94, 0x80, 0xfd, 0xbe, 0x80, 0x96, 0xdb, 0xbe, 0x1b, 0x31, 0x5d, 0x5d, 0xa5, 0xa1,
-	0x48, 0x33, 0xb5, 0x92, 0x4d, 0xaa, 0x70, 0xf8, 0x06, 0xb6, 0xaf, 0x91, 0x6f, 0x90, 0xb9, 0xbf,
-	0x2e, 0x73, 0x73, 0xe2, 0xf6, 0xa7, 0xae, 0x0b, 0xff, 0xa1, 0x83, 0x4e, 0xca, 0x0c, 0x3d, 0x05,
-	0x2b, 0xe2, 0x8c, 0x4a, 0x16, 0xdf, 0xc1, 0xa9, 0x96, 0xfa, 0x07, 0x1f, 0xc6, 0xe0, 0x16, 0x94,
-	0xb3, 0x4c, 0x36, 0xf3, 0x2a, 0x53, 0x0c, 0x45, 0xf8, 0x0d, 0x47, 0x63, 0xb0, 0x3e, 0xa5, 0x42,
-	0xe6, 0xfc, 0x0a, 0x6f, 0xa8, 0x3f, 0x5a, 0xa9, 0x6d, 0xce, 0x88, 0xb4, 0x04, 0xf4, 0x08, 0x4c,
-	0x21, 0xa9, 0x2c, 0x05, 0x36, 0x3d, 0xcd, 0xdf, 0x99, 0xec, 0x76, 0xd4, 0x53, 0x05, 0x93, 0x26,
-	0x8d, 0xc6, 0x60, 0x48, 0x9a, 0x08, 0x6c, 0xa9, 0x8e, 0xf7, 0x3b, 0x1a, 0x29, 0xb3, 0xe0, 0x8c,
-	0x26, 0xad, 0xe5, 0x15, 0x07, 0x8d, 0x60, 0x8b, 0xb3, 0x8b, 0x92, 0x09, 0x79, 0xc2, 0x63, 0xc6,
-	0xb1, 0xed, 0xe9, 0xbe, 0x43, 0xae, 0x61, 0xc8, 0x87, 0xdd, 0xbc, 0x94, 0x45, 0x29, 0xa7, 0x29,
-	0x67, 0x91, 0x12, 0xeb, 0xa8, 0x7d, 0xfa, 0x30, 0x9a, 0xc0, 0xde, 0x39, 0x15, 0xf2, 0xd9, 0x49,
-	0x8f, 0x0e, 0x8a, 0x7e, 0x63, 0xae, 0xad, 0xb9, 0xe8, 0xd7, 0x6c, 0xae, 0x6a, 0xfa, 0xb9, 0xea,
-	0x50, 0xbb, 0x45, 0x6e, 0x3b, 0x54, 0x7b, 0xdd, 0xef, 0x9f, 0x03, 0x30, 0x4f, 0xe9, 0xb2, 0x58,
-	0xb0, 0xff, 0x6c, 0x79, 0x67, 0xa3, 0x7e, 0x77, 0x1b, 0x8d, 0xbf, 0xdb, 0xf8, 0xb8, 0xb1, 0xb1,
-	0x3e, 0x8c, 0x07, 0x2b, 0x9a, 0xd2, 0x7f, 0xab, 0x93, 0xe6, 0x0d, 0x4e, 0x76, 0xa7, 0x39, 0xbb,
-	0x2c, 0x18, 0x4f, 0x2b, 0x61, 0xd8, 0x5a, 0x3f, 0xcd, 0x15, 0x8e, 0x30, 0x58, 0x21, 0xe5, 0x51,
-	0x1e, 0x33, 0x6c, 0x7b, 0x9a, 0xbf, 0x41, 0xda, 0xe7, 0x3f, 0xff, 0xfe, 0x78, 0x06, 0x66, 0xbd,
-	0x23, 0x42, 0xb0, 0xf3, 0xee, 0xf8, 0xe3, 0xfc, 0x78, 0x7e, 0x36, 0x7f, 0xf9, 0x7a, 0xfe, 0x61,
-	0x36, 0x75, 0xef, 0xa1, 0x2d, 0xb0, 0xcb, 0x4c, 0xd2, 0x24, 0x61, 0xb1, 0xab, 0x21, 0x00, 0xb3,
-	0x89, 0x07, 0x68, 0x1b, 0x1c, 0x9a, 0x65, 0x79, 0x99, 0x45, 0x2c, 0x76, 0xf5, 0xd0, 0x54, 0x06,
-	0x3d, 0xf9, 0x15, 0x00, 0x00, 0xff, 0xff, 0x2c, 0xa7, 0xb3, 0x33, 0xa4, 0x05, 0x00, 0x00,
+	// 1525 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x58, 0xe9, 0x6e, 0x14, 0xc7,
+	0x16, 0x9e, 0xbd, 0x67, 0xce, 0x2c, 0x1e, 0x0a, 0xae, 0x6f, 0x5f, 0x8b, 0x7b, 0xb1, 0xfa, 0x07,
+	0x58, 0x96, 0x18, 0xa4, 0xb9, 0x10, 0x10, 0x59, 0x24, 0xb0, 0x1d, 0xd9, 0x60, 0x16, 0x35, 0x86,
+	0x44, 0xfc, 0x89, 0xca, 0xd3, 0xe5, 0x76, 0x87, 0x99, 0xee, 0xa6, 0xaa, 0xda, 0xe0, 0x17, 0xc8,
+	0x8f, 0x3c, 0x48, 0xf2, 0x0c, 0xf9, 0x9f, 0x97, 0xc8, 0x13, 0x44, 0xca, 0x53, 0x44, 0x75, 0xba,
+	0xaa, 0x97, 0x99, 0x31, 0xd8, 0x4a, 0x50, 0xfe, 0xcd, 0x59, 0xab, 0xce, 0xa9, 0xef, 0x2c, 0x3d,
+	0xd0, 0x13, 0x13, 0x1e, 0x1c, 0xb2, 0x51, 0xcc, 0x23, 0x19, 0x11, 0x8b, 0xb3, 0x49, 0xc4, 0x3d,
+	0xb1, 0x76, 0xcd, 0x8f, 0x22, 0x7f, 0xca, 0x6e, 0x21, 0xfb, 0x30, 0x39, 0xba, 0x25, 0x83, 0x19,
+	0x13, 0x92, 0xce, 0xe2, 0x54, 0xd3, 0xf9, 0x06, 0xac, 0xad, 0x68, 0x36, 0x63, 0xa1, 0x24, 0xf7,
+	0xa0, 0x93, 0x49, 0xed, 0xea, 0x7a, 0x75, 0xa3, 0x3b, 0x5e, 0x1b, 0xa5, 0xf6, 0x23, 0x63, 0x3f,
+	0x3a, 0x30, 0x1a, 0x6e, 0xae, 0x4c, 0x08, 0x34, 0x24, 0x7b, 0x2f, 0xed, 0xda, 0x7a, 0x75, 0xa3,
+	0xe3, 0xe2, 0x6f, 0xe7, 0xa7, 0x06, 0x58, 0xcf, 0x79, 0xf4, 0x3d, 0x9b, 0x48, 0x72, 0x05, 0x9a,
+	0x53, 0x7a, 0xc8, 0xa6, 0x5a, 0x21, 0x25, 0xc8, 0x10, 0xea, 0x5b, 0x7b, 0xdb, 0x76, 0x1d, 0x79,
+	0xea, 0x27, 0x19, 0x41, 0xc3, 0x4d, 0x42, 0x61, 0x37, 0xd6, 0xeb, 0x78, 0xb8, 0x8e, 0x62, 0xa4,
+	0xfd, 0x8c, 0x94, 0x70, 0x27, 0x94, 0xfc, 0xd4, 0x45, 0x3d, 0x32, 0x80, 0x5a, 0xe0, 0xd9, 0x4d,
+	0x74, 0x50, 0x0b, 0x3c, 0xb2, 0x0e, 0x5d, 0x8f, 0xa9, 0x44, 0xc4, 0x32, 0x88, 0x42, 0xbb, 0x85,
+	0x82, 0x22, 0x8b, 0xac, 0x42, 0x2b, 0x7a, 0x17, 0x32, 0x2e, 0x6c, 0x6b, 0xbd, 0xbe, 0xd1, 0x71,
+	0x35, 0x45, 0x6e, 0x83, 0x35, 0xe1, 0x8c, 0x4a, 0xe6, 0xd9, 0xed, 0x8f, 0x46, 0x6e, 0x54, 0x89,
+	0x0d, 0xd6, 0x34, 0x98, 0xb0, 0x50, 0x30, 0xbb, 0x83, 0x67, 0x19, 0x92, 0x7c, 0x09, 0x9d, 0x69,
+	0x70, 0xc8, 0x29, 0x0f, 0x98, 0xb0, 0x01, 0xc3, 0xb9, 0xb6, 0x10, 0xce, 0xbe, 0xd1, 0x48, 0x63,
+	0xca, 0x2d, 0xc8, 0x5d, 0xb0, 0x04, 0x9d, 0xc5, 0x53, 0x26, 0xec, 0x2e, 0x1a, 0xff, 0x77, 0xc1,
+	0xf8, 0x45, 0x2a, 0x4f, 0x4d, 0x8d, 0xf6, 0xda, 0x5d, 0xe8, 0x64, 0x49, 0x52, 0x09, 0x7e, 0xc3,
+	0x4e, 0xf1, 0x29, 0x3b, 0xae, 0xfa, 0xa9, 0x1e, 0xe2, 0x84, 0x4e, 0x13, 0x66, 0x1e, 0x02, 0x89,
+	0xfb, 0xb5, 0x7b, 0xd5, 0xb5, 0x2f, 0x60, 0x50, 0xbe, 0xce, 0x85, 0xac, 0xef, 0x43, 0xaf, 0x78,
+	0x9f, 0x8b, 0xd8, 0x3a, 0x3f, 0xd4, 0x60, 0x45, 0x07, 0xb5, 0x4d, 0x25, 0x3d, 0xa4, 0x82, 0x91,
+	0x87, 0xd0, 0x8e, 0x53, 0x96, 0xb0, 0x6b, 0x98, 0x80, 0xeb, 0xf3, 0x09, 0x30, 0xba, 0x86, 0xd6,
+	0x99, 0xc8, 0xec, 0xd4, 0x1d, 0xe2, 0x20, 0x44, 0x78, 0xb5, 0x5d, 0xf5, 0x93, 0x8c, 0xc1, 0x3a,
+	0x61, 0x5c, 0x28, 0x68, 0x34, 0xf0, 0x91, 0xed, 0xcc, 0xa9, 0xf1, 0xf6, 0x2a, 0x95, 0xbb, 0x46,
+	0x91, 0xac, 0x41, 0x5b, 0x48, 0x2a, 0x99, 0x42, 0x6a, 0x0a, 0xb4, 0x8c, 0x5e, 0x7b, 0x02, 0xfd,
+	0xd2, 0xe1, 0x4b, 0xc2, 0xbe, 0x5e, 0x0c, 0xbb, 0x3b, 0x1e, 0xce, 0x47, 0x51, 0x4c, 0xc4, 0x2f,
+	0x55, 0x58, 0x99, 0xbb, 0x07, 0xb9, 0x0a, 0x9d, 0x98, 0x72, 0x16, 0x4a, 0x75, 0x7e, 0xea, 0x37,
+	0x67, 0x28, 0xe9, 0x8c, 0x71, 0x9f, 0x79, 0x4a, 0x9a, 0x26, 0x36, 0x67, 0x28, 0xac, 0xd3, 0x44,
+	0x1e, 0x47, 0x5c, 0x97, 0x98, 0xa6, 0xca, 0x75, 0xde, 0xb8, 0x48, 0x9d, 0xdb, 0x60, 0x89, 0x64,
+	0x36, 0xa3, 0xfc, 0x54, 0xe7, 0xc2, 0x90, 0xce, 0x1f, 0x0d, 0xa8, 0xbb, 0x49, 0x58, 0xac, 0xa3,
+	0xea, 0xf9, 0xeb, 0x68, 0x79, 0x7f, 0x18, 0x01, 0x49, 0x43, 0xd5, 0xb9, 0xda, 0x47, 0x95, 0x34,
+	0x96, 0x25, 0x12, 0xb2, 0x09, 0xc3, 0x12, 0x57, 0x25, 0xa5, 0x81, 0xda, 0x0b, 0x7c, 0xb2, 0x09,
+	0xd6, 0x71, 0x20, 0x64, 0x84, 0x91, 0xd4, 0x4b, 0x2f, 0xa3, 0xdb, 0xa1, 0x6b, 0x14, 0xc8, 0x0d,
+	0x68, 0xa9, 0x27, 0x4f, 0x04, 0x36, 0x94, 0xc1, 0x78, 0x25, 0x53, 0x7d, 0x81, 0x6c, 0x57, 0x8b,
+	0x89, 0x03, 0x3d, 0xce, 0xde, 0x26, 0x4c, 0xc8, 0x67, 0xdc, 0x63, 0xdc, 0x6e, 0x63, 0x8b, 0x29,
+	0xf1, 0xc8, 0x06, 0xac, 0x44, 0x89, 0x8c, 0x13, 0xb9, 0x1d, 0x70, 0x36, 0xc1, 0x0b, 0xa4, 0xad,
+	0x63, 0x9e, 0x4d, 0xc6, 0x70, 0xe5, 0x88, 0x0a, 0x79, 0xe7, 0xd9, 0x9c, 0x3a, 0xa0, 0xfa, 0x52,
+	0x99, 0xb1, 0x79, 0x3b, 0x6f, 0xd3, 0xcd, 0x6d, 0xe6, 0x65, 0x69, 0x13, 0x53, 0x95, 0x7f, 0x6a,
+	0xf7, 0x4c, 0x13, 0x43, 0x12, 0x9f, 0x5b, 0x77, 0xa1, 0x3e, 0x86, 0x62, 0x48, 0xb2, 0x09, 0x0d,
+	0x49, 0x7d, 0x61, 0x0f, 0x30, 0x77, 0xab, 0x59, 0x42, 0xdc, 0x24, 0x1c, 0x1d, 0x50, 0xdf, 0x34,
+	0x69, 0xa5, 0xb3, 0xf6, 0x08, 0x3a, 0x19, 0x6b, 0x49, 0x85, 0xdc, 0x28, 0x57, 0xc8, 0xa5, 0xcc,
+	0xd7, 0x01, 0xf5, 0x55, 0x7e, 0x59, 0xa1, 0x44, 0x1e, 0x35, 0xda, 0xd6, 0xb0, 0xed, 0xfc, 0x58,
+	0x07, 0x6b, 0x5f, 0xdf, 0xf1, 0x9f, 0x05, 0x5c, 0x06, 0xa2, 0xc6, 0xf9, 0x41, 0xd4, 0xfc, 0x30,
+	0x88, 0x6c, 0xb0, 0x62, 0xce, 0xe2, 0xc7, 0x81, 0xd4, 0xf3, 0xcb, 0x90, 0xe4, 0x7f, 0x00, 0x87,
+	0x94, 0x4f, 0x22, 0x8f, 0x29, 0xa1, 0x85, 0xc2, 0x02, 0x47, 0xc1, 0xef, 0x68, 0x1a, 0xbd, 0x9b,
+	0xb0, 0xe9, 0xf4, 0xe0, 0x34, 0x66, 0x38, 0xc8, 0x3a, 0x6e, 0x89, 0xa7, 0xda, 0x59, 0x10, 0xaa,
+	0xe7, 0x7f, 0xfa, 0x00, 0x71, 0x57, 0x75, 0x33, 0x5a, 0xc9, 0xa2, 0x98, 0x71, 0x2a, 0x23, 0xae,
+	0x41, 0x96, 0xd1, 0x45, 0x28, 0x74, 0x4b, 0x50, 0x70, 0x7e, 0xad, 0x43, 0x2b, 0xed, 0xfd, 0x7f,
+	0xeb, 0x5b, 0x14, 0x72, 0x5b, 0x3f, 0x7f, 0x6e, 0x1b, 0x17, 0x2b, 0xd0, 0xd6, 0x92, 0x02, 0xcd,
+	0xba, 0xc8, 0xce, 0xfb, 0x98, 0xf1, 0x40, 0x9d, 0xa4, 0x73, 0xbd, 0xc0, 0x57, 0x59, 0xd1, 0xf9,
+	0xc7, 0x64, 0x37, 0x5d, 0x43, 0x9e, 0x01, 0xa5, 0xce, 0x99, 0x50, 0xba, 0xa9, 0x0b, 0x2a, 0x5d,
+	0x15, 0xfe, 0x93, 0x07, 0x80, 0x99, 0xfd, 0xc4, 0x35, 0xd5, 0x1c, 0xb6, 0x9c, 0xdf, 0x6a, 0xd0,
+	0x36, 0x52, 0x72, 0x13, 0x9a, 0x38, 0xe4, 0xd0, 0xe7, 0x60, 0xfc, 0xef, 0x05, 0xfb, 0x91, 0xf6,
+	0x82, 0x5a, 0x0a, 0x38, 0x54, 0x4a, 0x36, 0x8b, 0x71, 0x5a, 0x57, 0x37, 0xfa, 0x6e, 0x46, 0xab,
+	0x11, 0x35, 0xa5, 0x42, 0xee, 0x70, 0x9e, 0xcd, 0xa1, 0x9c, 0xa1, 0x10, 0x23, 0x24, 0xe5, 0x0a,
+	0x31, 0x1f, 0x1f, 0x44, 0x46, 0x95, 0x7c, 0x06, 0xed, 0xa3, 0x20, 0x0c, 0xc4, 0x31, 0x4b, 0x97,
+	0xbf, 0x0f, 0x9b, 0x65, 0xba, 0xea, 0x2e, 0x9c, 0x89, 0x64, 0x8a, 0x93, 0x21, 0x2d, 0xae, 0x9c,
+	0xe1, 0x7c, 0x0b, 0xcd, 0x34, 0xfa, 0x2e, 0x58, 0x31, 0x0b, 0xbd, 0x20, 0xf4, 0x87, 0x15, 0xd2,
+	0x57, 0x36, 0x08, 0x0f, 0xe6, 0x0d, 0xab, 0x4a, 0xc6, 0x93, 0x30, 0x54, 0xb2, 0x1a, 0x01, 0x68,
+	0x1d, 0xd1, 0x60, 0xca, 0xbc, 0x61, 0x5d, 0x09, 0xc4, 0x9b, 0x20, 0x8e, 0x99, 0x37, 0x6c, 0x28,
+	0x23, 0x91, 0x4c, 0x26, 0x8c, 0x79, 0xcc, 0x1b, 0x36, 0x9d, 0xdf, 0x9b, 0x60, 0x3d, 0x61, 0x42,
+	0x50, 0x9f, 0x29, 0xc8, 0x98, 0x1d, 0xa4, 0x8a, 0xa9, 0xca, 0x36, 0x8d, 0x74, 0x99, 0xad, 0x65,
+	0xcb, 0xec, 0x2a, 0xb4, 0x04, 0x0b, 0x15, 0x4c, 0xf5, 0xf8, 0x4e, 0xa9, 0xbf, 0x36, 0xbe, 0xf5,
+	0x76, 0x64, 0xc6, 0xb7, 0x26, 0xc9, 0x1d, 0x00, 0x9e, 0x84, 0x5b, 0xba, 0x78, 0x5b, 0xe8, 0xf4,
+	0x72, 0xb1, 0xab, 0x6b, 0xd1, 0x6e, 0xc5, 0x2d, 0x28, 0x6a, 0xb3, 0x97, 0xb1, 0x87, 0x66, 0xd6,
+	0xa2, 0x99, 0x16, 0x69, 0x33, 0x4d, 0x91, 0xcf, 0xa1, 0x27, 0xa9, 0xbf, 0x15, 0x29, 0x68, 0xe7,
+	0x1b, 0xf7, 0xbf, 0x8a, 0x28, 0xcb, 0x84, 0xbb, 0x15, 0xb7, 0xa4, 0x4c, 0x9e, 0xc3, 0x65, 0x4f,
+	0x2f, 0x49, 0xbb, 0x8c, 0x7a, 0x5b, 0xc7, 0x34, 0xf4, 0x99, 0x87, 0xa5, 0xd5, 0x1d, 0x5f, 0x5d,
+	0x58, 0xe8, 0x0a, 0x3a, 0xbb, 0x15, 0x77, 0x99, 0x29, 0x79, 0x04, 0x97, 0x74, 0x1e, 0x5c, 0xe6,
+	0x07, 0x42, 0x32, 0xce, 0x3c, 0x6c, 0x80, 0x4b, 0x3e, 0x41, 0x72, 0x8d, 0xdd, 0x8a, 0xbb, 0x68,
+	0x46, 0xbe, 0x82, 0x7e, 0xda, 0x6b, 0xcc, 0xbd, 0xba, 0xe8, 0x67, 0x75, 0xae, 0x23, 0xe5, 0x37,
+	0x2a, 0xab, 0xeb, 0xd4, 0xb8, 0x06, 0x71, 0x38, 0x91, 0xe7, 0x52, 0x93, 0x09, 0x75, 0x6a, 0x32,
+	0x9a, 0x8c, 0xa1, 0x23, 0xa9, 0xff, 0x35, 0x42, 0xd2, 0xee, 0xa3, 0x25, 0x29, 0x5a, 0xa6, 0x92,
+	0xdd, 0x8a, 0x9b, 0xab, 0x91, 0x07, 0x30, 0xd0, 0xe3, 0xde, 0x3c, 0xe3, 0x00, 0x0d, 0xf3, 0x9a,
+	0xdf, 0x2f, 0x89, 0x77, 0x2b, 0xee, 0x9c, 0xc1, 0xc3, 0x0e, 0x58, 0x31, 0x3d, 0x9d, 0x46, 0xd4,
+	0x73, 0x6e, 0x03, 0xe4, 0x60, 0xc9, 0x3b, 0x7b, 0x75, 0xc9, 0x67, 0x5f, 0x2d, 0xfb, 0xec, 0x73,
+	0x5e, 0xa1, 0x95, 0x41, 0xc7, 0x39, 0xad, 0xd4, 0xc7, 0x5e, 0xcc, 0xd9, 0x49, 0x10, 0x25, 0x22,
+	0xff, 0x8c, 0x2c, 0xb2, 0x9c, 0xd7, 0xe6, 0x9b, 0xe6, 0xf4, 0x53, 0xf8, 0xee, 0x15, 0x61, 0xaa,
+	0x7a, 0x20, 0x4f, 0xc2, 0xfd, 0x82, 0xf3, 0x8c, 0x56, 0xfe, 0x25, 0xf5, 0x8d, 0x7f, 0x49, 0xfd,
+	0x72, 0x27, 0xaa, 0xcf, 0x77, 0xa2, 0x10, 0x7d, 0xe7, 0xef, 0x7a, 0x31, 0xdf, 0xab, 0xd0, 0x52,
+	0x25, 0x9a, 0x39, 0xd6, 0x94, 0xaa, 0x7e, 0xdd, 0x95, 0xb1, 0x6b, 0xf4, 0x5d, 0x43, 0x3a, 0xcf,
+	0x70, 0x9a, 0x68, 0x40, 0x5c, 0xec, 0xb0, 0x2b, 0xd0, 0x64, 0x85, 0xd6, 0x9e, 0x12, 0xce, 0x1e,
+	0x5c, 0x5e, 0x52, 0x7f, 0x26, 0xcf, 0xd5, 0x33, 0xf3, 0x5c, 0x5b, 0xcc, 0xf3, 0x63, 0xb8, 0xb4,
+	0x50, 0x7a, 0x67, 0x3c, 0xe3, 0x7c, 0x03, 0x5d, 0xf8, 0x7f, 0xc1, 0xf9, 0xb9, 0x0a, 0xfd, 0x52,
+	0x01, 0xaa, 0x9d, 0x2a, 0xc5, 0x39, 0x6e, 0x4c, 0xa9, 0xbb, 0x02, 0xe7, 0x8c, 0xe5, 0xe4, 0x2e,
+	0x0c, 0xcc, 0x1d, 0x53, 0x77, 0x78, 0xc8, 0x92, 0xc5, 0x63, 0x4e, 0xed, 0xdc, 0x9b, 0xca, 0xe6,
+	0x0c, 0x5a, 0xda, 0x84, 0xc0, 0xe0, 0xe5, 0xd3, 0xef, 0xf6, 0x9e, 0xee, 0x1d, 0xec, 0x3d, 0xd8,
+	0xdf, 0x7b, 0xbd, 0xb3, 0x3d, 0xac, 0x90, 0x1e, 0xb4, 0x93, 0x50, 0x52, 0xdf, 0xc7, 0x99, 0x04,
+	0xd0, 0xd2, 0xbf, 0x6b, 0x6a, 0xf2, 0xd0, 0x30, 0x8c, 0x92, 0x70, 0x82, 0x53, 0xa9, 0x07, 0xed,
+	0x89, 0x86, 0xe8, 0xb0, 0x51, 0x98, 0x57, 0x4d, 0x25, 0xa1, 0x7c, 0x72, 0x1c, 0x9c, 0x30, 0x6f,
+	0xd8, 0x3a, 0x6c, 0xe1, 0xe0, 0xf8, 0xff, 0x9f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x58, 0x62, 0x44,
+	0x58, 0x46, 0x12, 0x00, 0x00,
 }
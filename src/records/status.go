@@ -0,0 +1,244 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+var (
+	// ErrIllegalTransition is issued when a record can't move from its current status to the requested one
+	ErrIllegalTransition = errors.New("illegal status transition")
+
+	// ErrNoTags is issued when a record is tagged without any tags
+	ErrNoTags = errors.New("no tags provided")
+
+	// ErrIncompleteTags is issued when a record is completed before all of its tags are done
+	ErrIncompleteTags = errors.New("record has incomplete tags")
+)
+
+// transitions are the statuses that a record can move to from each status
+//
+// Tagging a tagged record adds more tags, and any record that is still being processed can fail.
+var transitions = map[Status][]Status{
+	Status_UN_INITIALIZED: {Status_tagged, Status_failed},
+	Status_untagged:       {Status_tagged, Status_failed},
+	Status_tagged:         {Status_tagged, Status_announced, Status_failed},
+	Status_announced:      {Status_complete, Status_failed},
+	Status_complete:       {Status_archived},
+	Status_failed:         {Status_archived},
+}
+
+// statusRecord is a record with a status that is changed by the state machine
+type statusRecord interface {
+	GetLabel() string
+	GetParentProjectLabel() string
+	GetStatus() Status
+	GetTags() map[string]*TagState
+	AddComment(text string) error
+}
+
+// CanTransition reports whether a record can move from one status to another
+func CanTransition(from, to Status) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// canReach reports whether a record can move from one status to another through any number of transitions
+func canReach(from, to Status) bool {
+	seen := map[Status]bool{from: true}
+	for queue := []Status{from}; len(queue) != 0; queue = queue[1:] {
+		for _, status := range transitions[queue[0]] {
+			if status == to {
+				return true
+			}
+			if !seen[status] {
+				seen[status] = true
+				queue = append(queue, status)
+			}
+		}
+	}
+	return false
+}
+
+// Tag will add tagged services to the run and move it to tagged
+//
+// The tags are added to the end of the request order and start as pending.
+func (run *Run) Tag(ctx context.Context, node *backend.Node, tags ...string) error {
+	msg, err := run.AddTags(tags...)
+	if err != nil {
+		return err
+	}
+	return AnnounceStatus(ctx, node, msg)
+}
+
+// AddTags will tag the run without announcing it, returning the StatusChanged message to announce once the run is synced
+func (run *Run) AddTags(tags ...string) (*Message, error) {
+	if err := checkTags("run", run, tags); err != nil {
+		return nil, err
+	}
+	if run.Tags == nil {
+		run.Tags = make(map[string]*TagState)
+	}
+	for _, tag := range tags {
+		run.Tags[tag] = NewTagState()
+	}
+	run.RequestOrder = append(run.RequestOrder, tags...)
+	return setStatus("run", run, Status_tagged, func(status Status) { run.Status = status }, strings.Join(tags, ", "))
+}
+
+// Announce will move a tagged run to announced, once its tagged services have been sent requests
+func (run *Run) Announce(ctx context.Context, node *backend.Node) error {
+	return changeStatus(ctx, node, "run", run, Status_announced, func(status Status) { run.Status = status }, "")
+}
+
+// Complete will move an announced run to complete, once all of its tags are done
+func (run *Run) Complete(ctx context.Context, node *backend.Node) error {
+	if err := checkComplete(run); err != nil {
+		return err
+	}
+	return changeStatus(ctx, node, "run", run, Status_complete, func(status Status) { run.Status = status }, "")
+}
+
+// Fail will move a run that is still being processed to failed, recording the reason in the history
+func (run *Run) Fail(ctx context.Context, node *backend.Node, reason string) error {
+	return changeStatus(ctx, node, "run", run, Status_failed, func(status Status) { run.Status = status }, reason)
+}
+
+// Archive will move a complete or failed run to archived
+func (run *Run) Archive(ctx context.Context, node *backend.Node) error {
+	return changeStatus(ctx, node, "run", run, Status_archived, func(status Status) { run.Status = status }, "")
+}
+
+// Tag will add tagged services to the sample and move it to tagged
+//
+// The tags are added to the end of the request order and start as pending.
+func (sample *Sample) Tag(ctx context.Context, node *backend.Node, tags ...string) error {
+	if err := checkTags("sample", sample, tags); err != nil {
+		return err
+	}
+	if sample.Tags == nil {
+		sample.Tags = make(map[string]*TagState)
+	}
+	for _, tag := range tags {
+		sample.Tags[tag] = NewTagState()
+	}
+	sample.RequestOrder = append(sample.RequestOrder, tags...)
+	return changeStatus(ctx, node, "sample", sample, Status_tagged, func(status Status) { sample.Status = status }, strings.Join(tags, ", "))
+}
+
+// Announce will move a tagged sample to announced, once its tagged services have been sent requests
+func (sample *Sample) Announce(ctx context.Context, node *backend.Node) error {
+	return changeStatus(ctx, node, "sample", sample, Status_announced, func(status Status) { sample.Status = status }, "")
+}
+
+// Complete will move an announced sample to complete, once all of its tags are done
+func (sample *Sample) Complete(ctx context.Context, node *backend.Node) error {
+	if err := checkComplete(sample); err != nil {
+		return err
+	}
+	return changeStatus(ctx, node, "sample", sample, Status_complete, func(status Status) { sample.Status = status }, "")
+}
+
+// Fail will move a sample that is still being processed to failed, recording the reason in the history
+func (sample *Sample) Fail(ctx context.Context, node *backend.Node, reason string) error {
+	return changeStatus(ctx, node, "sample", sample, Status_failed, func(status Status) { sample.Status = status }, reason)
+}
+
+// Archive will move a complete or failed sample to archived
+func (sample *Sample) Archive(ctx context.Context, node *backend.Node) error {
+	return changeStatus(ctx, node, "sample", sample, Status_archived, func(status Status) { sample.Status = status }, "")
+}
+
+// checkTransition will check a record can move to a new status and announce the change
+func checkTransition(recordType string, record statusRecord, to Status) error {
+	if !CanTransition(record.GetStatus(), to) {
+		return fmt.Errorf("%w: %v -> %v (%v)", ErrIllegalTransition, record.GetStatus(), to, record.GetLabel())
+	}
+	if len(record.GetParentProjectLabel()) == 0 {
+		return fmt.Errorf("%v %v needs a parent project to change status", recordType, record.GetLabel())
+	}
+	return nil
+}
+
+// checkTags will check a record can be tagged with a set of new tags
+func checkTags(recordType string, record statusRecord, tags []string) error {
+	if len(tags) == 0 {
+		return ErrNoTags
+	}
+	if err := checkTransition(recordType, record, Status_tagged); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if len(tag) == 0 {
+			return fmt.Errorf("empty tag for %v", record.GetLabel())
+		}
+		if _, tagged := record.GetTags()[tag]; tagged || seen[tag] {
+			return fmt.Errorf("%v is already tagged with %v", record.GetLabel(), tag)
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+// checkComplete will check all of a record's tags have succeeded or been skipped
+func checkComplete(record statusRecord) error {
+	for _, tag := range sortedKeys(record.GetTags()) {
+		if !record.GetTags()[tag].IsDone() {
+			return fmt.Errorf("%w: %v (%v)", ErrIncompleteTags, tag, record.GetLabel())
+		}
+	}
+	return nil
+}
+
+// changeStatus will move a record to a new status, adding a comment to its history and announcing the change to its parent project
+//
+// The record must have a parent project to announce to.
+func changeStatus(ctx context.Context, node *backend.Node, recordType string, record statusRecord, to Status, set func(Status), detail string) error {
+	msg, err := setStatus(recordType, record, to, set, detail)
+	if err != nil {
+		return err
+	}
+	return AnnounceStatus(ctx, node, msg)
+}
+
+// setStatus will move a record to a new status and add a comment to its history, returning the StatusChanged message for the change
+func setStatus(recordType string, record statusRecord, to Status, set func(Status), detail string) (*Message, error) {
+	if err := checkTransition(recordType, record, to); err != nil {
+		return nil, err
+	}
+	from := record.GetStatus()
+	set(to)
+	comment := fmt.Sprintf("status changed: %v -> %v", from, to)
+	if len(detail) != 0 {
+		comment = fmt.Sprintf("%v (%v)", comment, detail)
+	}
+	if err := record.AddComment(comment); err != nil {
+		return nil, err
+	}
+	msg := NewMessage(record.GetParentProjectLabel())
+	msg.Payload = &Message_StatusChanged{StatusChanged: &StatusChanged{
+		RecordType:     recordType,
+		Label:          record.GetLabel(),
+		PreviousStatus: from,
+		Status:         to,
+	}}
+	return msg, nil
+}
+
+// AnnounceStatus will announce a status change to the record's parent project
+func AnnounceStatus(ctx context.Context, node *backend.Node, msg *Message) error {
+	if err := Announce(ctx, node, msg); err != nil && !errors.Is(err, backend.ErrOffline) {
+		return fmt.Errorf("%w: %v", ErrNotAnnounced, err)
+	}
+	return nil
+}
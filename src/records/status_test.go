@@ -0,0 +1,118 @@
+package records
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestStatus
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 2, func(t *testing.T, nodes []*backend.Node) {
+		sub, err := nodes[1].Subscribe(ctx, projectLabel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receive := func() *StatusChanged {
+			select {
+			case received := <-sub.Messages():
+				msg, err := DecodeMessage(received.Data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return msg.GetStatusChanged()
+			case <-time.After(5 * time.Second):
+				t.Fatal("no announcement received")
+			}
+			return nil
+		}
+
+		// a run needs a parent project and legal transitions
+		run := InitRun(runLabel, outputDir, fast5Dir, fastqDir)
+		if err := run.Tag(ctx, nodes[0], "basecall"); err == nil {
+			t.Fatal("orphan run changed status")
+		}
+		run.ParentProjectLabel = projectLabel
+		if err := run.Announce(ctx, nodes[0]); !errors.Is(err, ErrIllegalTransition) {
+			t.Fatalf("untagged run was announced (%v)", err)
+		}
+		if err := run.Tag(ctx, nodes[0]); err != ErrNoTags {
+			t.Fatalf("run tagged without tags (%v)", err)
+		}
+
+		// tag the run and check the change is recorded and announced
+		history := len(run.GetHistory())
+		if err := run.Tag(ctx, nodes[0], "basecall", "upload"); err != nil {
+			t.Fatal(err)
+		}
+		if run.GetStatus() != Status_tagged || len(run.GetRequestOrder()) != 2 || run.GetTags()["basecall"].GetState() != TagState_pending {
+			t.Fatalf("run not tagged: %v", run)
+		}
+		if len(run.GetHistory()) != history+1 || !strings.Contains(run.GetHistory()[history].GetText(), "untagged -> tagged") {
+			t.Fatalf("transition not recorded in the history: %v", run.GetHistory())
+		}
+		if change := receive(); change.GetLabel() != runLabel || change.GetRecordType() != "run" || change.GetPreviousStatus() != Status_untagged || change.GetStatus() != Status_tagged {
+			t.Fatalf("unexpected status change: %v", change)
+		}
+		if err := run.Tag(ctx, nodes[0], "basecall"); err == nil {
+			t.Fatal("run tagged twice with the same tag")
+		}
+
+		// a run can only complete once its tags have
+		if err := run.Announce(ctx, nodes[0]); err != nil {
+			t.Fatal(err)
+		}
+		receive()
+		if err := run.Complete(ctx, nodes[0]); !errors.Is(err, ErrIncompleteTags) {
+			t.Fatalf("run completed with incomplete tags (%v)", err)
+		}
+		run.Tags["basecall"].Succeed("")
+		run.Tags["upload"].Skip()
+		if err := run.Complete(ctx, nodes[0]); err != nil {
+			t.Fatal(err)
+		}
+		receive()
+		if err := run.Fail(ctx, nodes[0], "too late"); !errors.Is(err, ErrIllegalTransition) {
+			t.Fatalf("complete run failed (%v)", err)
+		}
+		if err := run.Archive(ctx, nodes[0]); err != nil || run.GetStatus() != Status_archived {
+			t.Fatalf("run not archived (%v)", err)
+		}
+		receive()
+
+		// a run can be tagged without announcing it, so that it can be synced first
+		unannounced := InitRun("unannounced run", outputDir, fast5Dir, fastqDir)
+		unannounced.ParentProjectLabel = projectLabel
+		msg, err := unannounced.AddTags("basecall")
+		if err != nil || unannounced.GetStatus() != Status_tagged || msg.GetStatusChanged().GetStatus() != Status_tagged {
+			t.Fatalf("run not tagged (%v): %v", err, unannounced)
+		}
+		if err := AnnounceStatus(ctx, nodes[0], msg); err != nil {
+			t.Fatal(err)
+		}
+		if change := receive(); change.GetLabel() != "unannounced run" {
+			t.Fatalf("unexpected status change: %v", change)
+		}
+
+		// samples use the same transitions
+		sample := InitSample("sample 1", 1)
+		sample.ParentProjectLabel = projectLabel
+		if err := sample.Archive(ctx, nodes[0]); !errors.Is(err, ErrIllegalTransition) {
+			t.Fatalf("untagged sample was archived (%v)", err)
+		}
+		if err := sample.Fail(ctx, nodes[0], "contaminated"); err != nil {
+			t.Fatal(err)
+		}
+		if change := receive(); change.GetRecordType() != "sample" || change.GetStatus() != Status_failed {
+			t.Fatalf("unexpected status change: %v", change)
+		}
+		if !strings.Contains(sample.GetHistory()[len(sample.GetHistory())-1].GetText(), "contaminated") {
+			t.Fatalf("failure reason not recorded in the history: %v", sample.GetHistory())
+		}
+	})
+}
@@ -0,0 +1,126 @@
+// Package records interfaces the protobuf definitions with Scribe
+package records
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+)
+
+// tagStateRanks orders the tag states by how far a tagged service has progressed within an attempt
+var tagStateRanks = map[TagState_State]int{
+	TagState_pending:   0,
+	TagState_requested: 1,
+	TagState_running:   2,
+	TagState_failed:    3,
+	TagState_skipped:   4,
+	TagState_succeeded: 5,
+}
+
+// NewTagState will init a tag state for a service that has not been sent a request
+func NewTagState() *TagState {
+	return &TagState{State: TagState_pending}
+}
+
+// IsDone reports whether the tagged service has finished with the record, by succeeding or being skipped
+func (tag *TagState) IsDone() bool {
+	return tag.GetState() == TagState_succeeded || tag.GetState() == TagState_skipped
+}
+
+// Request will start a new attempt, once the tagged service has been sent a request
+func (tag *TagState) Request() {
+	tag.State = TagState_requested
+	tag.Attempts++
+	tag.Started, tag.Finished = nil, nil
+}
+
+// Start will record that the tagged service is processing the record
+func (tag *TagState) Start() {
+	tag.State = TagState_running
+	tag.Started = ptypes.TimestampNow()
+}
+
+// Succeed will record that the tagged service has finished with the record, and where its output is stored
+func (tag *TagState) Succeed(resultCID string) {
+	tag.State = TagState_succeeded
+	tag.Finished = ptypes.TimestampNow()
+	tag.ResultCID = resultCID
+}
+
+// Fail will record that the current attempt has failed
+func (tag *TagState) Fail(reason string) {
+	tag.State = TagState_failed
+	tag.Finished = ptypes.TimestampNow()
+	tag.LastError = reason
+}
+
+// Skip will record that the tagged service was not needed
+func (tag *TagState) Skip() {
+	tag.State = TagState_skipped
+	tag.Finished = ptypes.TimestampNow()
+}
+
+// Describe will return a human readable summary of the tag state
+func (tag *TagState) Describe() string {
+	if tag.GetAttempts() < 2 {
+		return tag.GetState().String()
+	}
+	return fmt.Sprintf("%v (attempt %d)", tag.GetState(), tag.GetAttempts())
+}
+
+// compareTags reports whether one tag state has progressed further than another (1), less far (-1) or as far (0)
+//
+// Later attempts have progressed further, and within an attempt the states are ordered by tagStateRanks.
+func compareTags(a, b *TagState) int {
+	switch {
+	case a.GetAttempts() > b.GetAttempts():
+		return 1
+	case a.GetAttempts() < b.GetAttempts():
+		return -1
+	case tagStateRanks[a.GetState()] > tagStateRanks[b.GetState()]:
+		return 1
+	case tagStateRanks[a.GetState()] < tagStateRanks[b.GetState()]:
+		return -1
+	}
+	return 0
+}
+
+// legacyTagState will convert the complete status of a tag from older versions of Scribe
+func legacyTagState(complete bool) *TagState {
+	if complete {
+		return &TagState{State: TagState_succeeded}
+	}
+	return NewTagState()
+}
+
+// migrateTags will convert the tags of a json record from a map of complete statuses to a map of tag states
+//
+// Records without tags, or with tags that have already been migrated, are returned unchanged.
+func migrateTags(data []byte) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data, nil
+	}
+	tagsData, ok := fields["tags"]
+	if !ok {
+		return data, nil
+	}
+	legacy := make(map[string]bool)
+	if err := json.Unmarshal(tagsData, &legacy); err != nil {
+		return data, nil
+	}
+	tags := make(map[string]json.RawMessage, len(legacy))
+	for tag, complete := range legacy {
+		state, err := marshalRecord(legacyTagState(complete))
+		if err != nil {
+			return nil, err
+		}
+		tags[tag] = state
+	}
+	var err error
+	if fields["tags"], err = json.Marshal(tags); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
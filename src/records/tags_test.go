@@ -0,0 +1,57 @@
+package records
+
+import (
+	"context"
+	"testing"
+
+	"github.com/will-rowe/scribe/src/backend"
+)
+
+// TestTagState
+func TestTagState(t *testing.T) {
+	ctx := context.Background()
+	backend.WithMemory(t, 1, func(t *testing.T, nodes []*backend.Node) {
+		node := nodes[0]
+
+		// check the tag states progress through an attempt
+		tag := NewTagState()
+		tag.Request()
+		tag.Start()
+		if tag.GetAttempts() != 1 || tag.GetState() != TagState_running || tag.GetStarted() == nil || tag.IsDone() {
+			t.Fatalf("tag not started: %v", tag)
+		}
+		tag.Fail("out of memory")
+		retry := &TagState{State: tag.GetState(), Attempts: tag.GetAttempts(), LastError: tag.GetLastError()}
+		retry.Request()
+		if compareTags(retry, tag) != 1 || compareTags(tag, retry) != -1 || retry.GetLastError() != "out of memory" {
+			t.Fatalf("a new attempt should progress further than a failed one: %v", retry)
+		}
+		retry.Succeed("result")
+		if !retry.IsDone() || retry.GetResultCID() != "result" || retry.Describe() != "succeeded (attempt 2)" {
+			t.Fatalf("tag not succeeded: %v", retry)
+		}
+
+		// check runs with tags from older versions of Scribe are migrated
+		data := []byte(`{"label": "legacy run", "tags": {"basecall": true, "demux": false}}`)
+		cid, err := node.DagPut(ctx, data, "json", "cbor", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		run := &Run{}
+		if err := getRecord(ctx, node, cid, run); err != nil {
+			t.Fatal(err)
+		}
+		if len(run.GetTags()) != 2 || run.GetTags()["basecall"].GetState() != TagState_succeeded || run.GetTags()["demux"].GetState() != TagState_pending {
+			t.Fatalf("legacy tags not migrated: %v", run.GetTags())
+		}
+
+		// and the same for a version 1 CRDT database
+		state := &crdtRun{}
+		if err := node.DagGet(ctx, cid, "", state); err != nil {
+			t.Fatal(err)
+		}
+		if !state.Tags["basecall"].tagState().IsDone() || state.Tags["demux"].tagState().IsDone() {
+			t.Fatalf("legacy CRDT tags not migrated: %v", state.Tags)
+		}
+	})
+}